@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyzeMsmFlag       string
+	analyzeInputFlag     []string
+	analyzeSaveFlag      string
+	analyzeThresholdFlag float64
+	analyzeOfflineFlag   bool
+	analyzeRoutesFlag    string
+)
+
+func init() {
+	analyzeCmd.Flags().StringVar(&analyzeMsmFlag, "msm", "", "Comma-separated IDs of existing traceroute measurements")
+	analyzeCmd.Flags().StringSliceVar(&analyzeInputFlag, "input", nil, "Saved traceroute results (JSON array or NDJSON), may be repeated")
+	analyzeCmd.Flags().StringVar(&analyzeSaveFlag, "save", "", "Save the merged raw results to this JSON file")
+	analyzeCmd.Flags().Float64Var(&analyzeThresholdFlag, "threshold", 0.8, "Threshold for common ASN (default: 0.8 = 80%)")
+	analyzeCmd.Flags().BoolVar(&analyzeOfflineFlag, "offline", false, "Analyze --input files without any network access, resolving ASNs from --routes only")
+	analyzeCmd.Flags().StringVar(&analyzeRoutesFlag, "routes", "", "Route table for --offline, one \"<prefix> <asn> [holder]\" per line")
+
+	analyzeCmd.MarkFlagsOneRequired("msm", "input")
+	analyzeCmd.MarkFlagsMutuallyExclusive("msm", "offline")
+
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Analyze existing traceroute measurements without creating a new one",
+	Long: `Fetch the results of one or more existing traceroute measurements (yours or public ones)
+or read saved result files, merge them and produce the same common ASN path report as
+the traceroute command. No credits are spent and no API key is needed, so the analysis
+can be re-run with different thresholds. Saved result files need no access to RIPE Atlas,
+but hop and probe ASNs are looked up in RIPEstat unless --offline is given. With --offline
+nothing is queried: ASNs come from the --routes table, and addresses it does not cover
+have no ASN.
+
+Example:
+  ripeatlas analyze --msm 12345
+  ripeatlas analyze --msm 12345,67890 --threshold 0.6
+  ripeatlas analyze --input results.json --input more.ndjson
+  ripeatlas analyze --input results.json --offline --routes routes.txt`,
+	Args: cobra.NoArgs,
+	RunE: runAnalyze,
+}
+
+func runAnalyze(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if analyzeRoutesFlag != "" && !analyzeOfflineFlag {
+		return fmt.Errorf("--routes requires --offline")
+	}
+	if analyzeOfflineFlag {
+		var routes []analyzer.Route
+		if analyzeRoutesFlag != "" {
+			var err error
+			if routes, err = analyzer.ReadRoutesFile(analyzeRoutesFlag); err != nil {
+				return err
+			}
+		}
+		analyzer.SetOffline(true, routes)
+		defer analyzer.SetOffline(false, nil)
+	}
+
+	// A repeated ID would count its results twice
+	var ids []int
+	for _, s := range splitList(analyzeMsmFlag) {
+		id, err := parseMeasurementID(s)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	// Results are kept as returned so --save loses no fields
+	var (
+		report atlas.Report
+		raw    []json.RawMessage
+		client = newAtlasClient()
+	)
+
+	if len(ids) > 0 {
+		msmResults, err := fetchMeasurements(ctx, client, &report, ids)
+		if err != nil {
+			return err
+		}
+		raw = append(raw, msmResults...)
+	}
+
+	if len(analyzeInputFlag) > 0 {
+		fmt.Printf("📂 Reading %d result file(s)...\n", len(analyzeInputFlag))
+		for _, path := range analyzeInputFlag {
+			fileResults, err := atlas.ReadRawResultsFile(path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("   %s: %d traceroute results\n", path, len(fileResults))
+			raw = append(raw, fileResults...)
+		}
+		fmt.Println()
+	}
+
+	results, err := atlas.DecodeResults[atlas.TracerouteResult](raw)
+	if err != nil {
+		return err
+	}
+	if len(analyzeInputFlag) > 0 {
+		describeResults(&report, results)
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no traceroute results to analyze")
+	}
+
+	if analyzeSaveFlag != "" {
+		if err := atlas.WriteResultsFile(analyzeSaveFlag, raw); err != nil {
+			return err
+		}
+		fmt.Printf("💾 Saved results to %s\n\n", analyzeSaveFlag)
+	}
+
+	// Rebuild the per-ASN distribution from the probes that actually reported.
+	// Measurement IDs allow an Atlas probe lookup. Saved files carry no probe
+	// ASNs, so they are looked up from the probes' source addresses in
+	// RIPEstat (or the offline routes), like the hops.
+	fmt.Printf("🔎 Resolving the ASNs of %d reporting probes...\n\n", countProbes(results))
+	var probeASNs map[int]int
+	if len(ids) > 0 {
+		probeASNs, err = probeASNsFromAtlas(ctx, client, results)
+	} else {
+		probeASNs, err = probeASNsFromResults(ctx, results)
+	}
+	if err != nil {
+		return err
+	}
+	fillAllocationsFromResults(&report, results, probeASNs)
+
+	if err := analyzeTraceroutes(ctx, &report, results, analyzeThresholdFlag); err != nil {
+		return err
+	}
+
+	fmt.Println(atlas.GenerateReport(report))
+
+	return nil
+}
+
+// fetchMeasurements fetches and merges the results of existing traceroute
+// measurements, filling in the measurement part of the report
+func fetchMeasurements(ctx context.Context, client *atlas.Client, report *atlas.Report, ids []int) ([]json.RawMessage, error) {
+	var (
+		results []json.RawMessage
+		targets []string
+		stopped time.Time
+	)
+
+	fmt.Printf("📥 Fetching results of %d measurement(s)...\n", len(ids))
+	for _, id := range ids {
+		status, err := client.GetMeasurementStatus(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get measurement %d: %w", id, err)
+		}
+		if status.Type != "traceroute" {
+			return nil, fmt.Errorf("measurement %d is a %s measurement, only traceroute can be analyzed", id, status.Type)
+		}
+
+		msmResults, err := client.GetMeasurementResultsRaw(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch results of measurement %d: %w", id, err)
+		}
+		fmt.Printf("   %d: %d traceroute results (%s)\n", id, len(msmResults), status.Status.Name)
+
+		results = append(results, msmResults...)
+		if !slices.Contains(targets, status.Target) {
+			targets = append(targets, status.Target)
+		}
+
+		start := time.Unix(status.StartTime, 0)
+		if report.CreatedAt.IsZero() || start.Before(report.CreatedAt) {
+			report.CreatedAt = start
+		}
+		if stop := time.Unix(status.StopTime, 0); stop.After(stopped) {
+			stopped = stop
+		}
+	}
+	fmt.Println()
+
+	report.MeasurementID = ids[0]
+	report.MeasurementIDs = ids
+	report.Target = strings.Join(targets, ", ")
+	if stopped.After(report.CreatedAt) {
+		report.Duration = stopped.Sub(report.CreatedAt)
+	}
+
+	return results, nil
+}
+
+// describeResults derives measurement IDs, targets and timing from the
+// results themselves, for reports built from saved files
+func describeResults(report *atlas.Report, results []atlas.TracerouteResult) {
+	var (
+		targets     = splitList(strings.ReplaceAll(report.Target, ", ", ","))
+		first, last int64
+	)
+
+	for _, result := range results {
+		if result.MsmID > 0 && !slices.Contains(report.MeasurementIDs, result.MsmID) {
+			report.MeasurementIDs = append(report.MeasurementIDs, result.MsmID)
+		}
+		if result.DstAddr != "" && !slices.Contains(targets, result.DstAddr) {
+			targets = append(targets, result.DstAddr)
+		}
+		if first == 0 || result.Timestamp < first {
+			first = result.Timestamp
+		}
+		last = max(last, result.Timestamp)
+	}
+
+	if len(report.MeasurementIDs) > 0 {
+		report.MeasurementID = report.MeasurementIDs[0]
+	}
+	report.Target = strings.Join(targets, ", ")
+	if report.CreatedAt.IsZero() && first > 0 {
+		report.CreatedAt = time.Unix(first, 0)
+		report.Duration = time.Duration(last-first) * time.Second
+	}
+}
+
+// countProbes returns the number of distinct probes that reported results
+func countProbes(results []atlas.TracerouteResult) int {
+	seen := make(map[int]bool)
+	for _, result := range results {
+		seen[result.ProbeID] = true
+	}
+	return len(seen)
+}
+
+// probeASNsFromAtlas maps the reporting probes to their IPv4 ASN using the Atlas probe API
+func probeASNsFromAtlas(ctx context.Context, client *atlas.Client, results []atlas.TracerouteResult) (map[int]int, error) {
+	var probeIDs []int
+	seen := make(map[int]bool)
+	for _, result := range results {
+		if !seen[result.ProbeID] {
+			seen[result.ProbeID] = true
+			probeIDs = append(probeIDs, result.ProbeID)
+		}
+	}
+
+	probes, err := client.GetProbesByID(ctx, probeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch probes: %w", err)
+	}
+
+	probeASNs := make(map[int]int, len(probes))
+	for id, probe := range probes {
+		probeASNs[id] = probe.ASNV4
+	}
+	return probeASNs, nil
+}
+
+// probeASNsFromResults maps the reporting probes to the ASN of their public
+// source address in RIPEstat, so saved results can be analyzed without the
+// Atlas API
+func probeASNsFromResults(ctx context.Context, results []atlas.TracerouteResult) (map[int]int, error) {
+	probeASNs := make(map[int]int)
+	for _, result := range results {
+		if _, done := probeASNs[result.ProbeID]; done || result.From == "" {
+			continue
+		}
+
+		asn, err := analyzer.LookupASN(ctx, result.From)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			asn = 0
+		}
+		probeASNs[result.ProbeID] = asn
+	}
+	return probeASNs, nil
+}
+
+// fillAllocationsFromResults groups the reporting probes by ASN and records
+// them as the report's probe distribution. A probe that reported in several
+// merged measurements counts once.
+func fillAllocationsFromResults(report *atlas.Report, results []atlas.TracerouteResult, probeASNs map[int]int) {
+	byASN := make(map[int][]int)
+	for _, result := range results {
+		asn := probeASNs[result.ProbeID]
+		if !slices.Contains(byASN[asn], result.ProbeID) {
+			byASN[asn] = append(byASN[asn], result.ProbeID)
+		}
+	}
+
+	for _, asn := range slices.Sorted(maps.Keys(byASN)) {
+		if asn == 0 {
+			continue
+		}
+		report.RequestedASNs = append(report.RequestedASNs, asn)
+		report.ASNsWithProbes = append(report.ASNsWithProbes, asn)
+		report.Allocations = append(report.Allocations, atlas.ProbeAllocation{
+			ASN:       asn,
+			Available: len(byASN[asn]),
+			Allocated: len(byASN[asn]),
+			ProbeIDs:  byASN[asn],
+		})
+	}
+	report.TotalProbes = countProbes(results)
+}
+
+// analyzeTraceroutes runs the common ASN and path diversity analysis on
+// results and fills in the analysis part of the report
+func analyzeTraceroutes(ctx context.Context, report *atlas.Report, results []atlas.TracerouteResult, threshold float64) error {
+	fmt.Printf("🔬 Analyzing common ASN paths...\n\n")
+	commonASNs, err := analyzer.AnalyzeCommonASNs(ctx, results, threshold)
+	if err != nil {
+		return fmt.Errorf("failed to analyze results: %w", err)
+	}
+
+	// Calculate path statistics
+	uniquePaths, avgHops, maxHops, incompletePaths := analyzer.CalculatePathStats(results)
+
+	report.CommonASNs = commonASNs
+	report.Threshold = threshold
+	report.UniquePaths = uniquePaths
+	report.AvgHops = avgHops
+	report.MaxHops = maxHops
+	report.IncompletePaths = incompletePaths
+	report.ResultCount = len(results)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+// runDemoTraceroute runs a traceroute from both demo eyeball ASNs, whose
+// measurement gets ID 1000001 on a fresh server
+func runDemoTraceroute(t *testing.T, srv *atlastest.Server, args ...string) {
+	t.Helper()
+
+	args = append([]string{"traceroute",
+		"--asns", fmt.Sprintf("%d,%d", atlastest.DemoEyeballA, atlastest.DemoEyeballB),
+		"--target", "8.8.8.8",
+	}, args...)
+	if out, err := runCLI(t, srv, args...); err != nil {
+		t.Fatalf("traceroute: %v\n%s", err, out)
+	}
+}
+
+func TestAnalyzeDuplicateMeasurementIDs(t *testing.T) {
+	srv := newFakeAtlas(t)
+	runDemoTraceroute(t, srv)
+
+	out, err := runCLI(t, srv, "analyze", "--msm", "1000001,1000001")
+	if err != nil {
+		t.Fatalf("analyze: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		"Fetching results of 1 measurement(s)",
+		"1000001: 7 traceroute results",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "1000001: "); n != 1 {
+		t.Errorf("measurement 1000001 fetched %d times, want once", n)
+	}
+}
+
+func TestAnalyzeSavedResults(t *testing.T) {
+	srv := newFakeAtlas(t)
+	path := filepath.Join(t.TempDir(), "results.json")
+	runDemoTraceroute(t, srv, "--save", path)
+
+	out, err := runCLI(t, srv, "analyze", "--input", path, "--threshold", "0.5")
+	if err != nil {
+		t.Fatalf("analyze --input: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		path + ": 7 traceroute results",
+		fmt.Sprintf("AS%d", atlastest.DemoTransitA),
+		fmt.Sprintf("AS%d", atlastest.DemoEyeballB),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAnalyzeCountsDistinctProbes(t *testing.T) {
+	srv := newFakeAtlas(t)
+	path := filepath.Join(t.TempDir(), "results.json")
+	runDemoTraceroute(t, srv, "--save", path)
+
+	// The same 7 probes reporting twice are still 7 probes
+	out, err := runCLI(t, srv, "analyze", "--input", path, "--input", path)
+	if err != nil {
+		t.Fatalf("analyze --input: %v\n%s", err, out)
+	}
+
+	if !regexp.MustCompile(`Total:\s+7 probes`).MatchString(out) {
+		t.Errorf("output lacks 7 probes in total:\n%s", out)
+	}
+	if !regexp.MustCompile(fmt.Sprintf(`AS%d\s.*\s3 probes \(`, atlastest.DemoEyeballA)).MatchString(out) {
+		t.Errorf("output lacks 3 probes in AS%d:\n%s", atlastest.DemoEyeballA, out)
+	}
+	if !strings.Contains(out, "Frequency: 100.0% (7/7 probes)") {
+		t.Errorf("output lacks the transit crossed by all 7 probes:\n%s", out)
+	}
+}
+
+func TestAnalyzeOffline(t *testing.T) {
+	srv := newFakeAtlas(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+	runDemoTraceroute(t, srv, "--save", path)
+
+	routes := filepath.Join(dir, "routes.txt")
+	table := fmt.Sprintf(`# offline route table
+192.0.2.0/25    AS%d
+192.0.2.128/25  %d
+198.51.100.0/24 AS%d Offline Transit Name
+`, atlastest.DemoEyeballA, atlastest.DemoEyeballB, atlastest.DemoTransitA)
+	if err := os.WriteFile(routes, []byte(table), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A server without routes answers no RIPEstat lookup, so every ASN
+	// in the report must come from the route table
+	empty := atlastest.NewServer()
+	t.Cleanup(empty.Close)
+
+	out, err := runCLI(t, empty, "analyze", "--input", path, "--offline", "--routes", routes, "--threshold", "0.5")
+	if err != nil {
+		t.Fatalf("analyze --offline: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		fmt.Sprintf("AS%d - Offline Transit Name", atlastest.DemoTransitA),
+		fmt.Sprintf("AS%d", atlastest.DemoEyeballB),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, fmt.Sprintf("AS%d", atlastest.DemoTransitB)) {
+		t.Errorf("output has an ASN missing from the route table:\n%s", out)
+	}
+}
+
+func TestAnalyzeOfflineFlags(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	for _, args := range [][]string{
+		{"analyze", "--msm", "1000001", "--offline"},
+		{"analyze", "--input", "results.json", "--routes", "routes.txt"},
+	} {
+		if _, err := runCLI(t, srv, args...); err == nil {
+			t.Errorf("%v succeeded", args)
+		}
+	}
+}
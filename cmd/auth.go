@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/internal/config"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	authCmd.AddCommand(authCheckCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect the configured RIPE Atlas API keys",
+}
+
+var authCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the API keys are valid and show their permissions and credits",
+	Long: `Ask RIPE Atlas whether the configured API keys are valid, which permissions
+they hold and when they expire, and show the account's credit balance.
+Exits with status 3 if a key is rejected, disabled or expired.
+
+Example:
+  ripeatlas auth check
+  ripeatlas auth check --api-key 01234567-89ab-cdef-0123-456789abcdef`,
+	Args:    cobra.NoArgs,
+	PreRunE: requireAPIKey,
+	RunE:    runAuthCheck,
+}
+
+// keyPermissions are the permissions auth check reports, in display order
+var keyPermissions = []struct {
+	permission, label string
+}{
+	{atlas.PermissionScheduleMeasurement, "Create measurements"},
+	{atlas.PermissionStopMeasurement, "Stop measurements"},
+	{atlas.PermissionViewResults, "Read results"},
+	{atlas.PermissionViewCredits, "View credits"},
+}
+
+func runAuthCheck(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	client := newAtlasClient()
+
+	var failed error
+
+	for _, setting := range []string{config.SettingAPIKey, config.SettingReadAPIKey} {
+		key := cfg.APIKey
+		if setting == config.SettingReadAPIKey {
+			if key = cfg.ReadAPIKey; key == "" {
+				continue
+			}
+		}
+		fmt.Printf("🔑 %s %s (from %s)\n", setting, config.Redact(key), cfg.Sources[setting])
+
+		apiKey, err := client.GetKey(ctx, key)
+		if err != nil {
+			fmt.Printf("   ❌ %v\n\n", err)
+			failed = errors.Join(failed, fmt.Errorf("%s: %w", setting, err))
+			continue
+		}
+
+		if usable, reason := apiKey.Usable(time.Now()); usable {
+			fmt.Printf("   ✅ Valid\n")
+		} else {
+			fmt.Printf("   ❌ Not usable: %s\n", reason)
+			failed = errors.Join(failed, fmt.Errorf("%w: %s is %s", atlas.ErrAuth, setting, reason))
+		}
+
+		if apiKey.Label != "" {
+			fmt.Printf("   Label: %s\n", apiKey.Label)
+		}
+		if apiKey.ValidTo != nil {
+			fmt.Printf("   Expires: %s UTC\n", apiKey.ValidTo.UTC().Format(time.DateTime))
+		} else {
+			fmt.Printf("   Expires: never\n")
+		}
+
+		fmt.Printf("   Permissions:\n")
+		for _, p := range keyPermissions {
+			mark := "✗"
+			if apiKey.HasPermission(p.permission) {
+				mark = "✓"
+			}
+			fmt.Printf("     %s %s\n", mark, p.label)
+		}
+		fmt.Println()
+	}
+
+	credits, err := client.GetCredits(ctx)
+	if err != nil {
+		fmt.Printf("💰 Credits: unavailable (%v)\n", err)
+	} else {
+		fmt.Printf("💰 Credits: %d\n", credits.CurrentBalance)
+		fmt.Printf("   Estimated daily income: %d\n", credits.EstimatedDailyIncome)
+		fmt.Printf("   Estimated daily spending: %d\n", credits.EstimatedDailyExpenditure)
+	}
+
+	return failed
+}
+
+// checkMeasurementKey checks up front that RIPE Atlas accepts the API key for
+// scheduling, before any probes are fetched or prompts shown. Commands that
+// create measurements run it at the start of RunE (see startRun), after cobra
+// has validated their flags, so a usage error costs no API request.
+func checkMeasurementKey(ctx context.Context, client *atlas.Client) error {
+	apiKey, err := client.GetKey(ctx, cfg.APIKey)
+	switch {
+	case errors.Is(err, atlas.ErrAuth):
+		return fmt.Errorf("API key rejected, run 'ripeatlas auth check' for details: %w", err)
+	case err != nil:
+		// The key lookup is only a courtesy, measurement creation reports auth errors too
+		fmt.Fprintf(os.Stderr, "⚠️  Could not verify the API key: %v\n", err)
+		return nil
+	}
+
+	if usable, reason := apiKey.Usable(time.Now()); !usable {
+		return fmt.Errorf("%w: API key is %s", atlas.ErrAuth, reason)
+	}
+	if !apiKey.HasPermission(atlas.PermissionScheduleMeasurement) {
+		return fmt.Errorf("%w: API key is not allowed to create measurements", atlas.ErrAuth)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestRequireMeasurementKey(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		setup func(srv *atlastest.Server)
+		want  string // empty if the measurement is created
+	}{
+		{
+			name:  "usable key",
+			setup: func(srv *atlastest.Server) {},
+		},
+		{
+			name:  "unknown key",
+			setup: func(srv *atlastest.Server) { srv.APIKey = "other" },
+			want:  "API key rejected",
+		},
+		{
+			name:  "expired key",
+			setup: func(srv *atlastest.Server) { srv.KeyValidTo = &expired },
+			want:  "API key is expired",
+		},
+		{
+			name: "key without the schedule permission",
+			setup: func(srv *atlastest.Server) {
+				srv.KeyPermissions = []string{atlas.PermissionViewResults, atlas.PermissionStopMeasurement}
+			},
+			want: "not allowed to create measurements",
+		},
+	}
+
+	targetsFile := filepath.Join(t.TempDir(), "targets.txt")
+	if err := os.WriteFile(targetsFile, []byte("8.8.8.8\n1.1.1.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	commands := [][]string{
+		{"ping", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8"},
+		{"traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--targets-file", targetsFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, args := range commands {
+				srv := newFakeAtlas(t)
+				tt.setup(srv)
+
+				out, err := runCLI(t, srv, args...)
+				_, created := srv.Measurement(1000001)
+
+				if tt.want == "" {
+					if err != nil || !created {
+						t.Errorf("%s = %v, created %v, want a measurement\n%s", args[0], err, created, out)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.want) || ExitCode(err) != ExitAuth {
+					t.Errorf("%s = %v, want %q with exit code %d", args[0], err, tt.want, ExitAuth)
+				}
+				if created {
+					t.Errorf("%s created a measurement with an unusable key", args[0])
+				}
+				if strings.Contains(out, "Initializing") {
+					t.Errorf("%s ran despite the key check:\n%s", args[0], out)
+				}
+			}
+		})
+	}
+}
+
+func TestUsageErrorsBeforeKeyCheck(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.APIKey = "other" // the key check would fail if it ran
+
+	out, err := runCLI(t, srv, "ping", "--asns", fmt.Sprint(atlastest.DemoEyeballA))
+	if err == nil || !strings.Contains(err.Error(), `required flag(s) "target" not set`) {
+		t.Errorf("ping without --target = %v, want a missing flag error\n%s", err, out)
+	}
+	if ExitCode(err) == ExitAuth {
+		t.Errorf("ping without --target exited with the auth error code")
+	}
+}
+
+func TestAuthCheckCreditsUseMainKey(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.APIKey = "test"
+	srv.Credits = 4242
+
+	// The server only knows the main key, so the read key itself is rejected
+	out, err := runCLI(t, srv, "auth", "check", "--read-api-key", "reader")
+	if ExitCode(err) != ExitAuth {
+		t.Errorf("auth check = %v, want the read key rejected", err)
+	}
+
+	if !strings.Contains(out, "💰 Credits: 4242") {
+		t.Errorf("output lacks the credit balance:\n%s", out)
+	}
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+// batchTarget is one target of a --targets-file batch
+type batchTarget struct {
+	Name     string // as listed in the file, e.g. aws_us-west-2
+	Resolved string // the measured address or host name
+	IDs      []int  // measurements of all shards
+}
+
+// readTargetsFile reads one target per line: an IP address, a host name or an
+// aws_<region>. Blank lines, text after # and repeated targets are skipped.
+func readTargetsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open targets file: %w", err)
+	}
+	defer f.Close()
+
+	var targets []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 1 {
+			return nil, fmt.Errorf("%s:%d: expected one target per line", path, n)
+		}
+		if !slices.Contains(targets, fields[0]) {
+			targets = append(targets, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets in %s", path)
+	}
+	return targets, nil
+}
+
+// runTracerouteBatch runs one traceroute per target of --targets-file, all
+// from the same probe selection, through the measurement scheduler. It prints
+// a report per target and the combined matrix.
+func runTracerouteBatch(cmd *cobra.Command) error {
+	startTime := time.Now()
+
+	ctx, stopSignals := signalContext(cmd)
+	defer stopSignals()
+
+	client := newAtlasClient()
+	if err := checkMeasurementKey(ctx, client); err != nil {
+		return err
+	}
+
+	asns, err := parseSourceASNs(asnsFlag)
+	if err != nil {
+		return err
+	}
+
+	names, err := readTargetsFile(targetsFileFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas traceroute batch of %d targets...\n\n", len(names))
+
+	// Resolve targets; all of them are measured from the same probes, so
+	// they must share one address family
+	targets := make([]*batchTarget, len(names))
+	af := 0
+	for i, name := range names {
+		resolved, err := resolveTarget(name)
+		if err != nil {
+			return err
+		}
+		targetAF, err := resolveAF(ctx, afFlag, resolved)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if af != 0 && targetAF != af {
+			return fmt.Errorf("targets mix IPv4 and IPv6 addresses, pick one with --af or split the file")
+		}
+		af = targetAF
+		targets[i] = &batchTarget{Name: name, Resolved: resolved}
+	}
+
+	definitions := make([]atlas.MeasurementDefinition, len(targets))
+	for i, target := range targets {
+		definitions[i], err = tracerouteOpts.definition(af, target.Resolved,
+			fmt.Sprintf("Traceroute to %s from %s", target.Name, describeSources(asns)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.Name, err)
+		}
+	}
+
+	sel, err := selectProbes(ctx, client, asns, af)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Creating traceroute measurements...\n")
+	fmt.Printf("   Targets: %d\n", len(targets))
+	fmt.Printf("   Probes: %d\n", sel.total())
+
+	// One request for the cost check and manifest; the dry run and scheduler
+	// get one request per target and shard
+	batchReq := atlas.MeasurementRequest{
+		Definitions: definitions,
+		Probes:      sel.probeSets(),
+		IsOneoff:    true,
+	}
+	checkCost(ctx, client, batchReq)
+
+	var (
+		requests []atlas.MeasurementRequest
+		owners   []*batchTarget
+	)
+	for i, def := range definitions {
+		req := batchReq
+		req.Definitions = []atlas.MeasurementDefinition{def}
+		for _, shard := range atlas.ShardRequest(req, atlas.MaxProbesPerMeasurement) {
+			requests = append(requests, shard)
+			owners = append(owners, targets[i])
+		}
+	}
+	fmt.Printf("📋 Scheduling %d measurements, at most %d at a time and %d per target\n\n",
+		len(requests), atlas.MaxConcurrentMeasurements, atlas.MaxOneoffsPerTarget)
+
+	if dryRunFlag {
+		for i, req := range requests {
+			body, err := json.MarshalIndent(req, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode request: %w", err)
+			}
+			fmt.Printf("Request %d of %d (%s):\n", i+1, len(requests), owners[i].Name)
+			fmt.Printf("🧪 Dry run, nothing was created. Request for POST %s/measurements/:\n%s\n", apiURLFlag, body)
+		}
+		if selectorOpts.manifest != "" {
+			return sel.writeManifest(batchReq, nil)
+		}
+		return nil
+	}
+
+	active, err := client.ActiveMeasurements(ctx)
+	if err != nil {
+		fmt.Printf("   ⚠️  Could not check the running measurements: %v\n", err)
+	} else if len(active) > 0 {
+		fmt.Printf("   %d measurements of the account are already running\n\n", len(active))
+	}
+
+	failures := make(map[int]error)
+	completed := 0
+	scheduler := atlas.Scheduler{
+		Client: client,
+		Active: active,
+		Created: func(i int, ids []int) {
+			owners[i].IDs = append(owners[i].IDs, ids...)
+			for _, id := range ids {
+				fmt.Printf("   ✅ Measurement created: ID %d (%s)\n", id, owners[i].Name)
+			}
+		},
+		Finished: func(id int, err error) {
+			completed++
+			if err != nil {
+				failures[id] = err
+				fmt.Printf("   ⚠️  Measurement %d ended without results: %v\n", id, err)
+				return
+			}
+			fmt.Printf("   ⏳ Measurement %d completed (%d of %d)\n", id, completed, len(requests))
+		},
+		KeepWaiting: func(ctx context.Context, unfinished []int) (bool, error) {
+			fmt.Printf("\n⏱️  No measurement has completed for 5 minutes, %d still running:\n", len(unfinished))
+			for _, id := range unfinished {
+				fmt.Printf("   https://atlas.ripe.net/measurements/%d\n", id)
+			}
+			fmt.Println()
+			return confirm(ctx, "❓ Wait for another 5 minutes? (y/n): ")
+		},
+	}
+
+	fmt.Printf("⏳ Waiting for measurements to complete... (Ctrl-C stops them early)\n")
+	_, unfinished, err := scheduler.Run(ctx, requests)
+	interrupted := ctx.Err() != nil
+	gaveUp := errors.Is(err, atlas.ErrWaitTimeout) && !interrupted
+	if err != nil && !interrupted && !gaveUp {
+		if len(unfinished) > 0 {
+			stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			stopMeasurements(stopCtx, client, unfinished, "Scheduling failed")
+			cancel()
+		}
+		return err
+	}
+	fmt.Println()
+
+	// Report what arrived so far, as after Ctrl-C
+	if gaveUp {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		stopMeasurements(stopCtx, client, unfinished, "Stopped waiting")
+		cancel()
+	}
+
+	if interrupted {
+		var cancel context.CancelFunc
+		ctx, cancel = stopInterrupted(stopSignals, client, unfinished...)
+		defer cancel()
+	}
+
+	if selectorOpts.manifest != "" {
+		ids := make([][]int, len(targets))
+		for i, target := range targets {
+			ids[i] = target.IDs
+		}
+		if err := sel.writeManifest(batchReq, ids); err != nil {
+			fmt.Fprintf(os.Stderr, "   ⚠️  %v\n", err)
+		}
+	}
+
+	fmt.Printf("📥 Fetching measurement results...\n")
+	var (
+		batch  atlas.BatchReport
+		allRaw []json.RawMessage
+	)
+	for _, target := range targets {
+		var reasons []string
+		for _, id := range target.IDs {
+			if err := failures[id]; err != nil {
+				reasons = append(reasons, fmt.Sprintf("measurement %d: %v", id, err))
+			}
+		}
+
+		raw, err := fetchResults(ctx, target.IDs, client.GetMeasurementResultsRaw)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("failed to fetch results: %v", err))
+		}
+		results, err := atlas.DecodeResults[atlas.TracerouteResult](raw)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			results = nil
+		}
+		if len(target.IDs) == 0 {
+			reasons = append(reasons, "not scheduled before the batch stopped")
+		}
+		if len(results) == 0 {
+			if len(reasons) == 0 {
+				reasons = append(reasons, "no probe reported")
+			}
+			batch.Failed = append(batch.Failed, atlas.BatchFailure{Target: target.Name, Reason: strings.Join(reasons, "; ")})
+			continue
+		}
+		allRaw = append(allRaw, raw...)
+
+		fmt.Printf("   %s: %d traceroute results\n", target.Name, len(results))
+
+		report := atlas.Report{
+			MeasurementID:     target.IDs[0],
+			MeasurementIDs:    target.IDs,
+			Target:            target.Name,
+			CreatedAt:         startTime,
+			Duration:          time.Since(startTime),
+			RequestedASNs:     sel.ASNs,
+			ASNsWithProbes:    sel.ASNsWithProbes,
+			ASNsWithoutProbes: sel.ASNsWithoutProbes,
+			Allocations:       sel.Allocations,
+			Allocator:         sel.Allocator,
+			Selectors:         sel.selectorNames(),
+			TotalProbes:       sel.total(),
+			Partial:           interrupted || gaveUp,
+			AF:                af,
+		}
+		if err := analyzeTraceroutes(ctx, &report, results, thresholdFlag); err != nil {
+			return fmt.Errorf("%s: %w", target.Name, err)
+		}
+		batch.Reports = append(batch.Reports, report)
+	}
+	fmt.Println()
+
+	if len(batch.Reports) == 0 {
+		fmt.Println(atlas.GenerateBatchReport(batch))
+		return fmt.Errorf("none of the %d targets has results", len(targets))
+	}
+
+	if saveFlag != "" {
+		if err := atlas.WriteResultsFile(saveFlag, allRaw); err != nil {
+			return err
+		}
+		fmt.Printf("💾 Saved results of all targets to %s\n\n", saveFlag)
+	}
+
+	for _, report := range batch.Reports {
+		fmt.Println(atlas.GenerateReport(report))
+	}
+	fmt.Println(atlas.GenerateBatchReport(batch))
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/cmingou/ripeatlas-cli/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the effective configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective configuration and where each value came from",
+	Long: `Show the effective configuration and where each value came from.
+
+Values are resolved from, highest priority first: command-line flags, the
+file given with --config, the RIPE_ATLAS_API and RIPE_ATLAS_READ_API
+environment variables, $XDG_CONFIG_HOME/ripeatlas/config.toml, and last the
+legacy ~/.env.key. API keys are redacted.
+
+Example:
+  ripeatlas config show
+  ripeatlas config show --config ./atlas.toml --profile edge-tcp`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	// Without a key cfg still holds everything else, only a broken file leaves it nil
+	if cfg == nil {
+		return fmt.Errorf("error loading config: %w", cfgErr)
+	}
+
+	fmt.Println("Config files (lowest priority first):")
+	for _, file := range cfg.Files {
+		status := "loaded"
+		if !file.Found {
+			status = "not found"
+		}
+		fmt.Printf("  %s (%s, %s)\n", file.Path, file.Origin, status)
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+
+	writeKey := func(setting, value string) {
+		if value == "" {
+			fmt.Fprintf(w, "%s\t(not set)\t-\n", setting)
+			return
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", setting, config.Redact(value), cfg.Sources[setting])
+	}
+	writeKey(config.SettingAPIKey, cfg.APIKey)
+	if cfg.ReadAPIKey == "" && cfg.APIKey != "" {
+		fmt.Fprintf(w, "%s\t(uses api_key)\t-\n", config.SettingReadAPIKey)
+	} else {
+		writeKey(config.SettingReadAPIKey, cfg.ReadAPIKey)
+	}
+
+	// Global flags, which a profile may set too
+	rootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if slices.Contains(unprofiledFlags, flag.Name) || flag.Name == "help" {
+			return
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", flag.Name, flag.Value, flagSource(cmd, flag.Name))
+	})
+	w.Flush()
+
+	if len(cfg.Profiles) == 0 {
+		fmt.Println("\nNo profiles defined.")
+		return nil
+	}
+
+	fmt.Println("\nProfiles:")
+	for _, name := range slices.Sorted(maps.Keys(cfg.Profiles)) {
+		marker := ""
+		if name == profileFlag {
+			marker = " (selected)"
+		}
+		fmt.Printf("  %s%s, from %s\n", name, marker, cfg.Sources["profile."+name])
+
+		profile := cfg.Profiles[name]
+		for _, key := range slices.Sorted(maps.Keys(profile)) {
+			fmt.Printf("    %s = %s\n", key, profile[key])
+		}
+	}
+
+	return nil
+}
+
+// flagSource describes where the effective value of a flag of cmd came from
+func flagSource(cmd *cobra.Command, name string) string {
+	switch {
+	case profileApplied[name]:
+		return fmt.Sprintf("profile %s (%s)", profileFlag, cfg.Sources["profile."+profileFlag])
+	case cmd.Flags().Changed(name):
+		return "flag --" + name
+	}
+	return "default"
+}
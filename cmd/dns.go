@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dnsASNsFlag     string
+	dnsQueryFlag    string
+	dnsTypeFlag     string
+	dnsServerFlag   string
+	dnsProtocolFlag string
+	dnsExpectFlag   string
+	dnsAFFlag       string
+)
+
+func init() {
+	dnsCmd.Flags().StringVar(&dnsASNsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	dnsCmd.Flags().StringVar(&dnsQueryFlag, "query", "", "Name to resolve (required)")
+	dnsCmd.Flags().StringVar(&dnsTypeFlag, "type", "A", "Query type (A, AAAA, CNAME, MX, NS, TXT, ...)")
+	dnsCmd.Flags().StringVar(&dnsServerFlag, "server", "", "Query this name server instead of the probe's own resolvers")
+	dnsCmd.Flags().StringVar(&dnsProtocolFlag, "protocol", "UDP", "Transport protocol (UDP or TCP)")
+	dnsCmd.Flags().StringVar(&dnsExpectFlag, "expect", "", "Comma-separated addresses or prefixes every answer must fall in")
+	dnsCmd.Flags().StringVar(&dnsAFFlag, "af", "auto", "Address family: 4, 6 or auto (the family of --server, IPv4 without it)")
+	selectorOpts.addFlags(dnsCmd)
+	addDryRunFlag(dnsCmd)
+
+	dnsCmd.MarkFlagRequired("query")
+
+	rootCmd.AddCommand(dnsCmd)
+}
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Run DNS measurement and compare answers across ASNs",
+	Long: `Run DNS measurements from specified ASNs, using each probe's own resolvers
+or a named server. Groups answers by source ASN, shows response times and flags
+answers that look inconsistent or hijacked: bogon addresses, addresses outside
+--expect, or (without --expect) answers that differ from the most common one.
+
+Example:
+  ripeatlas dns --asns 5384,7713 --query example.com
+  ripeatlas dns --asns 5384,7713 --query example.com --type AAAA --server 9.9.9.9
+  ripeatlas dns --asns 5384,7713 --query example.com --server 2620:fe::fe
+  ripeatlas dns --asns 5384,7713 --query example.com --expect 93.184.216.0/24`,
+	PreRunE: requireAPIKey,
+	RunE:    runDNS,
+}
+
+func runDNS(cmd *cobra.Command, args []string) error {
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
+
+	asns, err := parseSourceASNs(dnsASNsFlag)
+	if err != nil {
+		return err
+	}
+
+	expected, err := analyzer.ParseExpectedAnswers(dnsExpectFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --expect: %w", err)
+	}
+
+	queryType := strings.ToUpper(dnsTypeFlag)
+	protocol := strings.ToUpper(dnsProtocolFlag)
+	if protocol != "UDP" && protocol != "TCP" {
+		return fmt.Errorf("invalid protocol %q: must be UDP or TCP", dnsProtocolFlag)
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas DNS measurement...\n\n")
+
+	// Without --server the probes query their own resolvers, so auto means IPv4
+	af := 4
+	if dnsServerFlag != "" || dnsAFFlag != "auto" {
+		if af, err = resolveAF(run.ctx, dnsAFFlag, dnsServerFlag); err != nil {
+			return err
+		}
+	}
+
+	resolver := dnsServerFlag
+	if resolver == "" {
+		resolver = "probe resolvers"
+	}
+
+	def := atlas.MeasurementDefinition{
+		Type:             "dns",
+		AF:               af,
+		Target:           dnsServerFlag,
+		Description:      fmt.Sprintf("DNS %s %s via %s from %s", queryType, dnsQueryFlag, resolver, describeSources(asns)),
+		Protocol:         protocol,
+		QueryClass:       "IN",
+		QueryType:        queryType,
+		QueryArgument:    dnsQueryFlag,
+		UseProbeResolver: dnsServerFlag == "",
+		SetRDBit:         true,
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	if err := run.selectProbes(asns, af); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Creating DNS measurement...\n")
+	fmt.Printf("   Query: %s IN %s\n", dnsQueryFlag, queryType)
+	fmt.Printf("   Resolver: %s\n", resolver)
+	fmt.Printf("   Probes: %d\n", run.sel.total())
+
+	if created, err := run.create(def); !created {
+		return err
+	}
+
+	results, err := runResults(run, "DNS", run.client.GetDNSResults)
+	if err != nil {
+		return err
+	}
+
+	probes := run.probesOf(resultProbeIDs(results, func(r atlas.DNSResult) int { return r.ProbeID }))
+
+	report := atlas.DNSReport{
+		Report: run.baseReport(dnsQueryFlag, len(results)),
+		DNSAnalysis: analyzer.AnalyzeDNS(results, queryType, func(r atlas.DNSResult) int {
+			return probes[r.ProbeID].ASNV4
+		}, expected),
+		Query:     dnsQueryFlag,
+		QueryType: queryType,
+		Resolver:  dnsServerFlag,
+		Expected:  splitList(dnsExpectFlag),
+	}
+
+	fmt.Println(atlas.GenerateDNSReport(report))
+
+	return nil
+}
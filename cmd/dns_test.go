@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestDNSAddressFamily(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int // 0 when an error is expected
+	}{
+		{nil, 4},
+		{[]string{"--server", "9.9.9.9"}, 4},
+		{[]string{"--server", "2620:fe::fe"}, 6},
+		{[]string{"--af", "6"}, 6},
+		{[]string{"--server", "9.9.9.9", "--af", "6"}, 0},
+	}
+
+	for _, tt := range tests {
+		srv := newFakeAtlas(t)
+
+		args := append([]string{"dns", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--query", "example.com"}, tt.args...)
+		out, err := runCLI(t, srv, args...)
+		status, created := srv.Measurement(1000001)
+		if tt.want == 0 {
+			if err == nil || created {
+				t.Errorf("dns %v = %v, want an error before any measurement\n%s", tt.args, err, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("dns %v: %v\n%s", tt.args, err, out)
+			continue
+		}
+		if status.AF != tt.want {
+			t.Errorf("dns %v measured IPv%d, want IPv%d", tt.args, status.AF, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dualstackASNsFlag      string
+	dualstackTargetFlag    string
+	dualstackThresholdFlag float64
+
+	dualstackOpts tracerouteOptions
+)
+
+func init() {
+	dualstackCmd.Flags().StringVar(&dualstackASNsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	dualstackCmd.Flags().StringVar(&dualstackTargetFlag, "target", "", "Dual-stack target host name (required)")
+	dualstackCmd.Flags().Float64Var(&dualstackThresholdFlag, "threshold", 0.8, "Threshold for common ASN (default: 0.8 = 80%)")
+	dualstackOpts.addFlags(dualstackCmd)
+	selectorOpts.addFlags(dualstackCmd)
+	addDryRunFlag(dualstackCmd)
+
+	dualstackCmd.MarkFlagRequired("target")
+
+	rootCmd.AddCommand(dualstackCmd)
+}
+
+var dualstackCmd = &cobra.Command{
+	Use:   "dualstack",
+	Short: "Compare IPv4 and IPv6 paths to a dual-stack host",
+	Long: `Run paired IPv4 and IPv6 traceroutes from the same dual-stack probes in the
+specified ASNs to a host name with both A and AAAA records. Reports, per probe
+and in aggregate, where the AS paths diverge, which ASNs appear over only one
+address family and the RTT difference between IPv6 and IPv4.
+
+Example:
+  ripeatlas dualstack --asns 5384,7713 --target example.com`,
+	PreRunE: requireAPIKey,
+	RunE:    runDualStack,
+}
+
+func runDualStack(cmd *cobra.Command, args []string) error {
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
+
+	asns, err := parseSourceASNs(dualstackASNsFlag)
+	if err != nil {
+		return err
+	}
+
+	if _, err := netip.ParseAddr(dualstackTargetFlag); err == nil {
+		return fmt.Errorf("target must be a host name with both A and AAAA records, not an address")
+	}
+
+	definitions := make([]atlas.MeasurementDefinition, 0, 2)
+	for _, af := range []int{4, 6} {
+		def, err := dualstackOpts.definition(af, dualstackTargetFlag,
+			fmt.Sprintf("Dual-stack IPv%d traceroute to %s from %s", af, dualstackTargetFlag, describeSources(asns)))
+		if err != nil {
+			return err
+		}
+		definitions = append(definitions, def)
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas dual-stack measurement...\n\n")
+
+	// Only probes whose IPv6 works can measure both families, a probe with
+	// an address but broken IPv6 would show up as a false divergence
+	err = run.selectProbes(asns, 4, func(p atlas.Probe) bool {
+		return p.AddressV6 != "" && p.HasTag(atlas.TagIPv6Works)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Server-side sets get the same IPv6 tag requirement
+	for i, set := range run.sel.Selectors {
+		tags := atlas.ProbeTags{Include: []string{atlas.TagIPv6Works}}
+		if set.Tags != nil {
+			tags.Include = append(tags.Include, set.Tags.Include...)
+			tags.Exclude = set.Tags.Exclude
+		}
+		run.sel.Selectors[i].Tags = &tags
+	}
+
+	fmt.Printf("🚀 Creating IPv4 and IPv6 traceroute measurements...\n")
+	fmt.Printf("   Target: %s\n", dualstackTargetFlag)
+	fmt.Printf("   Probes: %d\n", run.sel.total())
+
+	if created, err := run.create(definitions...); !created {
+		return err
+	}
+
+	fmt.Printf("📥 Fetching measurement results...\n")
+	resultsV4, err := fetchResults(run.ctx, run.ids[0], run.client.GetMeasurementResults)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IPv4 results: %w", err)
+	}
+	resultsV6, err := fetchResults(run.ctx, run.ids[1], run.client.GetMeasurementResults)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IPv6 results: %w", err)
+	}
+
+	fmt.Printf("   Retrieved %d IPv4 and %d IPv6 traceroute results\n\n", len(resultsV4), len(resultsV6))
+
+	if len(resultsV4) == 0 || len(resultsV6) == 0 {
+		if run.interrupted {
+			return fmt.Errorf("interrupted before results in both address families arrived: %w", context.Canceled)
+		}
+		return fmt.Errorf("need results in both address families to compare paths")
+	}
+
+	fmt.Printf("🔬 Comparing IPv4 and IPv6 paths...\n\n")
+
+	probes := run.probesOf(resultProbeIDs(slices.Concat(resultsV4, resultsV6), func(r atlas.TracerouteResult) int {
+		return r.ProbeID
+	}))
+	comparison, err := analyzer.CompareDualStack(run.ctx, resultsV4, resultsV6, func(probeID int) int {
+		return probes[probeID].ASNV4
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compare paths: %w", err)
+	}
+
+	commonV4, err := analyzer.AnalyzeCommonASNs(run.ctx, resultsV4, dualstackThresholdFlag)
+	if err != nil {
+		return fmt.Errorf("failed to analyze IPv4 ASNs: %w", err)
+	}
+	commonV6, err := analyzer.AnalyzeCommonASNs(run.ctx, resultsV6, dualstackThresholdFlag)
+	if err != nil {
+		return fmt.Errorf("failed to analyze IPv6 ASNs: %w", err)
+	}
+
+	base := run.baseReport(dualstackTargetFlag, len(resultsV4)+len(resultsV6))
+	base.Threshold = dualstackThresholdFlag
+
+	report := atlas.DualStackReport{
+		Report:              base,
+		DualStackComparison: comparison,
+		CommonV4:            commonV4,
+		CommonV6:            commonV6,
+	}
+
+	fmt.Println(atlas.GenerateDualStackReport(report))
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestDualStackSkipsProbesWithBrokenIPv6(t *testing.T) {
+	srv := newFakeAtlas(t)
+	// An IPv6 address without the IPv6 Works tag
+	srv.AddProbes(atlas.Probe{
+		ID: 1004, AddressV4: "192.0.2.14", AddressV6: "2001:db8:a::14",
+		ASNV4: atlastest.DemoEyeballA, ASNV6: atlastest.DemoEyeballA, CountryCode: "NL",
+		Status: atlas.Status{ID: 1, Name: "Connected"}, IsPublic: true,
+		Tags: []atlas.ProbeTag{{Name: "IPv4 Works", Slug: atlas.TagIPv4Works}},
+	})
+
+	out, err := runCLI(t, srv, "dualstack", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "example.com")
+	if err != nil {
+		t.Fatalf("dualstack: %v\n%s", err, out)
+	}
+
+	for _, id := range []int{1000001, 1000002} {
+		status, ok := srv.Measurement(id)
+		if !ok {
+			t.Fatalf("measurement %d was not created", id)
+		}
+		if status.ProbesRequested != 3 {
+			t.Errorf("measurement %d has %d probes, want 3 without probe 1004", id, status.ProbesRequested)
+		}
+	}
+	if strings.Contains(out, "1004") {
+		t.Errorf("output mentions probe 1004:\n%s", out)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	httpASNsFlag   string
+	httpTargetFlag string
+	httpPathFlag   string
+	httpMethodFlag string
+	httpPortFlag   int
+	httpHTTPSFlag  bool
+	httpAFFlag     string
+)
+
+func init() {
+	httpCmd.Flags().StringVar(&httpASNsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	httpCmd.Flags().StringVar(&httpTargetFlag, "target", "", "Target host name or IP (required)")
+	httpCmd.Flags().StringVar(&httpPathFlag, "path", "/", "Request path")
+	httpCmd.Flags().StringVar(&httpMethodFlag, "method", "GET", "Request method (GET, HEAD or POST)")
+	httpCmd.Flags().IntVar(&httpPortFlag, "port", 0, "Target port (default 80, or 443 with --https)")
+	httpCmd.Flags().BoolVar(&httpHTTPSFlag, "https", false, "Use HTTPS")
+	httpCmd.Flags().StringVar(&httpAFFlag, "af", "auto", "Address family: 4, 6 or auto (IPv6 for IPv6 addresses and host names with only AAAA records)")
+	selectorOpts.addFlags(httpCmd)
+	addDryRunFlag(httpCmd)
+
+	httpCmd.MarkFlagRequired("target")
+
+	rootCmd.AddCommand(httpCmd)
+}
+
+var httpCmd = &cobra.Command{
+	Use:   "http",
+	Short: "Run HTTP measurement and report status codes and TTFB per ASN",
+	Long: `Run HTTP(S) measurements from specified ASNs to a target host.
+Reports status codes, time-to-first-byte and total time per source ASN.
+
+RIPE Atlas only allows HTTP measurements towards RIPE Atlas anchors.
+
+Example:
+  ripeatlas http --asns 5384,7713 --target nl-ams-as3333.anchors.atlas.ripe.net
+  ripeatlas http --asns 5384,7713 --target nl-ams-as3333.anchors.atlas.ripe.net --https --path /4096`,
+	PreRunE: requireAPIKey,
+	RunE:    runHTTP,
+}
+
+func runHTTP(cmd *cobra.Command, args []string) error {
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
+
+	asns, err := parseSourceASNs(httpASNsFlag)
+	if err != nil {
+		return err
+	}
+
+	method := strings.ToUpper(httpMethodFlag)
+	if method != "GET" && method != "HEAD" && method != "POST" {
+		return fmt.Errorf("invalid method %q: must be GET, HEAD or POST", httpMethodFlag)
+	}
+
+	scheme, port := "http", 80
+	if httpHTTPSFlag {
+		scheme, port = "https", 443
+	}
+	host := httpTargetFlag
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Is6() {
+		host = "[" + host + "]"
+	}
+	requestURL := fmt.Sprintf("%s://%s%s", scheme, host, httpPathFlag)
+	if httpPortFlag > 0 && httpPortFlag != port {
+		requestURL = fmt.Sprintf("%s://%s:%d%s", scheme, host, httpPortFlag, httpPathFlag)
+	}
+	// Atlas defaults to port 80 even for HTTPS, so always send the port
+	if httpPortFlag > 0 {
+		port = httpPortFlag
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas HTTP measurement...\n\n")
+
+	af, err := resolveAF(run.ctx, httpAFFlag, httpTargetFlag)
+	if err != nil {
+		return err
+	}
+
+	def := atlas.MeasurementDefinition{
+		Type:            "http",
+		AF:              af,
+		Target:          httpTargetFlag,
+		Description:     fmt.Sprintf("HTTP %s %s from %s", method, requestURL, describeSources(asns)),
+		Method:          method,
+		Path:            httpPathFlag,
+		Port:            port,
+		HTTPS:           httpHTTPSFlag,
+		TimingVerbosity: 1,
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	if err := run.selectProbes(asns, af); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Creating HTTP measurement...\n")
+	fmt.Printf("   Request: %s %s\n", method, requestURL)
+	fmt.Printf("   Probes: %d\n", run.sel.total())
+
+	if created, err := run.create(def); !created {
+		return err
+	}
+
+	results, err := runResults(run, "HTTP", run.client.GetHTTPResults)
+	if err != nil {
+		return err
+	}
+
+	probes := run.probesOf(resultProbeIDs(results, func(r atlas.HTTPResult) int { return r.ProbeID }))
+
+	report := atlas.HTTPReport{
+		Report: run.baseReport(httpTargetFlag, len(results)),
+		URL:    requestURL,
+		ByASN: analyzer.SummarizeHTTP(results, func(r atlas.HTTPResult) int {
+			return probes[r.ProbeID].ASNV4
+		}),
+	}
+
+	fmt.Println(atlas.GenerateHTTPReport(report))
+
+	return nil
+}
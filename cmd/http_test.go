@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestHTTPPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantPort int64
+		wantURL  string
+	}{
+		{name: "plain", wantPort: 80, wantURL: "GET http://anchor.example/"},
+		{name: "https", args: []string{"--https"}, wantPort: 443, wantURL: "GET https://anchor.example/"},
+		{name: "https on another port", args: []string{"--https", "--port", "8443"}, wantPort: 8443, wantURL: "GET https://anchor.example:8443/"},
+		{name: "explicit default", args: []string{"--port", "80"}, wantPort: 80, wantURL: "GET http://anchor.example/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeAtlas(t)
+			var port atomic.Int64
+			srv.Result = func(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+				port.Store(int64(def.Port))
+				return srv.HTTPResult(def, msmID, probe)
+			}
+
+			args := append([]string{"http",
+				"--asns", fmt.Sprint(atlastest.DemoEyeballA),
+				"--target", "anchor.example",
+			}, tt.args...)
+			out, err := runCLI(t, srv, args...)
+			if err != nil {
+				t.Fatalf("http: %v\n%s", err, out)
+			}
+
+			if got := port.Load(); got != tt.wantPort {
+				t.Errorf("requested port %d, want %d", got, tt.wantPort)
+			}
+			if !strings.Contains(out, "Request: "+tt.wantURL+"\n") {
+				t.Errorf("output lacks request %q:\n%s", tt.wantURL, out)
+			}
+		})
+	}
+}
+
+func TestHTTPAddressFamily(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    int
+		wantURL string
+	}{
+		{"anchor.example", 4, "http://anchor.example/"},
+		{"anchor6.example", 6, "http://anchor6.example/"},
+		{"2001:db8::80", 6, "http://[2001:db8::80]/"},
+	}
+
+	for _, tt := range tests {
+		srv := newFakeAtlas(t)
+
+		out, err := runCLI(t, srv, "http", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", tt.target)
+		if err != nil {
+			t.Errorf("http --target %s: %v\n%s", tt.target, err, out)
+			continue
+		}
+		if status, _ := srv.Measurement(1000001); status.AF != tt.want {
+			t.Errorf("http --target %s measured IPv%d, want IPv%d", tt.target, status.AF, tt.want)
+		}
+		if !strings.Contains(out, "Request: GET "+tt.wantURL+"\n") {
+			t.Errorf("output lacks request %q:\n%s", tt.wantURL, out)
+		}
+	}
+}
+
+func TestHTTPValidatesDefinition(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	out, err := runCLI(t, srv, "http", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "anchor.example", "--port", "70000")
+	if err == nil || !strings.Contains(err.Error(), "port must be") {
+		t.Errorf("http --port 70000 = %v, want a port error\n%s", err, out)
+	}
+	if strings.Contains(out, "Fetching IPv") {
+		t.Errorf("http --port 70000 selected probes before failing:\n%s", out)
+	}
+	if _, ok := srv.Measurement(1000001); ok {
+		t.Errorf("http --port 70000 created a measurement")
+	}
+}
@@ -0,0 +1,803 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/aws"
+	"github.com/spf13/cobra"
+)
+
+// dryRunFlag is the --dry-run flag shared by all measurement commands
+var dryRunFlag bool
+
+// addDryRunFlag registers --dry-run on a measurement command
+func addDryRunFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the measurement request, probe allocation and cost estimate without creating anything")
+}
+
+// probeSelection is the outcome of probe selection shared by all measurement
+// commands: probes allocated from ASNs plus server-side selector sets
+type probeSelection struct {
+	ASNs              []int
+	ProbesByASN       map[int][]atlas.Probe
+	Allocations       []atlas.ProbeAllocation
+	ASNsWithProbes    []int
+	ASNsWithoutProbes []int
+	ProbeIDs          []int
+	Allocator         string           // allocation strategy, see atlas.DescribeAllocator
+	Selectors         []atlas.ProbeSet // resolved by Atlas, see probeSelectors
+
+	// picked holds probes Atlas chose for the selectors, see probesOf
+	picked map[int]atlas.Probe
+}
+
+// signalContext returns a context cancelled on Ctrl-C / SIGTERM so a running
+// measurement can be stopped, and the function restoring default signal handling
+func signalContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+}
+
+// errInterruptedEarly is returned when Ctrl-C stopped a run before any results
+// arrived. It wraps context.Canceled so the run exits like any other interrupt.
+var errInterruptedEarly = fmt.Errorf("interrupted before any results arrived: %w", context.Canceled)
+
+// resolveTarget turns an aws_<region> target into one of the region's test IPs,
+// other targets are returned unchanged
+func resolveTarget(target string) (string, error) {
+	if !aws.IsAWSRegion(target) {
+		return target, nil
+	}
+
+	fmt.Printf("📍 Resolving AWS region: %s\n", target)
+	ip, err := aws.GetRegionIP(target, selectorOpts.random())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve AWS region: %w", err)
+	}
+	fmt.Printf("   Selected IP: %s\n\n", ip)
+
+	return ip, nil
+}
+
+// lookupNetIP resolves host names for resolveAF, replaced in tests
+var lookupNetIP = net.DefaultResolver.LookupNetIP
+
+// resolveAF turns the --af flag into an address family. "auto" picks the
+// family of an address literal, and for host names IPv4 when the name has an
+// A record and IPv6 when it only has AAAA records; an explicit family must
+// match a literal target.
+func resolveAF(ctx context.Context, af, target string) (int, error) {
+	addr, err := netip.ParseAddr(target)
+	literal := 0
+	if err == nil {
+		literal = 4
+		if addr.Is6() && !addr.Is4In6() {
+			literal = 6
+		}
+	}
+
+	switch af {
+	case "auto", "":
+		if literal != 0 {
+			return literal, nil
+		}
+		return hostAF(ctx, target)
+	case "4", "6":
+		family, _ := strconv.Atoi(af)
+		if literal != 0 && literal != family {
+			return 0, fmt.Errorf("target %s is not an IPv%d address", target, family)
+		}
+		return family, nil
+	}
+
+	return 0, fmt.Errorf("invalid address family %q: must be 4, 6 or auto", af)
+}
+
+// hostAF resolves a host name locally to pick the address family for
+// --af auto. Probes resolve the name themselves, so this only decides which
+// family to ask Atlas for.
+func hostAF(ctx context.Context, host string) (int, error) {
+	addrs, err := lookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return 0, fmt.Errorf("cannot pick an address family for %s, set --af 4 or --af 6: %w", host, err)
+	}
+
+	af := 0
+	for _, addr := range addrs {
+		if addr.Unmap().Is4() {
+			return 4, nil
+		}
+		af = 6
+	}
+	if af == 0 {
+		return 0, fmt.Errorf("%s has no addresses, set --af 4 or --af 6", host)
+	}
+	return af, nil
+}
+
+// selectProbes fetches the probes of the given ASNs in address family af,
+// keeps those accepted by every filter, allocates them and asks the user
+// whether to continue when some ASNs have no probes. The --from-* selectors
+// are added as server-side sets, which the filters cannot apply to.
+func selectProbes(ctx context.Context, client *atlas.Client, asns []int, af int, filters ...func(atlas.Probe) bool) (*probeSelection, error) {
+	sel := &probeSelection{ASNs: asns}
+
+	// Draw the seed up front so the manifest records one even when nothing
+	// random is picked
+	selectorOpts.random()
+
+	selectors, err := selectorOpts.probeSets()
+	if err != nil {
+		return nil, err
+	}
+	sel.Selectors = selectors
+
+	filter, err := selectorOpts.filter()
+	if err != nil {
+		return nil, err
+	}
+	filters = append([]func(atlas.Probe) bool{filter.Accept}, filters...)
+
+	if len(asns) > 0 {
+		if err := sel.allocateASNs(ctx, client, af, filters); err != nil {
+			return nil, err
+		}
+	}
+
+	if selectorOpts.probesFrom != "" {
+		if err := sel.replay(selectorOpts.probesFrom); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(sel.Selectors) > 0 {
+		fmt.Printf("🔎 Server-side probe selectors:\n")
+		for _, set := range sel.Selectors {
+			fmt.Printf("   %s\n", set)
+		}
+	}
+
+	if len(sel.ASNsWithoutProbes) > 0 {
+		// Ask user if they want to continue
+		ok, err := confirm(ctx, "\n❓ Some ASNs have no available probes. Continue? (y/n): ")
+		if err != nil {
+			return nil, fmt.Errorf("interrupted: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("operation cancelled by user")
+		}
+	}
+	fmt.Println()
+
+	return sel, nil
+}
+
+// allocateASNs fetches, filters and allocates the probes of sel.ASNs
+func (s *probeSelection) allocateASNs(ctx context.Context, client *atlas.Client, af int, filters []func(atlas.Probe) bool) error {
+	opts, err := selectorOpts.allocationOptions()
+	if err != nil {
+		return err
+	}
+	s.Allocator = atlas.DescribeAllocator(opts.Allocator)
+
+	// Get probes for ASNs
+	fmt.Printf("🔎 Fetching IPv%d probes for ASNs: %s\n", af, joinASNs(s.ASNs))
+	probesByASN, err := client.GetProbesByASN(ctx, s.ASNs, af)
+	if err != nil {
+		return fmt.Errorf("failed to fetch probes: %w", err)
+	}
+
+	skipped := 0
+	for asn, probes := range probesByASN {
+		before := len(probes)
+		probesByASN[asn] = slices.DeleteFunc(probes, func(p atlas.Probe) bool {
+			for _, accept := range filters {
+				if !accept(p) {
+					return true
+				}
+			}
+			return false
+		})
+		skipped += before - len(probesByASN[asn])
+	}
+
+	// Random picks only repeat for the same seed when the probes come in
+	// the same order, which paginated and parallel fetches do not promise
+	for _, probes := range probesByASN {
+		slices.SortFunc(probes, func(a, b atlas.Probe) int { return a.ID - b.ID })
+	}
+
+	duplicates := 0
+	if selectorOpts.dedup {
+		for _, asn := range s.ASNs {
+			probes := probesByASN[asn]
+			probesByASN[asn] = atlas.DedupByPrefix(probes, af, opts.PreferAnchors, opts.Rand)
+			duplicates += len(probes) - len(probesByASN[asn])
+		}
+	}
+	s.ProbesByASN = probesByASN
+
+	if skipped > 0 {
+		fmt.Printf("   Skipped %d probes that did not pass the probe filters\n", skipped)
+	}
+	if duplicates > 0 {
+		fmt.Printf("   Skipped %d probes sharing a prefix with another probe\n", duplicates)
+	}
+
+	// Allocate probes
+	s.Allocations, s.ASNsWithoutProbes, err = atlas.AllocateProbesWithOptions(probesByASN, s.ASNs, opts)
+	if err != nil {
+		// Selectors may still find probes when none of the ASNs have any
+		if len(s.Selectors) == 0 || !errors.Is(err, atlas.ErrNoSuitableProbes) {
+			return fmt.Errorf("probe allocation failed: %w", err)
+		}
+	}
+
+	// Display allocation summary
+	for _, alloc := range s.Allocations {
+		s.ASNsWithProbes = append(s.ASNsWithProbes, alloc.ASN)
+		s.ProbeIDs = append(s.ProbeIDs, alloc.ProbeIDs...)
+	}
+
+	fmt.Printf("   Allocation: %s, seed %d\n", s.Allocator, selectorOpts.seed)
+	fmt.Printf("   ASNs with probes: %v\n", s.ASNsWithProbes)
+	for _, alloc := range s.Allocations {
+		fmt.Printf("     AS%-8d %d of %d probes\n", alloc.ASN, alloc.Allocated, alloc.Available)
+	}
+	if len(s.ASNsWithoutProbes) > 0 {
+		fmt.Printf("   ⚠️  ASNs without probes: %v\n", s.ASNsWithoutProbes)
+	}
+
+	return nil
+}
+
+// replay selects the probes recorded in a manifest file. The probes Atlas
+// picked for the manifest's server-side selectors are not recorded, so those
+// selectors are run again and may pick other probes.
+func (s *probeSelection) replay(path string) error {
+	manifest, err := atlas.ReadManifestFile(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔁 Reusing the probes of %s\n", path)
+	fmt.Printf("   Recorded %s with seed %d\n", manifest.CreatedAt.Format(time.RFC3339), manifest.Seed)
+
+	s.Allocator = fmt.Sprintf("replayed from %s", filepath.Base(path))
+	if manifest.Allocator != "" {
+		s.Allocator += ", originally " + manifest.Allocator
+	}
+	s.Allocations = manifest.ProbeAllocations()
+	for _, alloc := range s.Allocations {
+		s.ASNs = append(s.ASNs, alloc.ASN)
+		s.ASNsWithProbes = append(s.ASNsWithProbes, alloc.ASN)
+	}
+	s.ProbeIDs = append(s.ProbeIDs, manifest.Probes...)
+	fmt.Printf("   %d probes\n", len(manifest.Probes))
+
+	if len(manifest.Selectors) > 0 {
+		s.Selectors = append(manifest.Selectors, s.Selectors...)
+		fmt.Printf("   ⚠️  The manifest's server-side selectors pick probes anew")
+		if ids := manifest.MeasurementIDs(); len(ids) > 0 {
+			fmt.Printf(", --from-msm %d reuses the probes of the recorded run instead", ids[0])
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// writeManifest records the selection and the measurements of req in the
+// --manifest file. ids holds the measurement IDs of each definition, one per
+// shard, and is nil for dry runs.
+func (s *probeSelection) writeManifest(req atlas.MeasurementRequest, ids [][]int) error {
+	manifest := &atlas.SelectionManifest{
+		CreatedAt: time.Now().UTC(),
+		Seed:      selectorOpts.seed,
+		Allocator: s.Allocator,
+		Probes:    s.ProbeIDs,
+		Selectors: s.Selectors,
+	}
+	if manifest.Probes == nil {
+		manifest.Probes = []int{}
+	}
+	for _, alloc := range s.Allocations {
+		manifest.Allocations = append(manifest.Allocations, atlas.ManifestAllocation{
+			ASN:       alloc.ASN,
+			Available: alloc.Available,
+			Probes:    alloc.ProbeIDs,
+		})
+	}
+	for i, def := range req.Definitions {
+		m := atlas.ManifestMeasurement{Type: def.Type, AF: def.AF, Target: def.Target}
+		if i >= len(ids) {
+			manifest.Measurements = append(manifest.Measurements, m)
+			continue
+		}
+		for _, id := range ids[i] {
+			m.ID = id
+			manifest.Measurements = append(manifest.Measurements, m)
+		}
+	}
+
+	if err := atlas.WriteManifestFile(selectorOpts.manifest, manifest); err != nil {
+		return err
+	}
+	fmt.Printf("📝 Wrote selection manifest to %s\n\n", selectorOpts.manifest)
+	return nil
+}
+
+// probeSets returns the Atlas probe sets for the selection: the allocated
+// probes by ID, then the server-side selectors
+func (s *probeSelection) probeSets() []atlas.ProbeSet {
+	var sets []atlas.ProbeSet
+	if len(s.ProbeIDs) > 0 {
+		sets = append(sets, atlas.ProbeSet{
+			Type:      "probes",
+			Value:     strings.Trim(strings.Join(strings.Fields(fmt.Sprint(s.ProbeIDs)), ","), "[]"),
+			Requested: len(s.ProbeIDs),
+			Tags:      selectorOpts.tags(),
+		})
+	}
+	return append(sets, s.Selectors...)
+}
+
+// total returns the number of probes requested across all probe sets
+func (s *probeSelection) total() int {
+	total := len(s.ProbeIDs)
+	for _, set := range s.Selectors {
+		total += set.Requested
+	}
+	return total
+}
+
+// selectorNames describes the server-side selectors for reports
+func (s *probeSelection) selectorNames() []string {
+	names := make([]string, len(s.Selectors))
+	for i, set := range s.Selectors {
+		names[i] = set.String()
+	}
+	return names
+}
+
+// probes returns the probes allocated from ASNs and those looked up by
+// probesOf, keyed by probe ID
+func (s *probeSelection) probes() map[int]atlas.Probe {
+	byID := make(map[int]atlas.Probe)
+	for _, probes := range s.ProbesByASN {
+		for _, probe := range probes {
+			byID[probe.ID] = probe
+		}
+	}
+	maps.Copy(byID, s.picked)
+	return byID
+}
+
+// probesOf returns the probes keyed by ID like probes, first looking up the
+// probes among probeIDs that Atlas picked for the server-side selectors.
+// A failed lookup only leaves those probes without ASN and country.
+func (s *probeSelection) probesOf(ctx context.Context, client *atlas.Client, probeIDs []int) map[int]atlas.Probe {
+	known := s.probes()
+
+	var unknown []int
+	for _, id := range probeIDs {
+		if _, ok := known[id]; !ok && !slices.Contains(unknown, id) {
+			unknown = append(unknown, id)
+		}
+	}
+
+	if len(unknown) > 0 {
+		picked, err := client.GetProbesByID(ctx, unknown)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   ⚠️  Failed to look up probes picked by Atlas: %v\n", err)
+		}
+		s.picked = picked
+	}
+
+	return s.probes()
+}
+
+// resultProbeIDs returns the probe ID of every result
+func resultProbeIDs[T any](results []T, probeID func(T) int) []int {
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = probeID(r)
+	}
+	return ids
+}
+
+// measurementRun carries a one-off run of a measurement command from probe
+// selection to the report: the selected probes, the measurements created and
+// whether Ctrl-C cut the run short. Commands only add their definitions and
+// analysis.
+type measurementRun struct {
+	// ctx is cancelled on Ctrl-C / SIGTERM, after which create replaces it
+	// with a bounded context for fetching the partial results
+	ctx         context.Context
+	stopSignals context.CancelFunc
+	cancel      context.CancelFunc
+
+	client      *atlas.Client
+	sel         *probeSelection
+	ids         [][]int // measurement IDs per definition
+	interrupted bool
+	start       time.Time
+}
+
+// startRun checks that the API key may create measurements and starts a run
+// of cmd. The caller must close it.
+func startRun(cmd *cobra.Command) (*measurementRun, error) {
+	ctx, stopSignals := signalContext(cmd)
+	run := &measurementRun{
+		ctx:         ctx,
+		stopSignals: stopSignals,
+		client:      newAtlasClient(),
+		start:       time.Now(),
+	}
+	if err := checkMeasurementKey(run.ctx, run.client); err != nil {
+		run.close()
+		return nil, err
+	}
+	return run, nil
+}
+
+// close restores default signal handling and releases the results context
+func (r *measurementRun) close() {
+	r.stopSignals()
+	if r.cancel != nil {
+		r.cancel()
+	}
+}
+
+// selectProbes selects the probes of the run, see selectProbes
+func (r *measurementRun) selectProbes(asns []int, af int, filters ...func(atlas.Probe) bool) error {
+	sel, err := selectProbes(r.ctx, r.client, asns, af, filters...)
+	r.sel = sel
+	return err
+}
+
+// create creates one-off measurements of defs from the selected probes and
+// waits for them to complete, stopping them on Ctrl-C. It reports false
+// without error after a dry run, when there are no results to fetch.
+func (r *measurementRun) create(defs ...atlas.MeasurementDefinition) (bool, error) {
+	req := atlas.MeasurementRequest{
+		Definitions: defs,
+		Probes:      r.sel.probeSets(),
+		IsOneoff:    true,
+	}
+
+	ids, interrupted, err := runOneoffs(r.ctx, r.client, req, r.sel)
+	r.ids = ids
+	if err != nil {
+		if created := slices.Concat(ids...); len(created) > 0 {
+			fmt.Printf("   ⚠️  Check whether these measurements are still running: %v\n", created)
+		}
+		return false, err
+	}
+	if dryRunFlag {
+		return false, nil
+	}
+
+	// Ctrl-C may also arrive before every measurement was created
+	if interrupted || r.ctx.Err() != nil {
+		r.interrupted = true
+		r.ctx, r.cancel = stopInterrupted(r.stopSignals, r.client, slices.Concat(ids...)...)
+	}
+	if slices.ContainsFunc(ids, func(ids []int) bool { return len(ids) == 0 }) {
+		if r.interrupted {
+			return false, fmt.Errorf("interrupted before the measurements were created: %w", context.Canceled)
+		}
+		return false, fmt.Errorf("expected a measurement per definition, got %v", ids)
+	}
+
+	return true, nil
+}
+
+// runResults fetches the results of a single-definition run, failing with
+// errInterruptedEarly when Ctrl-C left none
+func runResults[T any](r *measurementRun, kind string, fetch func(context.Context, int) ([]T, error)) ([]T, error) {
+	fmt.Printf("📥 Fetching measurement results...\n")
+	results, err := fetchResults(r.ctx, r.ids[0], fetch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch results: %w", err)
+	}
+
+	fmt.Printf("   Retrieved %d %s results\n\n", len(results), kind)
+
+	if r.interrupted && len(results) == 0 {
+		return nil, errInterruptedEarly
+	}
+	return results, nil
+}
+
+// probesOf returns the probes of the results, see probeSelection.probesOf
+func (r *measurementRun) probesOf(probeIDs []int) map[int]atlas.Probe {
+	return r.sel.probesOf(r.ctx, r.client, probeIDs)
+}
+
+// baseReport returns the report fields every measurement command shares
+func (r *measurementRun) baseReport(target string, resultCount int) atlas.Report {
+	ids := slices.Concat(r.ids...)
+	return atlas.Report{
+		MeasurementID:     ids[0],
+		MeasurementIDs:    ids,
+		Target:            target,
+		CreatedAt:         r.start,
+		Duration:          time.Since(r.start),
+		RequestedASNs:     r.sel.ASNs,
+		ASNsWithProbes:    r.sel.ASNsWithProbes,
+		ASNsWithoutProbes: r.sel.ASNsWithoutProbes,
+		Allocations:       r.sel.Allocations,
+		Allocator:         r.sel.Allocator,
+		Selectors:         r.sel.selectorNames(),
+		TotalProbes:       r.sel.total(),
+		Partial:           r.interrupted,
+		ResultCount:       resultCount,
+	}
+}
+
+// runOneoffs creates one-off measurements for every definition in req and
+// waits for all of them to complete. More than MaxProbesPerMeasurement probes
+// are split across several measurements per definition. It returns the
+// measurement IDs per definition, in the order of req.Definitions, and
+// reports interrupted=true when ctx was cancelled (Ctrl-C), in which case the
+// caller should call stopInterrupted. When waiting for one measurement fails,
+// the ones not waited for yet are stopped. With --dry-run it only prints the
+// requests and returns no measurements.
+func runOneoffs(ctx context.Context, client *atlas.Client, req atlas.MeasurementRequest, sel *probeSelection) ([][]int, bool, error) {
+	checkCost(ctx, client, req)
+
+	shards := atlas.ShardRequest(req, atlas.MaxProbesPerMeasurement)
+	if len(shards) > 1 {
+		if err := checkConcurrency(ctx, client, len(shards)*len(req.Definitions)); err != nil {
+			return nil, false, err
+		}
+		fmt.Printf("✂️  Splitting %d probes across %d measurements per definition\n\n", req.RequestedProbes(), len(shards))
+	}
+
+	if dryRunFlag {
+		for i, shard := range shards {
+			body, err := json.MarshalIndent(shard, "", "  ")
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to encode request: %w", err)
+			}
+			if len(shards) > 1 {
+				fmt.Printf("Request %d of %d:\n", i+1, len(shards))
+			}
+			fmt.Printf("🧪 Dry run, nothing was created. Request for POST %s/measurements/:\n%s\n", apiURLFlag, body)
+		}
+		if selectorOpts.manifest != "" {
+			return nil, false, sel.writeManifest(req, nil)
+		}
+		return nil, false, nil
+	}
+
+	ids := make([][]int, len(req.Definitions))
+	expected := make(map[int]int) // measurement ID -> probes requested
+	for _, shard := range shards {
+		// Atlas may create a measurement even when the POST is cancelled
+		// mid-request, and its ID would be lost. The create runs to
+		// completion and Ctrl-C is honoured once the ID is known.
+		if ctx.Err() != nil {
+			return ids, true, nil
+		}
+		created, err := client.CreateMeasurements(context.WithoutCancel(ctx), shard)
+		if err != nil {
+			// The shards that already run would only give a partial picture
+			if len(expected) > 0 {
+				stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+				stopMeasurements(stopCtx, client, slices.Concat(ids...), "Creating all measurements failed")
+				cancel()
+			}
+			return nil, false, fmt.Errorf("failed to create measurement: %w", err)
+		}
+
+		for i, id := range created {
+			fmt.Printf("   ✅ Measurement created: ID %d\n", id)
+			fmt.Printf("   🔗 https://atlas.ripe.net/measurements/%d\n", id)
+			if i < len(ids) {
+				ids[i] = append(ids[i], id)
+			}
+			expected[id] = shard.RequestedProbes()
+		}
+	}
+	fmt.Println()
+	if ctx.Err() != nil {
+		return ids, true, nil
+	}
+
+	// A manifest that fails to write must not orphan the running measurements
+	if selectorOpts.manifest != "" {
+		if err := sel.writeManifest(req, ids); err != nil {
+			fmt.Fprintf(os.Stderr, "   ⚠️  %v\n", err)
+		}
+	}
+
+	// Wait for measurements to complete (with 5-minute timeout)
+	fmt.Printf("⏳ Waiting for measurement to complete... (Ctrl-C stops it early)\n")
+
+	all := slices.Concat(ids...)
+	for i, id := range all {
+		interrupted, err := waitForMeasurement(ctx, client, id, expected[id])
+		if err != nil {
+			// As when a create fails, the other shards would only give a partial picture
+			if rest := all[i+1:]; len(rest) > 0 {
+				stopCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+				stopMeasurements(stopCtx, client, rest, fmt.Sprintf("Measurement %d failed", id))
+				cancel()
+			}
+			return ids, false, err
+		}
+		if interrupted {
+			return ids, true, nil
+		}
+	}
+
+	fmt.Printf("   ✅ Measurement completed!\n\n")
+
+	return ids, false, nil
+}
+
+// checkConcurrency makes sure n more measurements fit in the number of
+// measurements an account may run at the same time. A failed check, or a dry
+// run, only warns.
+func checkConcurrency(ctx context.Context, client *atlas.Client, n int) error {
+	active, err := client.CountActiveMeasurements(ctx)
+	if err != nil {
+		fmt.Printf("   ⚠️  Could not check the running measurements: %v\n", err)
+		return nil
+	}
+
+	if active+n <= atlas.MaxConcurrentMeasurements {
+		return nil
+	}
+	// A dry run creates nothing, but should show that the real run would fail
+	if dryRunFlag {
+		fmt.Printf("   ⚠️  %d measurements needed, %d of %d already running\n",
+			n, active, atlas.MaxConcurrentMeasurements)
+		return nil
+	}
+	return fmt.Errorf("%w: %d measurements needed, %d of %d already running",
+		atlas.ErrQuotaExceeded, n, active, atlas.MaxConcurrentMeasurements)
+}
+
+// fetchResults fetches the results of measurements, the shards of one
+// definition, and merges them
+func fetchResults[T any](ctx context.Context, ids []int, fetch func(context.Context, int) ([]T, error)) ([]T, error) {
+	var results []T
+	for _, id := range ids {
+		shard, err := fetch(ctx, id)
+		if err != nil {
+			if len(ids) > 1 {
+				return nil, fmt.Errorf("measurement %d: %w", id, err)
+			}
+			return nil, err
+		}
+		results = append(results, shard...)
+	}
+	return results, nil
+}
+
+// checkCost prints the estimated credit cost of req and warns when it exceeds
+// the account's balance or the Atlas quotas. The estimate is only a guide,
+// so Atlas has the final say when the measurement is created.
+func checkCost(ctx context.Context, client *atlas.Client, req atlas.MeasurementRequest) {
+	estimate := atlas.EstimateCost(req)
+
+	fmt.Printf("💰 Estimated cost: %d credits for %d results\n", estimate.Credits, estimate.Results)
+	if len(estimate.Definitions) > 1 {
+		for _, def := range estimate.Definitions {
+			fmt.Printf("   %s IPv%d %s: %d results × %d credits\n", def.Type, def.AF, def.Target, def.Results, def.PerResult)
+		}
+	}
+
+	warnings := estimate.QuotaWarnings(req)
+
+	credits, err := client.GetCredits(ctx)
+	switch {
+	case err != nil:
+		fmt.Printf("   ⚠️  Could not check the credit balance: %v\n", err)
+	case estimate.Credits > credits.CurrentBalance:
+		warnings = append(warnings, fmt.Sprintf("estimate exceeds the balance of %d credits", credits.CurrentBalance))
+	default:
+		fmt.Printf("   Balance: %d credits\n", credits.CurrentBalance)
+	}
+
+	for _, warning := range warnings {
+		fmt.Printf("   ⚠️  %s\n", warning)
+	}
+	fmt.Println()
+}
+
+// stopInterrupted stops measurements after Ctrl-C. The signal context is
+// already cancelled at that point, so it returns a fresh, bounded context for
+// fetching the partial results and building the report.
+func stopInterrupted(stopSignals context.CancelFunc, client *atlas.Client, measurementIDs ...int) (context.Context, context.CancelFunc) {
+	stopSignals() // a second Ctrl-C exits immediately
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	stopMeasurements(ctx, client, measurementIDs, "Interrupted")
+
+	return ctx, cancel
+}
+
+// stopMeasurements stops running measurements for the given reason, telling
+// the user to stop the ones that fail by hand
+func stopMeasurements(ctx context.Context, client *atlas.Client, measurementIDs []int, reason string) {
+	for _, measurementID := range measurementIDs {
+		fmt.Printf("\n🛑 %s, stopping measurement %d...\n", reason, measurementID)
+
+		if err := client.StopMeasurement(ctx, measurementID); err != nil {
+			fmt.Fprintf(os.Stderr, "   ⚠️  Failed to stop measurement: %v\n", err)
+			fmt.Fprintf(os.Stderr, "   Stop it manually: https://atlas.ripe.net/measurements/%d\n", measurementID)
+		} else {
+			fmt.Printf("   ✅ Measurement stopped\n")
+		}
+	}
+	fmt.Println()
+}
+
+// waitForMeasurement polls until the measurement completes, asking the user
+// whether to keep waiting every 5 minutes. It reports interrupted=true when
+// ctx was cancelled (Ctrl-C) so the caller can stop the measurement.
+func waitForMeasurement(ctx context.Context, client *atlas.Client, measurementID, expectedProbes int) (bool, error) {
+	waitStartTime := time.Now()
+	timeout := 5 * time.Minute
+
+	for {
+		elapsed := time.Since(waitStartTime)
+
+		if elapsed >= timeout {
+			fmt.Printf("\n⏱️  Measurement has been running for 5 minutes.\n")
+			fmt.Printf("   Measurement URL: https://atlas.ripe.net/measurements/%d\n", measurementID)
+			fmt.Printf("   Please check the URL manually.\n\n")
+
+			ok, err := confirm(ctx, "❓ Wait for another 5 minutes? (y/n): ")
+			if err != nil {
+				return true, nil
+			}
+			if !ok {
+				return false, fmt.Errorf("measurement still running, check URL manually")
+			}
+
+			// Reset timeout
+			waitStartTime = time.Now()
+		}
+
+		// Give each call room for at least one poll before its own timeout fires
+		err := client.WaitForMeasurement(ctx, measurementID, expectedProbes, pollIntervalFlag+time.Second)
+		if err == nil {
+			return false, nil
+		}
+
+		if ctx.Err() != nil {
+			return true, nil
+		}
+
+		// A poll timeout is expected, anything else is a real failure
+		if !errors.Is(err, atlas.ErrWaitTimeout) {
+			return false, fmt.Errorf("error waiting for measurement: %w", err)
+		}
+
+		time.Sleep(100 * time.Millisecond) // Small delay before checking user timeout
+	}
+}
+
+// joinASNs formats ASNs as a comma-separated list
+func joinASNs(asns []int) string {
+	return strings.Trim(strings.Join(strings.Fields(fmt.Sprint(asns)), ","), "[]")
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestResolveAF(t *testing.T) {
+	hosts := map[string][]netip.Addr{
+		"dual.example": {netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("192.0.2.1")},
+		"v4.example":   {netip.MustParseAddr("::ffff:192.0.2.2")},
+		"v6.example":   {netip.MustParseAddr("2001:db8::2")},
+	}
+	stubLookup(t, hosts)
+
+	tests := []struct {
+		af, target string
+		want       int // 0 when an error is expected
+	}{
+		{"auto", "192.0.2.1", 4},
+		{"auto", "2001:db8::1", 6},
+		{"auto", "::ffff:192.0.2.1", 4},
+		{"", "2001:db8::1", 6},
+		{"auto", "dual.example", 4},
+		{"auto", "v4.example", 4},
+		{"auto", "v6.example", 6},
+		{"auto", "missing.example", 0},
+		{"4", "missing.example", 4},
+		{"6", "v4.example", 6},
+		{"6", "192.0.2.1", 0},
+		{"4", "2001:db8::1", 0},
+		{"5", "192.0.2.1", 0},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveAF(context.Background(), tt.af, tt.target)
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("resolveAF(%q, %q) = %d, want an error", tt.af, tt.target, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolveAF(%q, %q) = %d, %v, want %d", tt.af, tt.target, got, err, tt.want)
+		}
+	}
+}
+
+// stubLookup makes resolveAF see hosts instead of the real DNS for the rest of the test
+func stubLookup(t *testing.T, hosts map[string][]netip.Addr) {
+	defaultLookup := lookupNetIP
+	t.Cleanup(func() { lookupNetIP = defaultLookup })
+	lookupNetIP = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		if addrs, ok := hosts[host]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+}
+
+// createdProbes records the probe IDs listed in every create request srv accepts
+func createdProbes(srv *atlastest.Server) *[][]int {
+	var created [][]int
+	srv.CreateHook = func(req atlas.MeasurementRequest) error {
+		var ids []int
+		for _, set := range req.Probes {
+			if set.Type != "probes" {
+				continue
+			}
+			for _, field := range strings.Split(set.Value, ",") {
+				id, err := strconv.Atoi(field)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+		}
+		slices.Sort(ids)
+		created = append(created, ids)
+		return nil
+	}
+	return &created
+}
+
+func TestManifestReplay(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.SeedBulk(50)
+	created := createdProbes(srv)
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	out, err := runCLI(t, srv, "traceroute",
+		"--asns", fmt.Sprintf("%d,%d", atlastest.DemoEyeballA, atlastest.DemoEyeballB),
+		"--target", "8.8.8.8",
+		"--budget", "6",
+		"--seed", "7",
+		"--manifest", path,
+	)
+	if err != nil {
+		t.Fatalf("traceroute --manifest: %v\n%s", err, out)
+	}
+
+	manifest, err := atlas.ReadManifestFile(path)
+	if err != nil {
+		t.Fatalf("ReadManifestFile: %v", err)
+	}
+	recorded := slices.Sorted(slices.Values(manifest.Probes))
+	if manifest.Seed != 7 || len(recorded) != 6 || len(manifest.Allocations) != 2 {
+		t.Fatalf("manifest = %+v, want 6 probes from 2 ASNs with seed 7", manifest)
+	}
+	if ids := manifest.MeasurementIDs(); !slices.Equal(ids, []int{1000001}) {
+		t.Errorf("manifest measurements = %v, want 1000001", ids)
+	}
+	if len(*created) != 1 || !slices.Equal((*created)[0], recorded) {
+		t.Fatalf("created %v, want the recorded probes %v", *created, recorded)
+	}
+
+	// The replay needs no --asns and ignores the seed of the new run
+	out, err = runCLI(t, srv, "traceroute",
+		"--target", "8.8.8.8",
+		"--probes-from", path,
+		"--seed", "8",
+	)
+	if err != nil {
+		t.Fatalf("traceroute --probes-from: %v\n%s", err, out)
+	}
+	if len(*created) != 2 || !slices.Equal((*created)[1], recorded) {
+		t.Errorf("replay created %v, want the recorded probes %v", (*created)[1:], recorded)
+	}
+	for _, want := range []string{"Reusing the probes of " + path, "with seed 7", "6 probes"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSameSeedSameSelection(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.SeedBulk(200)
+	dir := t.TempDir()
+
+	selection := func(seed int, name string, flags ...string) []int {
+		t.Helper()
+
+		path := filepath.Join(dir, name)
+		out, err := runCLI(t, srv, append([]string{"traceroute",
+			"--asns", fmt.Sprintf("%d,%d", atlastest.DemoEyeballA, atlastest.DemoEyeballB),
+			"--target", "8.8.8.8",
+			"--budget", "10",
+			"--seed", strconv.Itoa(seed),
+			"--manifest", path,
+			"--dry-run",
+		}, flags...)...)
+		if err != nil {
+			t.Fatalf("traceroute --seed %d: %v\n%s", seed, err, out)
+		}
+
+		manifest, err := atlas.ReadManifestFile(path)
+		if err != nil {
+			t.Fatalf("ReadManifestFile: %v", err)
+		}
+		if manifest.Seed != int64(seed) {
+			t.Errorf("manifest seed = %d, want %d", manifest.Seed, seed)
+		}
+		if len(manifest.Measurements) != 1 || manifest.Measurements[0].ID != 0 {
+			t.Errorf("dry run manifest measurements = %+v, want one without ID", manifest.Measurements)
+		}
+		return manifest.Probes
+	}
+
+	for i, flags := range [][]string{
+		{"--sampling", atlas.SamplingRandom},
+		{"--sampling", atlas.SamplingGeo},
+		{"--anchors", "prefer", "--exclude-nat"},
+	} {
+		first := selection(42, fmt.Sprintf("%d-first.json", i), flags...)
+		second := selection(42, fmt.Sprintf("%d-second.json", i), flags...)
+		if len(first) == 0 || !slices.Equal(first, second) {
+			t.Errorf("%v with seed 42 picked %v then %v", flags, first, second)
+		}
+
+		other := selection(43, fmt.Sprintf("%d-other.json", i), flags...)
+		if slices.Equal(first, other) {
+			t.Errorf("%v picked %v with seeds 42 and 43", flags, first)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listStatusFlag string
+	listTypeFlag   string
+	listTargetFlag string
+	listTagsFlag   string
+	listLimitFlag  int
+	resultsRawFlag bool
+)
+
+func init() {
+	measurementListCmd.Flags().StringVar(&listStatusFlag, "status", "", "Comma-separated statuses (specified, scheduled, ongoing, stopped, forced, noprobes, failed, archived)")
+	measurementListCmd.Flags().StringVar(&listTypeFlag, "type", "", "Measurement type (e.g., traceroute, ping)")
+	measurementListCmd.Flags().StringVar(&listTargetFlag, "target", "", "Measurement target")
+	measurementListCmd.Flags().StringVar(&listTagsFlag, "tags", "", "Comma-separated tags")
+	measurementListCmd.Flags().IntVar(&listLimitFlag, "limit", 50, "Maximum number of measurements to list (0 = all)")
+
+	measurementResultsCmd.Flags().BoolVar(&resultsRawFlag, "raw", false, "Print the raw JSON results")
+
+	measurementCmd.AddCommand(measurementListCmd, measurementStatusCmd, measurementResultsCmd, measurementStopCmd)
+	rootCmd.AddCommand(measurementCmd)
+}
+
+var measurementCmd = &cobra.Command{
+	Use:     "measurement",
+	Aliases: []string{"msm"},
+	Short:   "List, inspect and stop RIPE Atlas measurements",
+}
+
+var measurementListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your measurements",
+	Long: `List the measurements owned by the configured API key.
+
+Example:
+  ripeatlas measurement list --status ongoing,scheduled
+  ripeatlas measurement list --type traceroute --target 1.2.3.4 --limit 0`,
+	Args:    cobra.NoArgs,
+	PreRunE: requireAPIKey,
+	RunE:    runMeasurementList,
+}
+
+var measurementStatusCmd = &cobra.Command{
+	Use:   "status <id>",
+	Short: "Show the status of a measurement",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runMeasurementStatus,
+}
+
+var measurementResultsCmd = &cobra.Command{
+	Use:   "results <id>",
+	Short: "Show the results of a measurement",
+	Long: `Show the results of a measurement.
+
+Traceroute results are summarised per probe, other types and --raw print the JSON as returned by RIPE Atlas.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMeasurementResults,
+}
+
+var measurementStopCmd = &cobra.Command{
+	Use:     "stop <id>...",
+	Short:   "Stop one or more running measurements",
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: requireAPIKey,
+	RunE:    runMeasurementStop,
+}
+
+func runMeasurementList(cmd *cobra.Command, args []string) error {
+	filter := atlas.MeasurementFilter{
+		Type:   listTypeFlag,
+		Target: listTargetFlag,
+		Tags:   splitList(listTagsFlag),
+	}
+
+	for _, name := range splitList(listStatusFlag) {
+		id, ok := atlas.MeasurementStatusIDs[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("unknown status: %s", name)
+		}
+		filter.StatusIDs = append(filter.StatusIDs, id)
+	}
+
+	client := newAtlasClient()
+	it := client.ListMeasurements(cmd.Context(), filter)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tAF\tTARGET\tSTATUS\tPROBES\tDESCRIPTION")
+
+	shown := 0
+	for (listLimitFlag <= 0 || shown < listLimitFlag) && it.Next() {
+		m := it.Measurement()
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%d\t%s\n",
+			m.ID, m.Type, m.AF, m.Target, m.Status.Name, m.ParticipantCount, m.Description)
+		shown++
+	}
+	w.Flush()
+
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("failed to list measurements: %w", err)
+	}
+
+	fmt.Printf("\nShowing %d of %d measurements\n", shown, it.Count())
+	return nil
+}
+
+func runMeasurementStatus(cmd *cobra.Command, args []string) error {
+	id, err := parseMeasurementID(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := newAtlasClient()
+	status, err := client.GetMeasurementStatus(cmd.Context(), id)
+	if err != nil {
+		return fmt.Errorf("failed to get measurement status: %w", err)
+	}
+
+	fmt.Print(atlas.FormatMeasurementStatus(*status))
+	return nil
+}
+
+func runMeasurementResults(cmd *cobra.Command, args []string) error {
+	id, err := parseMeasurementID(args[0])
+	if err != nil {
+		return err
+	}
+
+	client := newAtlasClient()
+	raw, err := client.GetMeasurementResultsRaw(cmd.Context(), id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch results: %w", err)
+	}
+
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if len(raw) > 0 {
+		json.Unmarshal(raw[0], &probe)
+	}
+
+	if resultsRawFlag || probe.Type != "traceroute" {
+		out, err := json.MarshalIndent(raw, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	results := make([]atlas.TracerouteResult, 0, len(raw))
+	for _, r := range raw {
+		var result atlas.TracerouteResult
+		if err := json.Unmarshal(r, &result); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	fmt.Printf("Measurement %d results:\n", id)
+	fmt.Print(atlas.FormatTracerouteResults(results))
+	return nil
+}
+
+func runMeasurementStop(cmd *cobra.Command, args []string) error {
+	client := newAtlasClient()
+
+	var errs []error
+	for _, arg := range args {
+		id, err := parseMeasurementID(arg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := client.StopMeasurement(cmd.Context(), id); err != nil {
+			// Atlas refuses to stop a measurement that has already finished,
+			// which is what the caller wanted anyway
+			if status, statusErr := client.GetMeasurementStatus(cmd.Context(), id); statusErr == nil && finished(status.Status.ID) {
+				fmt.Printf("   ✅ %d already finished (%s)\n", id, status.Status.Name)
+				continue
+			}
+
+			fmt.Printf("   ✗ %d: %v\n", id, err)
+			errs = append(errs, fmt.Errorf("failed to stop measurement %d: %w", id, err))
+			continue
+		}
+		fmt.Printf("   ✅ %d stopped\n", id)
+	}
+
+	return errors.Join(errs...)
+}
+
+// finished reports whether a measurement status ID is past running
+func finished(statusID int) bool {
+	switch statusID {
+	case atlas.MeasurementSpecified, atlas.MeasurementScheduled, atlas.MeasurementOngoing:
+		return false
+	}
+	return true
+}
+
+// parseMeasurementID parses a positive measurement ID argument
+func parseMeasurementID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid measurement ID: %s", s)
+	}
+	return id, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
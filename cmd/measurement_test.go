@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+// createMeasurement creates a one-off measurement on srv without waiting for it
+func createMeasurement(t *testing.T, srv *atlastest.Server, msmType, target string) int {
+	t.Helper()
+
+	client := atlas.NewClient("test", atlas.WithBaseURL(srv.URL()))
+	id, err := client.CreateMeasurement(context.Background(), atlas.MeasurementRequest{
+		Definitions: []atlas.MeasurementDefinition{{Type: msmType, AF: 4, Target: target}},
+		Probes:      []atlas.ProbeSet{{Type: "probes", Value: "1001,2001", Requested: 2}},
+		IsOneoff:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateMeasurement: %v", err)
+	}
+	return id
+}
+
+// listedIDs returns the measurement IDs in the rows of measurement list output
+func listedIDs(out string) []int {
+	var ids []int
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestMeasurementList(t *testing.T) {
+	srv := newFakeAtlas(t)
+	trace := createMeasurement(t, srv, "traceroute", "192.0.2.1")
+	ping := createMeasurement(t, srv, "ping", "192.0.2.2")
+	stopped := createMeasurement(t, srv, "traceroute", "192.0.2.2")
+	if out, err := runCLI(t, srv, "measurement", "stop", fmt.Sprint(stopped)); err != nil {
+		t.Fatalf("measurement stop: %v\n%s", err, out)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		want  []int
+		shown string
+	}{
+		{"all", nil, []int{stopped, ping, trace}, "Showing 3 of 3"},
+		{"status", []string{"--status", "Scheduled,ongoing"}, []int{ping, trace}, "Showing 2 of 2"},
+		{"forced status", []string{"--status", "forced"}, []int{stopped}, "Showing 1 of 1"},
+		{"type", []string{"--type", "ping"}, []int{ping}, "Showing 1 of 1"},
+		{"target", []string{"--target", "192.0.2.2"}, []int{stopped, ping}, "Showing 2 of 2"},
+		{"type and target", []string{"--type", "traceroute", "--target", "192.0.2.2"}, []int{stopped}, "Showing 1 of 1"},
+		{"tags", []string{"--tags", "nightly"}, nil, "Showing 0 of 0"},
+		{"limit", []string{"--limit", "2"}, []int{stopped, ping}, "Showing 2 of 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCLI(t, srv, append([]string{"measurement", "list"}, tt.args...)...)
+			if err != nil {
+				t.Fatalf("measurement list: %v\n%s", err, out)
+			}
+			if got := listedIDs(out); !slices.Equal(got, tt.want) {
+				t.Errorf("listed %v, want %v\n%s", got, tt.want, out)
+			}
+			if !strings.Contains(out, tt.shown) {
+				t.Errorf("output lacks %q:\n%s", tt.shown, out)
+			}
+		})
+	}
+}
+
+func TestMeasurementListUnknownStatus(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	_, err := runCLI(t, srv, "measurement", "list", "--status", "running")
+	if err == nil || !strings.Contains(err.Error(), "unknown status: running") {
+		t.Errorf("measurement list --status running = %v, want an unknown status error", err)
+	}
+}
+
+func TestMeasurementStopAlreadyStopped(t *testing.T) {
+	srv := newFakeAtlas(t)
+	runDemoTraceroute(t, srv)
+
+	out, err := runCLI(t, srv, "measurement", "stop", "1000001")
+	if err != nil {
+		t.Fatalf("measurement stop: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "1000001 already finished (Stopped)") {
+		t.Errorf("output lacks the already finished note:\n%s", out)
+	}
+	if status, _ := srv.Measurement(1000001); status.Status.ID != atlas.MeasurementStopped {
+		t.Errorf("status = %+v, want it left stopped", status.Status)
+	}
+}
+
+func TestMeasurementStopSeveral(t *testing.T) {
+	srv := newFakeAtlas(t)
+	running := createMeasurement(t, srv, "traceroute", "192.0.2.1")
+
+	out, err := runCLI(t, srv, "measurement", "stop", fmt.Sprint(running), "42", "x")
+	if err == nil {
+		t.Fatalf("measurement stop of an unknown and an invalid ID succeeded\n%s", out)
+	}
+	for _, want := range []string{"failed to stop measurement 42", "invalid measurement ID: x"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q lacks %q", err, want)
+		}
+	}
+
+	// The valid ID is stopped despite the others failing
+	if !strings.Contains(out, fmt.Sprintf("%d stopped", running)) {
+		t.Errorf("output lacks the stopped measurement:\n%s", out)
+	}
+	if status, _ := srv.Measurement(running); status.Status.ID != atlas.MeasurementForcedStop {
+		t.Errorf("status = %+v, want forced stop", status.Status)
+	}
+}
+
+func TestMeasurementStatus(t *testing.T) {
+	srv := newFakeAtlas(t)
+	runDemoTraceroute(t, srv)
+
+	out, err := runCLI(t, srv, "measurement", "status", "1000001")
+	if err != nil {
+		t.Fatalf("measurement status: %v\n%s", err, out)
+	}
+	if !strings.Contains(out, "Stopped") {
+		t.Errorf("output lacks the status:\n%s", out)
+	}
+
+	if _, err := runCLI(t, srv, "measurement", "status", "42"); err == nil {
+		t.Error("measurement status of an unknown ID succeeded")
+	}
+}
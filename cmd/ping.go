@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pingASNsFlag    string
+	pingTargetFlag  string
+	pingPacketsFlag int
+	pingSizeFlag    int
+)
+
+func init() {
+	pingCmd.Flags().StringVar(&pingASNsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	pingCmd.Flags().StringVar(&pingTargetFlag, "target", "", "Target IP or AWS region (e.g., aws_us-west-2) (required)")
+	pingCmd.Flags().IntVar(&pingPacketsFlag, "packets", 3, fmt.Sprintf("Packets sent by each probe (1-%d)", atlas.MaxPackets))
+	pingCmd.Flags().IntVar(&pingSizeFlag, "size", 48, fmt.Sprintf("Packet size in bytes (0-%d)", atlas.MaxPacketSize))
+	selectorOpts.addFlags(pingCmd)
+	addDryRunFlag(pingCmd)
+
+	pingCmd.MarkFlagRequired("target")
+
+	rootCmd.AddCommand(pingCmd)
+}
+
+var pingCmd = &cobra.Command{
+	Use:   "ping",
+	Short: "Run ping measurement and report latency per ASN and country",
+	Long: `Run ping measurements from specified ASNs to a target IP or AWS region.
+Reports min, median, p95 and max RTT and packet loss per source ASN and per probe country.
+
+Example:
+  ripeatlas ping --asns 5384,7713 --target 1.2.3.4
+  ripeatlas ping --asns 5384,7713,9988 --target aws_us-west-2 --packets 5`,
+	PreRunE: requireAPIKey,
+	RunE:    runPing,
+}
+
+func runPing(cmd *cobra.Command, args []string) error {
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
+
+	asns, err := parseSourceASNs(pingASNsFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas ping measurement...\n\n")
+
+	target, err := resolveTarget(pingTargetFlag)
+	if err != nil {
+		return err
+	}
+
+	size := pingSizeFlag
+	def := atlas.MeasurementDefinition{
+		Type:        "ping",
+		AF:          4,
+		Target:      target,
+		Description: fmt.Sprintf("Ping to %s from %s", pingTargetFlag, describeSources(asns)),
+		Packets:     pingPacketsFlag,
+		Size:        &size,
+	}
+	if err := def.Validate(); err != nil {
+		return err
+	}
+
+	if err := run.selectProbes(asns, 4); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Creating ping measurement...\n")
+	fmt.Printf("   Target: %s\n", target)
+	fmt.Printf("   Probes: %d\n", run.sel.total())
+
+	if created, err := run.create(def); !created {
+		return err
+	}
+
+	results, err := runResults(run, "ping", run.client.GetPingResults)
+	if err != nil {
+		return err
+	}
+
+	probes := run.probesOf(resultProbeIDs(results, func(r atlas.PingResult) int { return r.ProbeID }))
+
+	report := atlas.LatencyReport{
+		Report:  run.baseReport(pingTargetFlag, len(results)),
+		Overall: analyzer.OverallLatency(results),
+		ByASN: analyzer.SummarizeLatency(results, func(r atlas.PingResult) string {
+			if asn := probes[r.ProbeID].ASNV4; asn > 0 {
+				return fmt.Sprintf("AS%d", asn)
+			}
+			return ""
+		}),
+		ByCountry: analyzer.SummarizeLatency(results, func(r atlas.PingResult) string {
+			if cc := probes[r.ProbeID].CountryCode; cc != "" {
+				return cc
+			}
+			return "Unknown"
+		}),
+	}
+
+	fmt.Println(atlas.GenerateLatencyReport(report))
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestPingValidatesDefinition(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--packets", "17"}, "packets must be between 1 and 16"},
+		{[]string{"--size", "4096"}, "size must be between 0 and 2048"},
+	}
+
+	for _, tt := range tests {
+		srv := newFakeAtlas(t)
+
+		args := append([]string{"ping", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8"}, tt.args...)
+		out, err := runCLI(t, srv, args...)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ping %v = %v, want %q\n%s", tt.args, err, tt.want, out)
+		}
+		if _, ok := srv.Measurement(1000001); ok {
+			t.Errorf("ping %v created a measurement", tt.args)
+		}
+	}
+}
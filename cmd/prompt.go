@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// stdinLines returns the lines typed on stdin, closed at EOF. A single
+// reader goroutine serves all prompts, so a prompt abandoned on Ctrl-C leaves
+// the next line to the next prompt instead of swallowing it.
+var stdinLines = sync.OnceValue(func() <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+})
+
+// confirm asks a yes/no question on stdin, a closed stdin answers no.
+// It returns ctx.Err() if the context is cancelled while waiting for an answer.
+func confirm(ctx context.Context, question string) (bool, error) {
+	fmt.Print(question)
+
+	select {
+	case <-ctx.Done():
+		fmt.Println()
+		return false, ctx.Err()
+	case response := <-stdinLines():
+		response = strings.TrimSpace(strings.ToLower(response))
+		return response == "y" || response == "yes", nil
+	}
+}
@@ -1,16 +1,38 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/cmingou/ripeatlas-cli/internal/config"
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/httpretry"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	cfg     *config.Config
+	cfgFile           string
+	apiKeyFlag        string
+	readAPIKeyFlag    string
+	profileFlag       string
+	pageSizeFlag      int
+	maxRetriesFlag    int
+	retryMaxDelayFlag time.Duration
+	apiURLFlag        string
+	ripestatURLFlag   string
+	pollIntervalFlag  time.Duration
+	cfg               *config.Config
+	cfgErr            error
+
+	// profileApplied holds the flags set from the --profile profile
+	profileApplied = make(map[string]bool)
 )
 
 // rootCmd represents the base command
@@ -23,6 +45,39 @@ This tool helps you:
 - Select probes from multiple ASNs
 - Run traceroute measurements to target IPs or AWS regions
 - Analyze common ASN paths across multiple traceroutes`,
+	PersistentPreRunE: applyProfile,
+}
+
+// Process exit codes, so scripts can react to specific failures
+const (
+	ExitError             = 1
+	ExitAuth              = 3
+	ExitQuotaExceeded     = 4
+	ExitNoSuitableProbes  = 5
+	ExitMeasurementFailed = 6
+	ExitRateLimited       = 7
+	ExitInterrupted       = 130
+)
+
+// ExitCode maps an error returned by Execute to a process exit code
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, atlas.ErrAuth):
+		return ExitAuth
+	case errors.Is(err, atlas.ErrQuotaExceeded):
+		return ExitQuotaExceeded
+	case errors.Is(err, atlas.ErrNoSuitableProbes):
+		return ExitNoSuitableProbes
+	case errors.Is(err, atlas.ErrMeasurementFailed):
+		return ExitMeasurementFailed
+	case errors.Is(err, atlas.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	}
+	return ExitError
 }
 
 // Execute runs the root command
@@ -33,19 +88,112 @@ func Execute() error {
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (optional, default checks RIPE_ATLAS_API env or ~/.env.key)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (optional, overrides RIPE_ATLAS_API env and the default files)")
+	rootCmd.PersistentFlags().StringVar(&apiKeyFlag, "api-key", "", "RIPE Atlas API key (overrides the config file and environment)")
+	rootCmd.PersistentFlags().StringVar(&readAPIKeyFlag, "read-api-key", "", "Separate API key for read-only requests (probes, results)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Named profile from the config file supplying flag defaults")
+	rootCmd.PersistentFlags().IntVar(&maxRetriesFlag, "max-retries", httpretry.DefaultPolicy().MaxRetries, "Retries for transient API failures (5xx, 429, network errors)")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxDelayFlag, "retry-max-delay", httpretry.DefaultPolicy().MaxDelay, "Longest backoff between retries, also the longest Retry-After honored")
+	rootCmd.PersistentFlags().StringVar(&apiURLFlag, "api-url", atlas.BaseURL, "RIPE Atlas API root (e.g. a local fake server for testing)")
+	rootCmd.PersistentFlags().StringVar(&ripestatURLFlag, "ripestat-url", analyzer.DefaultRIPEstatURL, "RIPEstat data API root")
+	rootCmd.PersistentFlags().DurationVar(&pollIntervalFlag, "poll-interval", atlas.DefaultPollInterval, "How often to check on a running measurement")
+	rootCmd.PersistentFlags().IntVar(&pageSizeFlag, "page-size", atlas.DefaultPageSize, "Page size for paginated RIPE Atlas API requests")
 }
 
 func initConfig() {
-	var err error
-	cfg, err = config.LoadWithPriority(cfgFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+	// A missing key is only an error for commands that need one, see requireAPIKey
+	cfg, cfgErr = config.LoadWithPriority(config.Options{
+		ConfigPath: cfgFile,
+		APIKey:     apiKeyFlag,
+		ReadAPIKey: readAPIKeyFlag,
+	})
+}
+
+// applyProfile sets every flag of the selected profile that was not given on
+// the command line, then configures the packages that read global flags.
+// It runs before cobra validates required flags, so a profile can supply --asns.
+func applyProfile(cmd *cobra.Command, args []string) error {
+	if err := setProfileFlags(cmd); err != nil {
+		return err
+	}
+
+	analyzer.SetBaseURL(ripestatURLFlag)
+	analyzer.SetRetryPolicy(retryPolicy())
+	return nil
+}
+
+// unprofiledFlags are read before profiles are applied, so a profile cannot set them
+var unprofiledFlags = []string{"profile", "config", "api-key", "read-api-key"}
+
+// setProfileFlags copies the values of the --profile profile into unset flags of cmd
+func setProfileFlags(cmd *cobra.Command) error {
+	if profileFlag == "" {
+		return nil
+	}
+	if cfg == nil {
+		return fmt.Errorf("error loading config: %w", cfgErr)
 	}
+
+	profile, ok := cfg.Profiles[profileFlag]
+	if !ok {
+		available := slices.Sorted(maps.Keys(cfg.Profiles))
+		if len(available) == 0 {
+			return fmt.Errorf("profile %q not found: no profiles defined in the config file", profileFlag)
+		}
+		return fmt.Errorf("profile %q not found, available: %s", profileFlag, strings.Join(available, ", "))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(profile)) {
+		flag := cmd.Flags().Lookup(name)
+		switch {
+		case flag == nil || slices.Contains(unprofiledFlags, name):
+			fmt.Fprintf(os.Stderr, "⚠️  Profile %q: %s does not apply to %s, ignored\n", profileFlag, name, cmd.CommandPath())
+		case flag.Changed:
+			// Flags given on the command line win over the profile
+		default:
+			if err := cmd.Flags().Set(name, profile[name]); err != nil {
+				return fmt.Errorf("profile %q: invalid %s: %w", profileFlag, name, err)
+			}
+			profileApplied[name] = true
+		}
+	}
+
+	return nil
+}
+
+// requireAPIKey is used as PreRunE by commands that must authenticate against RIPE Atlas
+func requireAPIKey(cmd *cobra.Command, args []string) error {
+	if cfgErr != nil {
+		return fmt.Errorf("error loading config: %w", cfgErr)
+	}
+	return nil
 }
 
-// GetConfig returns the loaded configuration
+// GetConfig returns the loaded configuration, nil if the config file could not be read
 func GetConfig() *config.Config {
 	return cfg
 }
+
+// newAtlasClient creates an Atlas API client from the loaded configuration and global flags
+func newAtlasClient() *atlas.Client {
+	apiKey, readKey := "", ""
+	if cfg != nil {
+		apiKey, readKey = cfg.APIKey, cfg.ReadAPIKey
+	}
+
+	return atlas.NewClient(apiKey,
+		atlas.WithReadKey(readKey),
+		atlas.WithBaseURL(apiURLFlag),
+		atlas.WithPageSize(pageSizeFlag),
+		atlas.WithPollInterval(pollIntervalFlag),
+		atlas.WithRetryPolicy(retryPolicy()),
+	)
+}
+
+// retryPolicy builds the HTTP retry policy from the global flags
+func retryPolicy() httpretry.Policy {
+	policy := httpretry.DefaultPolicy()
+	policy.MaxRetries = max(maxRetriesFlag, 0)
+	policy.MaxDelay = retryMaxDelayFlag
+	return policy
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{errors.New("boom"), ExitError},
+		{&atlas.APIError{StatusCode: 403}, ExitAuth},
+		{fmt.Errorf("failed to create measurement: %w", &atlas.APIError{StatusCode: 400, Detail: "Not enough credit"}), ExitQuotaExceeded},
+		{&atlas.APIError{StatusCode: 400, Detail: "max hops exceeded"}, ExitError},
+		{&atlas.APIError{StatusCode: 429, Detail: "quota exceeded"}, ExitRateLimited},
+		{fmt.Errorf("wait: %w", atlas.ErrNoSuitableProbes), ExitNoSuitableProbes},
+		{atlas.ErrMeasurementFailed, ExitMeasurementFailed},
+		{fmt.Errorf("interrupted: %w", context.Canceled), ExitInterrupted},
+		{errInterruptedEarly, ExitInterrupted},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+// newFakeAtlas starts a fake Atlas with the demo topology for runCLI
+func newFakeAtlas(t *testing.T) *atlastest.Server {
+	t.Helper()
+
+	srv := atlastest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SeedDemo()
+	stubLookup(t, map[string][]netip.Addr{
+		"anchor.example":  {netip.MustParseAddr("192.0.2.80")},
+		"anchor6.example": {netip.MustParseAddr("2001:db8::80")},
+	})
+	return srv
+}
+
+// runCLI runs the command line args against srv and returns what it printed
+// on stdout. Flags are reset first, so every call starts from the defaults.
+func runCLI(t *testing.T, srv *atlastest.Server, args ...string) (string, error) {
+	t.Helper()
+
+	return runCLIContext(t, context.Background(), srv, args...)
+}
+
+// runCLIContext is runCLI with ctx standing in for the signal context, so
+// cancelling it acts like Ctrl-C
+func runCLIContext(t *testing.T, ctx context.Context, srv *atlastest.Server, args ...string) (string, error) {
+	t.Helper()
+
+	resetFlags(rootCmd)
+	setContext(rootCmd, ctx)
+	selectorOpts.rng = nil
+	clear(profileApplied)
+
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	rootCmd.SetArgs(append(args,
+		"--api-key", "test",
+		"--api-url", srv.URL(),
+		"--ripestat-url", srv.StatURL(),
+		"--poll-interval", "5ms",
+	))
+	runErr := rootCmd.ExecuteContext(ctx)
+
+	printed, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(printed), runErr
+}
+
+// resetFlags sets every flag of cmd and its subcommands back to its default
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// setContext gives cmd and its subcommands ctx. Cobra only passes the context
+// of Execute on to commands without one, so the first run's would stick.
+func setContext(cmd *cobra.Command, ctx context.Context) {
+	cmd.SetContext(ctx)
+	for _, sub := range cmd.Commands() {
+		setContext(sub, ctx)
+	}
+}
+
+func TestInterruptedBeforeResults(t *testing.T) {
+	tests := [][]string{
+		{"traceroute", "--target", "8.8.8.8"},
+		{"ping", "--target", "8.8.8.8"},
+		{"dns", "--query", "example.com"},
+		{"http", "--target", "anchor.example"},
+		{"sslcert", "--target", "example.com"},
+		{"dualstack", "--target", "example.com"},
+	}
+
+	for _, args := range tests {
+		t.Run(args[0], func(t *testing.T) {
+			srv := newFakeAtlas(t)
+			srv.PollsPerStage = 1000 // no results before the interrupt
+
+			// Ctrl-C while the measurement is being created
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			srv.CreateHook = func(atlas.MeasurementRequest) error {
+				cancel()
+				return nil
+			}
+
+			out, err := runCLIContext(t, ctx, srv, append(args, "--asns", fmt.Sprint(atlastest.DemoEyeballA))...)
+			if ExitCode(err) != ExitInterrupted || !strings.Contains(err.Error(), "interrupted before") {
+				t.Fatalf("%s = %v, want an interrupt with exit code %d\n%s", args[0], err, ExitInterrupted, out)
+			}
+			if status, ok := srv.Measurement(1000001); !ok || status.Status.ID != atlas.MeasurementForcedStop {
+				t.Errorf("measurement = %+v, want it stopped", status)
+			}
+		})
+	}
+}
+
+func TestDualStackInterruptedBeforeCreate(t *testing.T) {
+	srv := newFakeAtlas(t)
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := atlas.WriteManifestFile(path, &atlas.SelectionManifest{Probes: []int{1001, 2001}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ctrl-C before anything was created
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	out, err := runCLIContext(t, ctx, srv, "dualstack", "--target", "example.com", "--probes-from", path)
+	if ExitCode(err) != ExitInterrupted || !strings.Contains(err.Error(), "interrupted before the measurements were created") {
+		t.Fatalf("dualstack = %v, want an interrupt with exit code %d\n%s", err, ExitInterrupted, out)
+	}
+	if _, ok := srv.Measurement(1000001); ok {
+		t.Error("a measurement was created after the interrupt")
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+// defaultSelectorProbes is the number of probes a --from-* selector requests without :N
+const defaultSelectorProbes = 10
+
+// probeSelectors are the probe selection flags shared by all measurement
+// commands: server-side sets Atlas picks probes for itself, next to the
+// probes allocated from --asns, and quality filters applied to both.
+type probeSelectors struct {
+	countries   []string
+	areas       []string
+	prefixes    []string
+	asns        []string
+	msms        []string
+	tagsInclude []string
+	tagsExclude []string
+	excludeNAT  bool
+	minUptime   float64
+	anchors     string
+	sampling    string
+	dedup       bool
+	allocation  string
+	weightsFile string
+	limits      atlas.AllocationLimits
+	seed        int64
+	manifest    string
+	probesFrom  string
+
+	// rng is the random source of the run, see random
+	rng *rand.Rand
+}
+
+// Values of the --anchors flag
+const (
+	anchorsAny     = "any"
+	anchorsPrefer  = "prefer"
+	anchorsExclude = "exclude"
+)
+
+// selectorOpts holds the selector flags of the command being run
+var selectorOpts probeSelectors
+
+// addFlags registers the selector flags on cmd
+func (o *probeSelectors) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&o.countries, "from-country", nil, "Let Atlas pick probes in these countries, as CC[:N] (e.g. DE:20)")
+	cmd.Flags().StringSliceVar(&o.areas, "from-area", nil, "Let Atlas pick probes in these areas, as AREA[:N] ("+strings.Join(atlas.Areas, ", ")+")")
+	cmd.Flags().StringSliceVar(&o.prefixes, "from-prefix", nil, "Let Atlas pick probes in these prefixes, as PREFIX[:N]")
+	cmd.Flags().StringSliceVar(&o.asns, "from-asn", nil, "Let Atlas pick probes in these ASNs, as ASN[:N]")
+	cmd.Flags().StringSliceVar(&o.msms, "from-msm", nil, "Reuse the probes of these measurements, as ID[:N]")
+	cmd.Flags().StringSliceVar(&o.tagsInclude, "tags-include", nil, "Only use probes with all of these tags (e.g. system-ipv4-works)")
+	cmd.Flags().StringSliceVar(&o.tagsExclude, "tags-exclude", nil, "Never use probes with any of these tags")
+	cmd.Flags().BoolVar(&o.excludeNAT, "exclude-nat", false, "Skip probes behind RFC1918 NAT (tag "+atlas.TagIPv4RFC1918+")")
+	cmd.Flags().Float64Var(&o.minUptime, "min-uptime", 0, "Skip --asns probes connected less than this fraction of the time since their first connection (e.g. 0.95)")
+	cmd.Flags().StringVar(&o.anchors, "anchors", anchorsAny, "Anchors among --asns probes: any, prefer or exclude")
+	cmd.Flags().StringVar(&o.sampling, "sampling", atlas.SamplingRandom, "How probes are picked within an ASN: random, or geo to spread them across locations")
+	cmd.Flags().BoolVar(&o.dedup, "dedup-prefix", false, "Use at most one --asns probe per /24 (IPv4) or /48 (IPv6)")
+	cmd.Flags().StringVar(&o.allocation, "allocation", "", "How many probes each of --asns contributes: equal, proportional or weighted (default: equal, weighted with --weights)")
+	cmd.Flags().StringVar(&o.weightsFile, "weights", "", "File with one \"ASN WEIGHT\" pair per line for --allocation weighted")
+	cmd.Flags().IntVar(&o.limits.Budget, "budget", 0, fmt.Sprintf("Total probes allocated from --asns (default: %d)", atlas.MaxProbesPerMeasurement))
+	cmd.Flags().IntVar(&o.limits.MinPerASN, "min-per-asn", 0, "Probes allocated from every ASN before the rest is shared out")
+	cmd.Flags().IntVar(&o.limits.MaxPerASN, "max-per-asn", 0, "Most probes allocated from a single ASN (default: no limit)")
+	cmd.Flags().Int64Var(&o.seed, "seed", 0, "Seed for the random probe and AWS target IP picks, to repeat a selection (default: a new seed per run)")
+	cmd.Flags().StringVar(&o.manifest, "manifest", "", "Write the probes, targets, strategy and seed of the run to this JSON file")
+	cmd.Flags().StringVar(&o.probesFrom, "probes-from", "", "Use the probes recorded in a --manifest file instead of --asns")
+}
+
+// random returns the random source of the run. Without --seed a seed is drawn
+// once, so the manifest can still repeat the run.
+func (o *probeSelectors) random() *rand.Rand {
+	if o.rng == nil {
+		if o.seed == 0 {
+			o.seed = time.Now().UnixNano()
+		}
+		o.rng = rand.New(rand.NewSource(o.seed))
+	}
+	return o.rng
+}
+
+// empty reports whether no --from-* selector was given
+func (o *probeSelectors) empty() bool {
+	return len(o.countries)+len(o.areas)+len(o.prefixes)+len(o.asns)+len(o.msms) == 0
+}
+
+// tags returns the tag filter applied to every probe set, nil without tag flags
+func (o *probeSelectors) tags() *atlas.ProbeTags {
+	exclude := o.tagsExclude
+	if o.excludeNAT {
+		exclude = append(slices.Clip(exclude), atlas.TagIPv4RFC1918)
+	}
+
+	if len(o.tagsInclude) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	return &atlas.ProbeTags{Include: o.tagsInclude, Exclude: exclude}
+}
+
+// filter returns the quality filter for probes allocated from --asns. Unlike
+// server-side sets these can also be checked for uptime and anchor status.
+func (o *probeSelectors) filter() (atlas.ProbeFilter, error) {
+	if o.minUptime < 0 || o.minUptime > 1 {
+		return atlas.ProbeFilter{}, fmt.Errorf("invalid --min-uptime %g: must be between 0 and 1", o.minUptime)
+	}
+	if !slices.Contains([]string{anchorsAny, anchorsPrefer, anchorsExclude}, o.anchors) {
+		return atlas.ProbeFilter{}, fmt.Errorf("invalid --anchors %q: must be any, prefer or exclude", o.anchors)
+	}
+	if o.sampling != atlas.SamplingRandom && o.sampling != atlas.SamplingGeo {
+		return atlas.ProbeFilter{}, fmt.Errorf("invalid --sampling %q: must be random or geo", o.sampling)
+	}
+
+	filter := atlas.ProbeFilter{
+		MinUptime:      o.minUptime,
+		ExcludeAnchors: o.anchors == anchorsExclude,
+	}
+	if tags := o.tags(); tags != nil {
+		filter.IncludeTags, filter.ExcludeTags = tags.Include, tags.Exclude
+	}
+	return filter, nil
+}
+
+// allocationOptions returns how many probes each ASN contributes and how
+// they are picked within an ASN
+func (o *probeSelectors) allocationOptions() (atlas.AllocationOptions, error) {
+	opts := atlas.AllocationOptions{
+		PreferAnchors: o.anchors == anchorsPrefer,
+		Sampling:      o.sampling,
+		Rand:          o.random(),
+	}
+
+	name := o.allocation
+	if name == "" && o.weightsFile != "" {
+		name = atlas.AllocatorWeighted
+	}
+
+	var weights map[int]float64
+	switch {
+	case o.weightsFile != "" && name != atlas.AllocatorWeighted:
+		return opts, fmt.Errorf("--weights only applies to --allocation %s", atlas.AllocatorWeighted)
+	case o.weightsFile == "" && name == atlas.AllocatorWeighted:
+		return opts, fmt.Errorf("--allocation %s requires --weights", atlas.AllocatorWeighted)
+	case o.weightsFile != "":
+		var err error
+		if weights, err = atlas.ReadWeightsFile(o.weightsFile); err != nil {
+			return opts, err
+		}
+	}
+
+	allocator, err := atlas.NewAllocator(name, o.limits, weights, o.weightsFile)
+	if err != nil {
+		return opts, fmt.Errorf("invalid probe allocation: %w", err)
+	}
+	opts.Allocator = allocator
+
+	return opts, nil
+}
+
+// probeSets parses the --from-* flags into server-side probe sets
+func (o *probeSelectors) probeSets() ([]atlas.ProbeSet, error) {
+	var sets []atlas.ProbeSet
+
+	for _, selector := range []struct {
+		setType string
+		specs   []string
+	}{
+		{atlas.ProbeSetCountry, o.countries},
+		{atlas.ProbeSetArea, o.areas},
+		{atlas.ProbeSetPrefix, o.prefixes},
+		{atlas.ProbeSetASN, o.asns},
+		{atlas.ProbeSetMsm, o.msms},
+	} {
+		for _, spec := range selector.specs {
+			set, err := atlas.ParseProbeSet(selector.setType, spec, defaultSelectorProbes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --from-%s: %w", selector.setType, err)
+			}
+			set.Tags = o.tags()
+			sets = append(sets, set)
+		}
+	}
+
+	return sets, nil
+}
+
+// parseSourceASNs parses the --asns flag of a measurement command, which may
+// be empty when --from-* selectors choose the probes instead
+func parseSourceASNs(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		if selectorOpts.empty() && selectorOpts.probesFrom == "" {
+			return nil, fmt.Errorf("--asns, --probes-from or a --from-* probe selector is required")
+		}
+		return nil, nil
+	}
+	if selectorOpts.probesFrom != "" {
+		return nil, fmt.Errorf("--asns and --probes-from cannot be combined")
+	}
+
+	asns, err := parseASNs(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ASNs: %w", err)
+	}
+	return asns, nil
+}
+
+// describeSources names the probe sources for measurement descriptions, e.g.
+// "ASNs 5384,7713 and country DE"
+func describeSources(asns []int) string {
+	var sources []string
+	if len(asns) > 0 {
+		sources = append(sources, "ASNs "+joinASNs(asns))
+	}
+	if selectorOpts.probesFrom != "" {
+		sources = append(sources, "probes of "+filepath.Base(selectorOpts.probesFrom))
+	}
+
+	// Invalid selectors are reported by selectProbes
+	sets, _ := selectorOpts.probeSets()
+	for _, set := range sets {
+		sources = append(sources, set.Type+" "+set.Value)
+	}
+
+	return strings.Join(sources, " and ")
+}
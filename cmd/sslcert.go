@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sslcertASNsFlag        string
+	sslcertTargetFlag      string
+	sslcertPortFlag        int
+	sslcertHostnameFlag    string
+	sslcertFingerprintFlag string
+)
+
+func init() {
+	sslcertCmd.Flags().StringVar(&sslcertASNsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	sslcertCmd.Flags().StringVar(&sslcertTargetFlag, "target", "", "Target host name or IP (required)")
+	sslcertCmd.Flags().IntVar(&sslcertPortFlag, "port", 443, "Target port")
+	sslcertCmd.Flags().StringVar(&sslcertHostnameFlag, "hostname", "", "Server name sent in SNI (default: target when it is a host name)")
+	sslcertCmd.Flags().StringVar(&sslcertFingerprintFlag, "expect-fingerprint", "", "Expected SHA-256 fingerprint of the leaf certificate")
+	selectorOpts.addFlags(sslcertCmd)
+	addDryRunFlag(sslcertCmd)
+
+	sslcertCmd.MarkFlagRequired("target")
+
+	rootCmd.AddCommand(sslcertCmd)
+}
+
+var sslcertCmd = &cobra.Command{
+	Use:   "sslcert",
+	Short: "Fetch TLS certificates from selected ASNs and compare fingerprints",
+	Long: `Run SSL certificate measurements from specified ASNs to a target host.
+Reports the SHA-256 fingerprint of the certificate served to each ASN and,
+with --expect-fingerprint, every probe served a different leaf certificate.
+Intermediate and root certificates in the chain are not compared.
+
+Example:
+  ripeatlas sslcert --asns 5384,7713 --target example.com
+  ripeatlas sslcert --asns 5384,7713 --target example.com --expect-fingerprint 5E:F2:...:9A`,
+	PreRunE: requireAPIKey,
+	RunE:    runSSLCert,
+}
+
+func runSSLCert(cmd *cobra.Command, args []string) error {
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
+
+	asns, err := parseSourceASNs(sslcertASNsFlag)
+	if err != nil {
+		return err
+	}
+
+	var expected string
+	if sslcertFingerprintFlag != "" {
+		if expected, err = atlas.ParseFingerprint(sslcertFingerprintFlag); err != nil {
+			return err
+		}
+	}
+
+	hostname := sslcertHostnameFlag
+	if _, err := netip.ParseAddr(sslcertTargetFlag); hostname == "" && err != nil {
+		hostname = sslcertTargetFlag
+	}
+
+	fmt.Printf("🔍 Initializing RIPE Atlas SSL certificate measurement...\n\n")
+
+	if err := run.selectProbes(asns, 4); err != nil {
+		return err
+	}
+
+	fmt.Printf("🚀 Creating SSL certificate measurement...\n")
+	fmt.Printf("   Target: %s:%d\n", sslcertTargetFlag, sslcertPortFlag)
+	fmt.Printf("   Probes: %d\n", run.sel.total())
+
+	def := atlas.MeasurementDefinition{
+		Type:        "sslcert",
+		AF:          4,
+		Target:      sslcertTargetFlag,
+		Description: fmt.Sprintf("SSL certificate of %s:%d from %s", sslcertTargetFlag, sslcertPortFlag, describeSources(asns)),
+		Port:        sslcertPortFlag,
+		Hostname:    hostname,
+	}
+	if created, err := run.create(def); !created {
+		return err
+	}
+
+	results, err := runResults(run, "SSL certificate", run.client.GetSSLCertResults)
+	if err != nil {
+		return err
+	}
+
+	probes := run.probesOf(resultProbeIDs(results, func(r atlas.SSLCertResult) int { return r.ProbeID }))
+	byASN, mismatches := analyzer.SummarizeCerts(results, func(r atlas.SSLCertResult) int {
+		return probes[r.ProbeID].ASNV4
+	}, expected)
+
+	report := atlas.CertReport{
+		Report:     run.baseReport(fmt.Sprintf("%s:%d", sslcertTargetFlag, sslcertPortFlag), len(results)),
+		Expected:   expected,
+		ByASN:      byASN,
+		Mismatches: mismatches,
+	}
+
+	fmt.Println(atlas.GenerateCertReport(report))
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestSSLCertExpectFingerprintComparesLeaf(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	// Intercepted probes get a chain that carries the genuine certificate
+	// behind the proxy's own leaf
+	srv.Result = func(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+		result := srv.SSLCertResult(def, msmID, probe).(atlas.SSLCertResult)
+		if probe.ID%10 == 3 {
+			genuine := srv.SSLCertResult(def, msmID, atlas.Probe{ID: 1}).(atlas.SSLCertResult)
+			result.Cert = append(result.Cert, genuine.Cert...)
+		}
+		return result
+	}
+
+	out, err := runCLI(t, srv, "sslcert",
+		"--asns", fmt.Sprint(atlastest.DemoEyeballA),
+		"--target", "example.com",
+		"--expect-fingerprint", atlastest.DemoCertFingerprint,
+	)
+	if err != nil {
+		t.Fatalf("sslcert: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		"Fingerprint Mismatches (1):",
+		"Probe 1003",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
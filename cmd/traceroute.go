@@ -1,16 +1,12 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/cmingou/ripeatlas-cli/pkg/analyzer"
 	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
-	"github.com/cmingou/ripeatlas-cli/pkg/aws"
 	"github.com/spf13/cobra"
 )
 
@@ -18,15 +14,27 @@ var (
 	asnsFlag      string
 	targetFlag    string
 	thresholdFlag float64
+	saveFlag      string
+	afFlag        string
+
+	targetsFileFlag string
+
+	tracerouteOpts tracerouteOptions
 )
 
 func init() {
-	tracerouteCmd.Flags().StringVar(&asnsFlag, "asns", "", "Comma-separated list of ASNs (required)")
-	tracerouteCmd.Flags().StringVar(&targetFlag, "target", "", "Target IP or AWS region (e.g., aws_us-west-2) (required)")
+	tracerouteCmd.Flags().StringVar(&asnsFlag, "asns", "", "Comma-separated list of ASNs to allocate probes from")
+	tracerouteCmd.Flags().StringVar(&targetFlag, "target", "", "Target IP or AWS region (e.g., aws_us-west-2)")
+	tracerouteCmd.Flags().StringVar(&targetsFileFlag, "targets-file", "", "File with one target per line (IPs, host names or AWS regions), measured from the same probes")
 	tracerouteCmd.Flags().Float64Var(&thresholdFlag, "threshold", 0.8, "Threshold for common ASN (default: 0.8 = 80%)")
+	tracerouteCmd.Flags().StringVar(&saveFlag, "save", "", "Save the raw traceroute results to this JSON file")
+	tracerouteCmd.Flags().StringVar(&afFlag, "af", "auto", "Address family: 4, 6 or auto (IPv6 for IPv6 addresses and host names with only AAAA records)")
+	tracerouteOpts.addFlags(tracerouteCmd)
+	selectorOpts.addFlags(tracerouteCmd)
+	addDryRunFlag(tracerouteCmd)
 
-	tracerouteCmd.MarkFlagRequired("asns")
-	tracerouteCmd.MarkFlagRequired("target")
+	tracerouteCmd.MarkFlagsOneRequired("target", "targets-file")
+	tracerouteCmd.MarkFlagsMutuallyExclusive("target", "targets-file")
 
 	rootCmd.AddCommand(tracerouteCmd)
 }
@@ -39,193 +47,86 @@ Analyzes the results to find common ASN paths.
 
 Example:
   ripeatlas traceroute --asns 5384,7713 --target 1.2.3.4
-  ripeatlas traceroute --asns 5384,7713,9988 --target aws_us-west-2 --threshold 0.85`,
-	RunE: runTraceroute,
+  ripeatlas traceroute --asns 5384,7713,9988 --target aws_us-west-2 --threshold 0.85
+  ripeatlas traceroute --asns 5384,7713 --target 2001:db8::1
+  ripeatlas traceroute --asns 5384,7713 --target 1.2.3.4 --protocol TCP --port 443 --max-hops 32
+  ripeatlas traceroute --asns 5384,7713 --targets-file targets.txt`,
+	PreRunE: requireAPIKey,
+	RunE:    runTraceroute,
 }
 
 func runTraceroute(cmd *cobra.Command, args []string) error {
-	startTime := time.Now()
+	if targetsFileFlag != "" {
+		return runTracerouteBatch(cmd)
+	}
+
+	run, err := startRun(cmd)
+	if err != nil {
+		return err
+	}
+	defer run.close()
 
 	// Parse ASNs
-	asns, err := parseASNs(asnsFlag)
+	asns, err := parseSourceASNs(asnsFlag)
 	if err != nil {
-		return fmt.Errorf("invalid ASNs: %w", err)
+		return err
 	}
 
 	fmt.Printf("🔍 Initializing RIPE Atlas traceroute measurement...\n\n")
 
 	// Resolve target
-	target := targetFlag
-	if aws.IsAWSRegion(targetFlag) {
-		fmt.Printf("📍 Resolving AWS region: %s\n", targetFlag)
-		ip, err := aws.GetRegionIP(targetFlag)
-		if err != nil {
-			return fmt.Errorf("failed to resolve AWS region: %w", err)
-		}
-		fmt.Printf("   Selected IP: %s\n\n", ip)
-		target = ip
-	}
-
-	// Create Atlas client
-	client := atlas.NewClient(cfg.APIKey)
-
-	// Get probes for ASNs
-	fmt.Printf("🔎 Fetching probes for ASNs: %s\n", asnsFlag)
-	probesByASN, err := client.GetProbesByASN(asns)
+	target, err := resolveTarget(targetFlag)
 	if err != nil {
-		return fmt.Errorf("failed to fetch probes: %w", err)
+		return err
 	}
 
-	// Allocate probes
-	allocations, asnsWithoutProbes, err := atlas.AllocateProbes(probesByASN, asns)
+	af, err := resolveAF(run.ctx, afFlag, target)
 	if err != nil {
-		return fmt.Errorf("probe allocation failed: %w", err)
-	}
-
-	// Display allocation summary
-	var asnsWithProbes []int
-	for _, alloc := range allocations {
-		asnsWithProbes = append(asnsWithProbes, alloc.ASN)
+		return err
 	}
 
-	fmt.Printf("   ASNs with probes: %v\n", asnsWithProbes)
-	if len(asnsWithoutProbes) > 0 {
-		fmt.Printf("   ⚠️  ASNs without probes: %v\n", asnsWithoutProbes)
-
-		// Ask user if they want to continue
-		fmt.Printf("\n❓ Some ASNs have no available probes. Continue? (y/n): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response != "y" && response != "yes" {
-			return fmt.Errorf("operation cancelled by user")
-		}
+	definition, err := tracerouteOpts.definition(af, target,
+		fmt.Sprintf("Traceroute to %s from %s", targetFlag, describeSources(asns)))
+	if err != nil {
+		return err
 	}
-	fmt.Println()
 
-	// Prepare probe list
-	var probeIDs []int
-	for _, alloc := range allocations {
-		probeIDs = append(probeIDs, alloc.ProbeIDs...)
+	if err := run.selectProbes(asns, af); err != nil {
+		return err
 	}
 
 	// Create measurement
 	fmt.Printf("🚀 Creating traceroute measurement...\n")
 	fmt.Printf("   Target: %s\n", target)
-	fmt.Printf("   Probes: %d\n", len(probeIDs))
-
-	measurementReq := atlas.MeasurementRequest{
-		Definitions: []atlas.MeasurementDefinition{
-			{
-				Type:            "traceroute",
-				AF:              4,
-				Target:          target,
-				Description:     fmt.Sprintf("Traceroute to %s from ASNs %s", targetFlag, asnsFlag),
-				Protocol:        "ICMP",
-				Packets:         3,
-				Size:            48,
-				MaxHops:         40,
-				Paris:           16,
-				ResponseTimeout: 4000,
-			},
-		},
-		Probes: []atlas.ProbeSet{
-			{
-				Type:      "probes",
-				Value:     strings.Trim(strings.Join(strings.Fields(fmt.Sprint(probeIDs)), ","), "[]"),
-				Requested: len(probeIDs),
-			},
-		},
-		IsOneoff: true,
-	}
-
-	measurementID, err := client.CreateMeasurement(measurementReq)
-	if err != nil {
-		return fmt.Errorf("failed to create measurement: %w", err)
-	}
-
-	fmt.Printf("   ✅ Measurement created: ID %d\n", measurementID)
-	fmt.Printf("   🔗 https://atlas.ripe.net/measurements/%d\n\n", measurementID)
-
-	// Wait for measurement to complete (with 5-minute timeout)
-	fmt.Printf("⏳ Waiting for measurement to complete...\n")
-
-	waitStartTime := time.Now()
-	timeout := 5 * time.Minute
+	fmt.Printf("   Probes: %d\n", run.sel.total())
 
-	for {
-		elapsed := time.Since(waitStartTime)
-
-		if elapsed >= timeout {
-			fmt.Printf("\n⏱️  Measurement has been running for 5 minutes.\n")
-			fmt.Printf("   Measurement URL: https://atlas.ripe.net/measurements/%d\n", measurementID)
-			fmt.Printf("   Please check the URL manually.\n\n")
-			fmt.Printf("❓ Wait for another 5 minutes? (y/n): ")
-
-			reader := bufio.NewReader(os.Stdin)
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response != "y" && response != "yes" {
-				return fmt.Errorf("measurement still running, check URL manually")
-			}
-
-			// Reset timeout
-			waitStartTime = time.Now()
-		}
-
-		err := client.WaitForMeasurement(measurementID, len(probeIDs), 3*time.Second)
-		if err == nil {
-			break
-		}
-
-		// Check if it's a timeout error (expected during polling)
-		if !strings.Contains(err.Error(), "timeout") {
-			return fmt.Errorf("error waiting for measurement: %w", err)
-		}
-
-		time.Sleep(100 * time.Millisecond) // Small delay before checking user timeout
+	if created, err := run.create(definition); !created {
+		return err
 	}
 
-	fmt.Printf("   ✅ Measurement completed!\n\n")
-
 	// Fetch results
-	fmt.Printf("📥 Fetching measurement results...\n")
-	results, err := client.GetMeasurementResults(measurementID)
+	raw, err := runResults(run, "traceroute", run.client.GetMeasurementResultsRaw)
 	if err != nil {
-		return fmt.Errorf("failed to fetch results: %w", err)
+		return err
 	}
-
-	fmt.Printf("   Retrieved %d traceroute results\n\n", len(results))
-
-	// Analyze common ASNs
-	fmt.Printf("🔬 Analyzing common ASN paths...\n\n")
-	commonASNs, err := analyzer.AnalyzeCommonASNs(results, thresholdFlag)
+	results, err := atlas.DecodeResults[atlas.TracerouteResult](raw)
 	if err != nil {
-		return fmt.Errorf("failed to analyze results: %w", err)
+		return err
 	}
 
-	// Calculate path statistics
-	uniquePaths, avgHops, maxHops, incompletePaths := analyzer.CalculatePathStats(results)
+	if saveFlag != "" {
+		if err := atlas.WriteResultsFile(saveFlag, raw); err != nil {
+			return err
+		}
+		fmt.Printf("💾 Saved results to %s\n\n", saveFlag)
+	}
 
 	// Generate report
-	report := atlas.Report{
-		MeasurementID:     measurementID,
-		Target:            targetFlag,
-		CreatedAt:         startTime,
-		Duration:          time.Since(startTime),
-		RequestedASNs:     asns,
-		ASNsWithProbes:    asnsWithProbes,
-		ASNsWithoutProbes: asnsWithoutProbes,
-		Allocations:       allocations,
-		CommonASNs:        commonASNs,
-		Threshold:         thresholdFlag,
-		TotalProbes:       len(probeIDs),
-		UniquePaths:       uniquePaths,
-		AvgHops:           avgHops,
-		MaxHops:           maxHops,
-		IncompletePaths:   incompletePaths,
+	report := run.baseReport(targetFlag, len(results))
+	report.AF = af
+
+	if err := analyzeTraceroutes(run.ctx, &report, results, thresholdFlag); err != nil {
+		return err
 	}
 
 	// Display report
@@ -234,6 +135,60 @@ func runTraceroute(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// tracerouteOptions holds the traceroute definition knobs shared by the
+// commands that create traceroutes
+type tracerouteOptions struct {
+	protocol        string
+	port            int
+	packets         int
+	size            int
+	firstHop        int
+	maxHops         int
+	paris           int
+	dontFragment    bool
+	responseTimeout time.Duration
+	spread          time.Duration
+}
+
+// addFlags registers the traceroute knobs on cmd, defaulting to the values
+// this tool has always used
+func (o *tracerouteOptions) addFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.StringVar(&o.protocol, "protocol", "ICMP", "Traceroute protocol: ICMP, UDP or TCP")
+	flags.IntVar(&o.port, "port", 0, "Destination port for UDP and TCP traceroutes (Atlas default for TCP: 80)")
+	flags.IntVar(&o.packets, "packets", 3, fmt.Sprintf("Packets sent per hop (1-%d)", atlas.MaxPackets))
+	flags.IntVar(&o.size, "size", 48, fmt.Sprintf("Packet size in bytes (0-%d)", atlas.MaxPacketSize))
+	flags.IntVar(&o.firstHop, "first-hop", 1, fmt.Sprintf("TTL of the first hop (1-%d)", atlas.MaxHopLimit))
+	flags.IntVar(&o.maxHops, "max-hops", 40, fmt.Sprintf("Maximum TTL (1-%d)", atlas.MaxHopLimit))
+	flags.IntVar(&o.paris, "paris", 16, fmt.Sprintf("Paris traceroute variations (0 disables, max %d)", atlas.MaxParis))
+	flags.BoolVar(&o.dontFragment, "dont-fragment", false, "Set the don't fragment bit")
+	flags.DurationVar(&o.responseTimeout, "response-timeout", 4*time.Second, "How long to wait for a reply to each packet")
+	flags.DurationVar(&o.spread, "spread", 0, "Spread probe start times over this period (default: Atlas default)")
+}
+
+// definition builds and validates a traceroute definition from the knobs
+func (o *tracerouteOptions) definition(af int, target, description string) (atlas.MeasurementDefinition, error) {
+	paris, size := o.paris, o.size
+	def := atlas.MeasurementDefinition{
+		Type:            "traceroute",
+		AF:              af,
+		Target:          target,
+		Description:     description,
+		Protocol:        strings.ToUpper(o.protocol),
+		Port:            o.port,
+		Packets:         o.packets,
+		Size:            &size,
+		FirstHop:        o.firstHop,
+		MaxHops:         o.maxHops,
+		Paris:           &paris,
+		DontFragment:    o.dontFragment,
+		ResponseTimeout: int(o.responseTimeout.Milliseconds()),
+		Spread:          int(o.spread.Seconds()),
+	}
+
+	return def, def.Validate()
+}
+
 // parseASNs parses a comma-separated list of ASNs
 func parseASNs(s string) ([]int, error) {
 	parts := strings.Split(s, ",")
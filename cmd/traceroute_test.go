@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func TestTracerouteEndToEnd(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	out, err := runCLI(t, srv, "traceroute",
+		"--asns", fmt.Sprintf("%d,%d", atlastest.DemoEyeballA, atlastest.DemoEyeballB),
+		"--target", "8.8.8.8",
+		"--page-size", "2",
+		"--seed", "1",
+	)
+	if err != nil {
+		t.Fatalf("traceroute: %v\n%s", err, out)
+	}
+
+	for _, want := range []string{
+		"Measurement created: ID 1000001",
+		"Retrieved 7 traceroute results",
+		fmt.Sprintf("AS%d", atlastest.DemoTransitA),
+		"TRANSIT-A Example Backbone",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	status, ok := srv.Measurement(1000001)
+	if !ok {
+		t.Fatal("measurement 1000001 was not created")
+	}
+	if status.Status.ID != atlas.MeasurementStopped || status.ProbesRequested != 7 {
+		t.Errorf("measurement = %+v, want stopped with 7 probes", status)
+	}
+}
+
+func TestTracerouteDryRun(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	out, err := runCLI(t, srv, "traceroute",
+		"--asns", fmt.Sprint(atlastest.DemoEyeballA),
+		"--target", "8.8.8.8",
+		"--dry-run",
+	)
+	if err != nil {
+		t.Fatalf("traceroute --dry-run: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "Dry run, nothing was created") {
+		t.Errorf("output lacks the dry run request:\n%s", out)
+	}
+	if _, ok := srv.Measurement(1000001); ok {
+		t.Error("a dry run created a measurement")
+	}
+}
+
+func TestTracerouteBatchDryRun(t *testing.T) {
+	srv := newFakeAtlas(t)
+	targetsFile := filepath.Join(t.TempDir(), "targets.txt")
+	if err := os.WriteFile(targetsFile, []byte("8.8.8.8\n1.1.1.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, srv, "traceroute",
+		"--asns", fmt.Sprint(atlastest.DemoEyeballA),
+		"--targets-file", targetsFile,
+		"--dry-run",
+	)
+	if err != nil {
+		t.Fatalf("traceroute --targets-file --dry-run: %v\n%s", err, out)
+	}
+
+	// Each printed request is one that would be POSTed: a single target
+	for _, want := range []string{"Request 1 of 2 (8.8.8.8)", "Request 2 of 2 (1.1.1.1)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, `"definitions"`); n != 2 {
+		t.Errorf("printed %d requests, want 2:\n%s", n, out)
+	}
+	if n := strings.Count(out, `"target"`); n != 2 {
+		t.Errorf("printed %d definitions, want one per request:\n%s", n, out)
+	}
+	if _, ok := srv.Measurement(1000001); ok {
+		t.Error("a dry run created a measurement")
+	}
+}
+
+func TestTracerouteShardsOverConcurrencyQuota(t *testing.T) {
+	tests := []struct {
+		dryRun bool
+		want   string
+	}{
+		{dryRun: false, want: "2 measurements needed, 99 of 100 already running"},
+		{dryRun: true, want: "⚠️  2 measurements needed, 99 of 100 already running"},
+	}
+
+	for _, tt := range tests {
+		srv := newFakeAtlas(t)
+		srv.SeedBulk(1500)
+		srv.PollsPerStage = 1000 // keep the measurements below running
+
+		client := atlas.NewClient("", atlas.WithBaseURL(srv.URL()))
+		for i := range atlas.MaxConcurrentMeasurements - 1 {
+			_, err := client.CreateMeasurement(context.Background(), atlas.MeasurementRequest{
+				Definitions: []atlas.MeasurementDefinition{{Type: "ping", AF: 4, Target: fmt.Sprintf("192.0.2.%d", i+1)}},
+				Probes:      []atlas.ProbeSet{{Type: "probes", Value: "1001", Requested: 1}},
+				IsOneoff:    true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		args := []string{"traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8", "--budget", "1500"}
+		if tt.dryRun {
+			args = append(args, "--dry-run")
+		}
+		out, err := runCLI(t, srv, args...)
+
+		if tt.dryRun {
+			if err != nil || !strings.Contains(out, tt.want) {
+				t.Errorf("dry run = %v, want the warning %q:\n%s", err, tt.want, out)
+			}
+			continue
+		}
+		if !errors.Is(err, atlas.ErrQuotaExceeded) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("traceroute = %v, want %v with %q", err, atlas.ErrQuotaExceeded, tt.want)
+		}
+		if _, ok := srv.Measurement(1000000 + atlas.MaxConcurrentMeasurements); ok {
+			t.Error("a shard was created over the quota")
+		}
+	}
+}
+
+func TestTracerouteRejectedKey(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.APIKey = "other"
+
+	_, err := runCLI(t, srv, "traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8")
+	if ExitCode(err) != ExitAuth {
+		t.Errorf("traceroute with a rejected key = %v, want exit code %d", err, ExitAuth)
+	}
+}
+
+func TestTracerouteOverBalanceWarns(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.Credits = 1
+
+	out, err := runCLI(t, srv, "traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8")
+	if err != nil {
+		t.Fatalf("traceroute: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "estimate exceeds the balance of 1 credits") {
+		t.Errorf("output lacks the balance warning:\n%s", out)
+	}
+	if _, ok := srv.Measurement(1000001); !ok {
+		t.Error("measurement was not created")
+	}
+}
+
+func TestTracerouteShardCreateFailureStopsFirstShard(t *testing.T) {
+	srv := newFakeAtlas(t)
+	srv.SeedBulk(1500)
+	var creates atomic.Int32
+	srv.CreateHook = func(req atlas.MeasurementRequest) error {
+		if creates.Add(1) == 2 {
+			return errors.New("simulated failure of the second shard")
+		}
+		return nil
+	}
+
+	out, err := runCLI(t, srv, "traceroute",
+		"--asns", fmt.Sprint(atlastest.DemoEyeballA),
+		"--target", "8.8.8.8",
+		"--budget", "1500",
+	)
+	if err == nil || !strings.Contains(err.Error(), "simulated failure") {
+		t.Fatalf("traceroute = %v, want the second create to fail\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "Splitting 1500 probes across 2 measurements") {
+		t.Errorf("output lacks the split:\n%s", out)
+	}
+	status, ok := srv.Measurement(1000001)
+	if !ok {
+		t.Fatal("the first shard was not created")
+	}
+	if status.Status.ID != atlas.MeasurementForcedStop || status.ProbesRequested != 1000 {
+		t.Errorf("first shard = %+v, want 1000 probes forced to stop", status)
+	}
+	if _, ok := srv.Measurement(1000002); ok {
+		t.Error("the second shard was created")
+	}
+}
+
+func TestTracerouteShardWaitFailureStopsOtherShards(t *testing.T) {
+	srv := newFakeAtlas(t)
+
+	// The first 1000 recorded probes are gone, so the first shard finds no
+	// suitable probes while the second one runs
+	manifest := &atlas.SelectionManifest{}
+	for id := 1; id <= 1000; id++ {
+		manifest.Probes = append(manifest.Probes, id)
+	}
+	manifest.Probes = append(manifest.Probes, 1001)
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := atlas.WriteManifestFile(path, manifest); err != nil {
+		t.Fatal(err)
+	}
+	srv.PollsPerStage = 1000 // keep the second shard scheduled until it is stopped
+
+	out, err := runCLI(t, srv, "traceroute", "--probes-from", path, "--target", "8.8.8.8")
+	if !errors.Is(err, atlas.ErrNoSuitableProbes) {
+		t.Fatalf("traceroute = %v, want ErrNoSuitableProbes\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "Splitting 1001 probes across 2 measurements") {
+		t.Errorf("output lacks the split:\n%s", out)
+	}
+	failed, ok := srv.Measurement(1000001)
+	if !ok || failed.Status.ID != atlas.MeasurementNoSuitableProbes {
+		t.Errorf("first shard = %+v, want no suitable probes", failed)
+	}
+	other, ok := srv.Measurement(1000002)
+	if !ok || other.Status.ID != atlas.MeasurementForcedStop {
+		t.Errorf("second shard = %+v, want it forced to stop", other)
+	}
+	if !strings.Contains(out, "Measurement 1000001 failed, stopping measurement 1000002") {
+		t.Errorf("output lacks the stop:\n%s", out)
+	}
+}
+
+func TestTracerouteDefinitionFlags(t *testing.T) {
+	for _, flag := range []string{"--packets", "--first-hop", "--max-hops", "--response-timeout"} {
+		srv := newFakeAtlas(t)
+
+		value := "0"
+		if flag == "--response-timeout" {
+			value = "0s"
+		}
+		_, err := runCLI(t, srv, "traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8", flag, value)
+		if err == nil || !strings.Contains(err.Error(), "must be between 1 and") {
+			t.Errorf("traceroute %s %s = %v, want a range error", flag, value, err)
+		}
+		if _, ok := srv.Measurement(1000001); ok {
+			t.Errorf("traceroute %s %s created a measurement", flag, value)
+		}
+	}
+}
+
+func TestTracerouteSendsZeroSize(t *testing.T) {
+	srv := newFakeAtlas(t)
+	var size *int
+	srv.CreateHook = func(req atlas.MeasurementRequest) error {
+		size = req.Definitions[0].Size
+		return nil
+	}
+
+	out, err := runCLI(t, srv, "traceroute", "--asns", fmt.Sprint(atlastest.DemoEyeballA), "--target", "8.8.8.8", "--size", "0")
+	if err != nil {
+		t.Fatalf("traceroute --size 0: %v\n%s", err, out)
+	}
+	if size == nil || *size != 0 {
+		t.Errorf("size sent = %v, want 0", size)
+	}
+}
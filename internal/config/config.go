@@ -1,98 +1,176 @@
 package config
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
+// Environment variables holding the API keys
+const (
+	EnvAPIKey     = "RIPE_ATLAS_API"
+	EnvReadAPIKey = "RIPE_ATLAS_READ_API"
+)
+
+// Setting names, used as keys of Config.Sources
+const (
+	SettingAPIKey     = "api_key"
+	SettingReadAPIKey = "read_api_key"
+)
+
+// ErrAPIKeyNotFound is returned by LoadWithPriority when no source provides an API key
+var ErrAPIKeyNotFound = errors.New("RIPE Atlas API key not found. Please pass --api-key, set the RIPE_ATLAS_API environment variable, or add api_key to ~/.config/ripeatlas/config.toml or ~/.env.key")
+
 // Config holds the application configuration
 type Config struct {
+	// APIKey creates and stops measurements, and reads when ReadAPIKey is empty
 	APIKey string
+	// ReadAPIKey, if set, is used for requests that only read data
+	ReadAPIKey string
+	Profiles   map[string]Profile
+
+	// Sources records where each effective value came from, keyed by
+	// SettingAPIKey, SettingReadAPIKey or "profile.<name>"
+	Sources map[string]string
+	// Files lists every config file consulted, lowest priority first
+	Files []File
+}
+
+// File is a config file LoadWithPriority consulted
+type File struct {
+	Path   string
+	Origin string // "--config" or "default"
+	Found  bool
 }
 
-// LoadWithPriority loads API key with the following priority:
-// 1. Environment variable RIPE_ATLAS_API
-// 2. ~/.env.key file
-// 3. Custom config file (if configPath is provided)
-func LoadWithPriority(configPath string) (*Config, error) {
-	cfg := &Config{}
-
-	// Priority 1: Check environment variable RIPE_ATLAS_API
-	if apiKey := os.Getenv("RIPE_ATLAS_API"); apiKey != "" {
-		cfg.APIKey = apiKey
-		return cfg, nil
+// Profile is a named set of flag defaults, keyed by flag name (e.g. "asns",
+// "protocol", "threshold"). Values are in the form the flag parses.
+type Profile map[string]string
+
+// Options are the command-line inputs to LoadWithPriority
+type Options struct {
+	ConfigPath string // --config
+	APIKey     string // --api-key
+	ReadAPIKey string // --read-api-key
+}
+
+// LoadWithPriority resolves the configuration from, highest priority first:
+// 1. Command-line flags (--api-key, --read-api-key)
+// 2. The config file given with --config
+// 3. Environment variables RIPE_ATLAS_API and RIPE_ATLAS_READ_API
+// 4. $XDG_CONFIG_HOME/ripeatlas/config.toml, or ~/.config/ripeatlas/config.toml
+// 5. The legacy ~/.env.key
+//
+// Profiles are merged from the files in the same order. When no API key is
+// found the returned config still holds the profiles, along with
+// ErrAPIKeyNotFound.
+func LoadWithPriority(opts Options) (*Config, error) {
+	cfg := &Config{
+		Profiles: make(map[string]Profile),
+		Sources:  make(map[string]string),
 	}
 
-	// Priority 2: Check ~/.env.key
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		homeConfigPath := filepath.Join(homeDir, ".env.key")
-		if _, err := os.Stat(homeConfigPath); err == nil {
-			cfg, err = loadFromFile(homeConfigPath)
-			if err == nil && cfg.APIKey != "" {
-				return cfg, nil
-			}
+	// Apply the layers lowest priority first, so later ones overwrite
+	homeDir, homeErr := os.UserHomeDir()
+
+	// ~/.env.key is optional and a broken one is skipped, as before
+	if homeErr == nil {
+		path := filepath.Join(homeDir, ".env.key")
+		fileCfg, err := loadFromFile(path)
+		cfg.Files = append(cfg.Files, File{Path: path, Origin: "default", Found: err == nil})
+		if err == nil {
+			cfg.merge(fileCfg, path)
 		}
 	}
 
-	// Priority 3: Check custom config file (if provided)
-	if configPath != "" {
-		cfg, err = loadFromFile(configPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load config from %s: %w", configPath, err)
+	if path := xdgConfigPath(homeDir); path != "" {
+		fileCfg, err := loadFromFile(path)
+		switch {
+		case err == nil:
+			cfg.Files = append(cfg.Files, File{Path: path, Origin: "default", Found: true})
+			cfg.merge(fileCfg, path)
+		case errors.Is(err, os.ErrNotExist):
+			cfg.Files = append(cfg.Files, File{Path: path, Origin: "default"})
+		default:
+			return nil, fmt.Errorf("failed to load config from %s: %w", path, err)
 		}
-		if cfg.APIKey != "" {
-			return cfg, nil
+	}
+
+	cfg.set(SettingAPIKey, os.Getenv(EnvAPIKey), "env "+EnvAPIKey)
+	cfg.set(SettingReadAPIKey, os.Getenv(EnvReadAPIKey), "env "+EnvReadAPIKey)
+
+	if opts.ConfigPath != "" {
+		fileCfg, err := loadFromFile(opts.ConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", opts.ConfigPath, err)
 		}
+		cfg.Files = append(cfg.Files, File{Path: opts.ConfigPath, Origin: "--config", Found: true})
+		cfg.merge(fileCfg, opts.ConfigPath)
 	}
 
-	return nil, fmt.Errorf("RIPE Atlas API key not found. Please set RIPE_ATLAS_API environment variable or create ~/.env.key file")
-}
+	cfg.set(SettingAPIKey, opts.APIKey, "flag --api-key")
+	cfg.set(SettingReadAPIKey, opts.ReadAPIKey, "flag --read-api-key")
 
-// Load reads configuration from env.key file (kept for backward compatibility)
-func Load(filePath string) (*Config, error) {
-	return loadFromFile(filePath)
+	if cfg.APIKey == "" {
+		return cfg, ErrAPIKeyNotFound
+	}
+
+	return cfg, nil
 }
 
-// loadFromFile reads configuration from a file
-func loadFromFile(filePath string) (*Config, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
+// xdgConfigPath returns $XDG_CONFIG_HOME/ripeatlas/config.toml, falling back
+// to ~/.config as the XDG base directory spec prescribes
+func xdgConfigPath(homeDir string) string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		if homeDir == "" {
+			return ""
+		}
+		dir = filepath.Join(homeDir, ".config")
 	}
-	defer file.Close()
-
-	cfg := &Config{}
-	scanner := bufio.NewScanner(file)
+	return filepath.Join(dir, "ripeatlas", "config.toml")
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// merge copies the keys and profiles defined in a file over cfg
+func (cfg *Config) merge(file *Config, path string) {
+	cfg.set(SettingAPIKey, file.APIKey, path)
+	cfg.set(SettingReadAPIKey, file.ReadAPIKey, path)
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	for name, profile := range file.Profiles {
+		cfg.Profiles[name] = profile
+		cfg.Sources["profile."+name] = path
+	}
+}
 
-		// Parse KEY=VALUE format
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
+// set stores a non-empty key along with its source
+func (cfg *Config) set(setting, value, source string) {
+	if value == "" {
+		return
+	}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.Trim(strings.TrimSpace(parts[1]), "\"")
+	switch setting {
+	case SettingAPIKey:
+		cfg.APIKey = value
+	case SettingReadAPIKey:
+		cfg.ReadAPIKey = value
+	}
+	cfg.Sources[setting] = source
+}
 
-		// Support both RIPE_ATLAS_API and RIPE_ATLAS_KEY
-		if key == "RIPE_ATLAS_API" || key == "RIPE_ATLAS_KEY" {
-			cfg.APIKey = value
-		}
+// Redact hides all but the last four characters of a key
+func Redact(key string) string {
+	if len(key) <= 8 {
+		return "********"
 	}
+	return "********" + key[len(key)-4:]
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+// Load reads configuration from env.key file (kept for backward compatibility)
+func Load(filePath string) (*Config, error) {
+	cfg, err := loadFromFile(filePath)
+	if err != nil {
+		return nil, err
 	}
 
 	if cfg.APIKey == "" {
@@ -101,3 +179,14 @@ func loadFromFile(filePath string) (*Config, error) {
 
 	return cfg, nil
 }
+
+// loadFromFile reads configuration from a file, see parse for the format
+func loadFromFile(filePath string) (*Config, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	return parse(file)
+}
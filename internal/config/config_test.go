@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// configEnv is a temporary HOME and XDG_CONFIG_HOME with no keys in the environment
+type configEnv struct {
+	home, xdg string
+}
+
+func newConfigEnv(t *testing.T) configEnv {
+	t.Helper()
+
+	env := configEnv{home: t.TempDir(), xdg: t.TempDir()}
+	t.Setenv("HOME", env.home)
+	t.Setenv("XDG_CONFIG_HOME", env.xdg)
+	t.Setenv(EnvAPIKey, "")
+	t.Setenv(EnvReadAPIKey, "")
+	return env
+}
+
+// envKeyPath returns the path of ~/.env.key
+func (e configEnv) envKeyPath() string {
+	return filepath.Join(e.home, ".env.key")
+}
+
+// xdgPath returns the path of $XDG_CONFIG_HOME/ripeatlas/config.toml
+func (e configEnv) xdgPath() string {
+	return filepath.Join(e.xdg, "ripeatlas", "config.toml")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadWithPriority(t *testing.T) {
+	// Every layer sets the API key to its own name, and the read key too
+	// unless noted
+	type layers struct {
+		envKey, xdg, env, config, flag bool
+	}
+
+	tests := []struct {
+		name       string
+		layers     layers
+		wantKey    string
+		wantSource string // "envkey", "xdg", "config" or a literal source
+	}{
+		{"only ~/.env.key", layers{envKey: true}, "envkey", "envkey"},
+		{"xdg over ~/.env.key", layers{envKey: true, xdg: true}, "xdg", "xdg"},
+		{"env over xdg", layers{envKey: true, xdg: true, env: true}, "env", "env " + EnvAPIKey},
+		{"--config over env", layers{envKey: true, xdg: true, env: true, config: true}, "config", "config"},
+		{"flag over --config", layers{envKey: true, xdg: true, env: true, config: true, flag: true}, "flag", "flag --api-key"},
+		{"flag alone", layers{flag: true}, "flag", "flag --api-key"},
+		{"--config over ~/.env.key", layers{envKey: true, config: true}, "config", "config"},
+		{"env over ~/.env.key", layers{envKey: true, env: true}, "env", "env " + EnvAPIKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newConfigEnv(t)
+			configPath := filepath.Join(t.TempDir(), "custom.toml")
+
+			var opts Options
+			if tt.layers.envKey {
+				writeFile(t, env.envKeyPath(), "RIPE_ATLAS_API=envkey\nRIPE_ATLAS_READ_API=envkey\n")
+			}
+			if tt.layers.xdg {
+				writeFile(t, env.xdgPath(), "api_key = \"xdg\"\nread_api_key = \"xdg\"\n")
+			}
+			if tt.layers.env {
+				t.Setenv(EnvAPIKey, "env")
+				t.Setenv(EnvReadAPIKey, "env")
+			}
+			if tt.layers.config {
+				writeFile(t, configPath, "api_key = \"config\"\nread_api_key = \"config\"\n")
+				opts.ConfigPath = configPath
+			}
+			if tt.layers.flag {
+				opts.APIKey = "flag"
+				opts.ReadAPIKey = "flag"
+			}
+
+			cfg, err := LoadWithPriority(opts)
+			if err != nil {
+				t.Fatalf("LoadWithPriority: %v", err)
+			}
+
+			wantSource := map[string]string{
+				"envkey": env.envKeyPath(),
+				"xdg":    env.xdgPath(),
+				"config": configPath,
+			}[tt.wantSource]
+			if wantSource == "" {
+				wantSource = tt.wantSource
+			}
+
+			if cfg.APIKey != tt.wantKey || cfg.Sources[SettingAPIKey] != wantSource {
+				t.Errorf("api key = %q from %q, want %q from %q", cfg.APIKey, cfg.Sources[SettingAPIKey], tt.wantKey, wantSource)
+			}
+
+			// The read key follows the same order, with its own flag and variable
+			readSource := wantSource
+			switch readSource {
+			case "flag --api-key":
+				readSource = "flag --read-api-key"
+			case "env " + EnvAPIKey:
+				readSource = "env " + EnvReadAPIKey
+			}
+			if cfg.ReadAPIKey != tt.wantKey || cfg.Sources[SettingReadAPIKey] != readSource {
+				t.Errorf("read key = %q from %q, want %q from %q", cfg.ReadAPIKey, cfg.Sources[SettingReadAPIKey], tt.wantKey, readSource)
+			}
+		})
+	}
+}
+
+func TestLoadWithPriorityMixesLayers(t *testing.T) {
+	env := newConfigEnv(t)
+	writeFile(t, env.envKeyPath(), "RIPE_ATLAS_API=envkey\nRIPE_ATLAS_READ_API=envkey-read\n")
+	writeFile(t, env.xdgPath(), "api_key = \"xdg\"\n")
+
+	cfg, err := LoadWithPriority(Options{})
+	if err != nil {
+		t.Fatalf("LoadWithPriority: %v", err)
+	}
+
+	// A layer only overrides the settings it defines
+	if cfg.APIKey != "xdg" || cfg.Sources[SettingAPIKey] != env.xdgPath() {
+		t.Errorf("api key = %q from %q, want xdg", cfg.APIKey, cfg.Sources[SettingAPIKey])
+	}
+	if cfg.ReadAPIKey != "envkey-read" || cfg.Sources[SettingReadAPIKey] != env.envKeyPath() {
+		t.Errorf("read key = %q from %q, want it from ~/.env.key", cfg.ReadAPIKey, cfg.Sources[SettingReadAPIKey])
+	}
+}
+
+func TestLoadWithPriorityProfiles(t *testing.T) {
+	env := newConfigEnv(t)
+	configPath := filepath.Join(t.TempDir(), "custom.toml")
+	writeFile(t, env.envKeyPath(), "RIPE_ATLAS_API=envkey\n[profile.shared]\nport = 1\n[profile.legacy]\nport = 1\n")
+	writeFile(t, env.xdgPath(), "[profile.shared]\nport = 2\n[profile.xdg]\nport = 2\n")
+	writeFile(t, configPath, "[profile.shared]\nport = 3\n")
+
+	cfg, err := LoadWithPriority(Options{ConfigPath: configPath})
+	if err != nil {
+		t.Fatalf("LoadWithPriority: %v", err)
+	}
+
+	tests := []struct {
+		profile, port, source string
+	}{
+		{"shared", "3", configPath},
+		{"xdg", "2", env.xdgPath()},
+		{"legacy", "1", env.envKeyPath()},
+	}
+	for _, tt := range tests {
+		if got := cfg.Profiles[tt.profile]["port"]; got != tt.port {
+			t.Errorf("profile %s port = %q, want %q", tt.profile, got, tt.port)
+		}
+		if got := cfg.Sources["profile."+tt.profile]; got != tt.source {
+			t.Errorf("profile %s source = %q, want %q", tt.profile, got, tt.source)
+		}
+	}
+
+	// Files are listed lowest priority first
+	want := []File{
+		{Path: env.envKeyPath(), Origin: "default", Found: true},
+		{Path: env.xdgPath(), Origin: "default", Found: true},
+		{Path: configPath, Origin: "--config", Found: true},
+	}
+	if len(cfg.Files) != len(want) {
+		t.Fatalf("files = %+v, want %+v", cfg.Files, want)
+	}
+	for i := range want {
+		if cfg.Files[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, cfg.Files[i], want[i])
+		}
+	}
+}
+
+func TestLoadWithPriorityXDGFallback(t *testing.T) {
+	env := newConfigEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	path := filepath.Join(env.home, ".config", "ripeatlas", "config.toml")
+	writeFile(t, path, "api_key = \"home-config\"\n")
+
+	cfg, err := LoadWithPriority(Options{})
+	if err != nil {
+		t.Fatalf("LoadWithPriority: %v", err)
+	}
+	if cfg.APIKey != "home-config" || cfg.Sources[SettingAPIKey] != path {
+		t.Errorf("api key = %q from %q, want it from %s", cfg.APIKey, cfg.Sources[SettingAPIKey], path)
+	}
+}
+
+func TestLoadWithPriorityNoKey(t *testing.T) {
+	env := newConfigEnv(t)
+	writeFile(t, env.xdgPath(), "[profile.p]\nport = 1\n")
+
+	cfg, err := LoadWithPriority(Options{})
+	if !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("LoadWithPriority = %v, want ErrAPIKeyNotFound", err)
+	}
+	if cfg == nil || cfg.Profiles["p"]["port"] != "1" {
+		t.Errorf("config = %+v, want the profiles despite the missing key", cfg)
+	}
+
+	// Missing default files are listed but not found
+	for _, file := range cfg.Files {
+		if file.Path == env.envKeyPath() && file.Found {
+			t.Errorf("~/.env.key reported as found")
+		}
+	}
+}
+
+func TestLoadWithPriorityBrokenFiles(t *testing.T) {
+	t.Run("broken ~/.env.key is skipped", func(t *testing.T) {
+		env := newConfigEnv(t)
+		writeFile(t, env.envKeyPath(), "not a key value line\n")
+		t.Setenv(EnvAPIKey, "env")
+
+		cfg, err := LoadWithPriority(Options{})
+		if err != nil {
+			t.Fatalf("LoadWithPriority: %v", err)
+		}
+		if cfg.APIKey != "env" {
+			t.Errorf("api key = %q, want env", cfg.APIKey)
+		}
+	})
+
+	t.Run("broken xdg file fails", func(t *testing.T) {
+		env := newConfigEnv(t)
+		writeFile(t, env.xdgPath(), "[unknown]\n")
+		t.Setenv(EnvAPIKey, "env")
+
+		if _, err := LoadWithPriority(Options{}); err == nil {
+			t.Error("LoadWithPriority succeeded with a broken config.toml")
+		}
+	})
+
+	t.Run("missing --config file fails", func(t *testing.T) {
+		newConfigEnv(t)
+
+		_, err := LoadWithPriority(Options{ConfigPath: filepath.Join(t.TempDir(), "missing.toml"), APIKey: "flag"})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("LoadWithPriority = %v, want a not exist error", err)
+		}
+	})
+}
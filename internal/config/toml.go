@@ -0,0 +1,199 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// profileSection is the table prefix of named profiles, e.g. [profile.edge-tcp]
+const profileSection = "profile."
+
+// parse reads a config file in the small TOML subset this tool understands:
+//
+//	# top-level keys, legacy KEY=VALUE lines included
+//	RIPE_ATLAS_API=abc123
+//	api_key = "abc123"
+//	read_api_key = "def456"
+//
+//	[profile.edge-tcp]
+//	asns = [5384, 7713]
+//	protocol = "TCP"
+//	port = 443
+//
+// Values are strings, numbers, booleans or single-line arrays of those.
+// Arrays are flattened to comma-separated strings, which is what list flags
+// expect. As in TOML, a key may only be defined once per table.
+func parse(r io.Reader) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]Profile)}
+
+	var (
+		profile Profile                 // nil while in the top-level table
+		seen    = make(map[string]bool) // keys of the current table
+		lineNo  int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+
+		// Skip empty lines and comments
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed table header %q", lineNo, line)
+			}
+			table := strings.TrimSpace(line[1 : len(line)-1])
+			if !strings.HasPrefix(table, profileSection) {
+				return nil, fmt.Errorf("line %d: unknown table [%s], expected [profile.<name>]", lineNo, table)
+			}
+
+			name := strings.Trim(strings.TrimPrefix(table, profileSection), `"`)
+			if name == "" {
+				return nil, fmt.Errorf("line %d: profile name is empty", lineNo)
+			}
+			if _, exists := cfg.Profiles[name]; exists {
+				return nil, fmt.Errorf("line %d: profile %q defined twice", lineNo, name)
+			}
+			profile = make(Profile)
+			cfg.Profiles[name] = profile
+			clear(seen)
+			continue
+		}
+
+		// Parse KEY=VALUE format
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: key is empty", lineNo)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: %s defined twice", lineNo, key)
+		}
+		seen[key] = true
+
+		value, err := parseValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+
+		if profile != nil {
+			profile[key] = value
+			continue
+		}
+
+		// Support RIPE_ATLAS_API, RIPE_ATLAS_KEY and api_key, and their read-only counterparts
+		switch key {
+		case "RIPE_ATLAS_API", "RIPE_ATLAS_KEY", SettingAPIKey:
+			cfg.APIKey = value
+		case "RIPE_ATLAS_READ_API", "RIPE_ATLAS_READ_KEY", SettingReadAPIKey:
+			cfg.ReadAPIKey = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// parseValue decodes a quoted string, an array, or a bare value (numbers,
+// booleans and the unquoted values of legacy KEY=VALUE lines)
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") || strings.Contains(raw[1:len(raw)-1], "'") {
+			return "", fmt.Errorf("malformed string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return "", fmt.Errorf("arrays must be on a single line")
+		}
+		var items []string
+		for _, item := range splitArray(raw[1 : len(raw)-1]) {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			value, err := parseValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return strings.Join(items, ","), nil
+	}
+
+	return raw, nil
+}
+
+// splitArray splits array contents on commas outside quoted strings
+func splitArray(s string) []string {
+	var (
+		items []string
+		start int
+	)
+	forEachUnquoted(s, func(i int, r rune) bool {
+		if r == ',' {
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+		return true
+	})
+	return append(items, s[start:])
+}
+
+// stripComment removes a # comment that is not inside a quoted string
+func stripComment(line string) string {
+	end := len(line)
+	forEachUnquoted(line, func(i int, r rune) bool {
+		if r == '#' {
+			end = i
+			return false
+		}
+		return true
+	})
+	return line[:end]
+}
+
+// forEachUnquoted calls fn with the byte offset of every rune of s outside a
+// quoted string, until fn returns false. Basic strings ("...") honor
+// backslash escapes, literal strings ('...') have none.
+func forEachUnquoted(s string, fn func(i int, r rune) bool) {
+	var (
+		quote   rune
+		escaped bool
+	)
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		default:
+			if !fn(i, r) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		apiKey   string
+		readKey  string
+		profiles map[string]Profile
+	}{
+		{
+			name:   "legacy env.key",
+			input:  "RIPE_ATLAS_API=abc123\n",
+			apiKey: "abc123",
+		},
+		{
+			name:    "legacy key aliases",
+			input:   "RIPE_ATLAS_KEY = abc123\nRIPE_ATLAS_READ_KEY = def456\n",
+			apiKey:  "abc123",
+			readKey: "def456",
+		},
+		{
+			name:    "quoted keys",
+			input:   "api_key = \"abc123\"\nread_api_key = 'def456'\n",
+			apiKey:  "abc123",
+			readKey: "def456",
+		},
+		{
+			name:   "comments and blank lines",
+			input:  "# keys\n\n   # indented comment\napi_key = \"abc123\" # trailing comment\n",
+			apiKey: "abc123",
+		},
+		{
+			name:   "hash inside a basic string",
+			input:  `api_key = "abc#123" # comment`,
+			apiKey: "abc#123",
+		},
+		{
+			name:   "hash inside a literal string",
+			input:  `api_key = 'abc#123'`,
+			apiKey: "abc#123",
+		},
+		{
+			name:   "escaped quote before a hash",
+			input:  `api_key = "a\"#b"`,
+			apiKey: `a"#b`,
+		},
+		{
+			name:   "escaped backslash ends the string",
+			input:  `api_key = "a\\" # comment`,
+			apiKey: `a\`,
+		},
+		{
+			name:   "backslash in a literal string",
+			input:  `api_key = 'C:\keys' # comment`,
+			apiKey: `C:\keys`,
+		},
+		{
+			name:   "unknown top-level keys are ignored",
+			input:  "api_key = \"abc123\"\ncolor = true\n",
+			apiKey: "abc123",
+		},
+		{
+			name: "profiles",
+			input: `api_key = "abc123"
+
+[profile.edge-tcp]
+asns = [5384, 7713]
+protocol = "TCP"
+port = 443
+dont-fragment = true
+
+[ profile."quoted name" ] # comment
+target = "192.0.2.1"
+`,
+			apiKey: "abc123",
+			profiles: map[string]Profile{
+				"edge-tcp":    {"asns": "5384,7713", "protocol": "TCP", "port": "443", "dont-fragment": "true"},
+				"quoted name": {"target": "192.0.2.1"},
+			},
+		},
+		{
+			name:  "arrays of strings",
+			input: "[profile.p]\ntags = [\"a,b\", 'c', \"d#e\" ,] # comment\nempty = []\n",
+			profiles: map[string]Profile{
+				"p": {"tags": "a,b,c,d#e", "empty": ""},
+			},
+		},
+		{
+			name:  "keys in a profile do not set the API key",
+			input: "[profile.p]\napi_key = \"abc123\"\n",
+			profiles: map[string]Profile{
+				"p": {"api_key": "abc123"},
+			},
+		},
+		{
+			name:  "the same key in different tables",
+			input: "[profile.a]\nport = 1\n[profile.b]\nport = 2\n",
+			profiles: map[string]Profile{
+				"a": {"port": "1"},
+				"b": {"port": "2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			if cfg.APIKey != tt.apiKey || cfg.ReadAPIKey != tt.readKey {
+				t.Errorf("keys = %q, %q, want %q, %q", cfg.APIKey, cfg.ReadAPIKey, tt.apiKey, tt.readKey)
+			}
+
+			if len(cfg.Profiles) != len(tt.profiles) {
+				t.Fatalf("profiles = %v, want %v", cfg.Profiles, tt.profiles)
+			}
+			for name, want := range tt.profiles {
+				if got := cfg.Profiles[name]; !maps.Equal(got, want) {
+					t.Errorf("profile %q = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing equals", "api_key \"abc\"", "line 1: expected key = value"},
+		{"empty key", " = \"abc\"", "line 1: key is empty"},
+		{"unterminated basic string", `api_key = "abc`, "line 1: api_key"},
+		{"unterminated string before a hash", `api_key = "abc # comment`, "line 1: api_key"},
+		{"text after a string", `api_key = "abc" def`, "line 1: api_key"},
+		{"unterminated literal string", "api_key = 'abc", "malformed string"},
+		{"quote inside a literal string", "api_key = 'a'b'", "malformed string"},
+		{"multi-line array", "[profile.p]\nasns = [5384,\n7713]", "line 2: asns: arrays must be on a single line"},
+		{"bad array item", "[profile.p]\ntags = [\"a]", "line 2: tags"},
+		{"malformed table header", "[profile.p", "line 1: malformed table header"},
+		{"unknown table", "[server]", "line 1: unknown table [server]"},
+		{"empty profile name", "[profile.]", "line 1: profile name is empty"},
+		{"duplicate profile", "[profile.p]\n[profile.p]", `line 2: profile "p" defined twice`},
+		{"duplicate top-level key", "api_key = \"a\"\napi_key = \"b\"", "line 2: api_key defined twice"},
+		{"duplicate profile key", "[profile.p]\nport = 1\nport = 2", "line 3: port defined twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parse(%q) = %v, want an error containing %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
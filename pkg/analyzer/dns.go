@@ -0,0 +1,245 @@
+package analyzer
+
+import (
+	"cmp"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// bogonPrefixes are ranges a public name should never resolve to; answers in
+// them usually come from a captive portal, a filtering resolver or a hijack
+var bogonPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// dnsResponse is a single decoded response attributed to a probe and ASN
+type dnsResponse struct {
+	probeID  int
+	asn      int
+	resolver string
+	rt       float64
+	answer   string
+	addrs    []netip.Addr
+}
+
+// AnalyzeDNS groups DNS responses by the ASN returned by asnOf and flags
+// answers that contain bogon addresses, fall outside the expected prefixes,
+// or (when no expectation is given) differ from the most common answer.
+// Only records of queryType make up an answer set.
+func AnalyzeDNS(results []atlas.DNSResult, queryType string, asnOf func(atlas.DNSResult) int, expected []netip.Prefix) atlas.DNSAnalysis {
+	var (
+		analysis  atlas.DNSAnalysis
+		responses []dnsResponse
+		summaries = make(map[int]*atlas.DNSASNSummary)
+		probes    = make(map[int]map[int]bool)
+		rts       = make(map[int][]float64)
+	)
+
+	for _, result := range results {
+		asn := asnOf(result)
+		summary, ok := summaries[asn]
+		if !ok {
+			summary = &atlas.DNSASNSummary{ASN: asn}
+			summaries[asn] = summary
+			probes[asn] = make(map[int]bool)
+		}
+		probes[asn][result.ProbeID] = true
+
+		for _, set := range result.Responses() {
+			if set.Result == nil {
+				summary.Errors++
+				continue
+			}
+
+			msg, err := set.Result.Decode()
+			if err != nil {
+				summary.Errors++
+				continue
+			}
+
+			answer, addrs := answerSet(msg, queryType)
+			responses = append(responses, dnsResponse{
+				probeID:  result.ProbeID,
+				asn:      asn,
+				resolver: set.DstAddr,
+				rt:       set.Result.RT,
+				answer:   answer,
+				addrs:    addrs,
+			})
+			summary.Responses++
+			rts[asn] = append(rts[asn], set.Result.RT)
+		}
+	}
+
+	// Find the consensus answer
+	counts := make(map[string]int)
+	for _, r := range responses {
+		counts[r.answer]++
+	}
+	for answer, count := range counts {
+		if count > analysis.ConsensusCount || (count == analysis.ConsensusCount && answer < analysis.Consensus) {
+			analysis.Consensus, analysis.ConsensusCount = answer, count
+		}
+	}
+	analysis.Responses = len(responses)
+
+	// Flag answers and count them per ASN
+	answerCounts := make(map[int]map[string]*atlas.DNSAnswerCount)
+	for _, r := range responses {
+		reason := answerReason(r.answer, r.addrs, analysis.Consensus, expected)
+		if reason != "" {
+			analysis.Findings = append(analysis.Findings, atlas.DNSFinding{
+				ProbeID:  r.probeID,
+				ASN:      r.asn,
+				Resolver: r.resolver,
+				Answer:   r.answer,
+				Reason:   reason,
+			})
+		}
+
+		if answerCounts[r.asn] == nil {
+			answerCounts[r.asn] = make(map[string]*atlas.DNSAnswerCount)
+		}
+		if ac, ok := answerCounts[r.asn][r.answer]; ok {
+			ac.Count++
+		} else {
+			answerCounts[r.asn][r.answer] = &atlas.DNSAnswerCount{Answer: r.answer, Count: 1, Reason: reason}
+		}
+	}
+
+	for asn, summary := range summaries {
+		summary.Probes = len(probes[asn])
+
+		if samples := rts[asn]; len(samples) > 0 {
+			slices.Sort(samples)
+			summary.MedianRT = percentile(samples, 50)
+			summary.MaxRT = samples[len(samples)-1]
+		}
+
+		for _, ac := range answerCounts[asn] {
+			summary.Answers = append(summary.Answers, *ac)
+		}
+		slices.SortFunc(summary.Answers, func(a, b atlas.DNSAnswerCount) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Answer, b.Answer)
+		})
+
+		analysis.ByASN = append(analysis.ByASN, *summary)
+	}
+
+	slices.SortFunc(analysis.ByASN, func(a, b atlas.DNSASNSummary) int {
+		return cmp.Compare(a.ASN, b.ASN)
+	})
+	slices.SortFunc(analysis.Findings, func(a, b atlas.DNSFinding) int {
+		if c := cmp.Compare(a.ASN, b.ASN); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProbeID, b.ProbeID)
+	})
+
+	return analysis
+}
+
+// ParseExpectedAnswers parses a comma-separated list of addresses and prefixes
+func ParseExpectedAnswers(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid prefix: %s", part)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %s", part)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// answerSet renders the records of queryType in msg as a sorted, comma
+// separated answer, or the response code when the query failed
+func answerSet(msg *atlas.DNSMessage, queryType string) (string, []netip.Addr) {
+	if msg.Rcode != 0 {
+		return msg.RcodeName(), nil
+	}
+
+	var (
+		data  []string
+		addrs []netip.Addr
+	)
+	for _, answer := range msg.Answers {
+		if !strings.EqualFold(answer.Type, queryType) {
+			continue
+		}
+		data = append(data, answer.Data)
+		if addr, err := netip.ParseAddr(answer.Data); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	if len(data) == 0 {
+		return "NODATA", nil
+	}
+
+	slices.Sort(data)
+	return strings.Join(slices.Compact(data), ", "), addrs
+}
+
+// answerReason explains why an answer set looks inconsistent or hijacked,
+// or returns "" when it looks fine
+func answerReason(answer string, addrs []netip.Addr, consensus string, expected []netip.Prefix) string {
+	for _, addr := range addrs {
+		for _, bogon := range bogonPrefixes {
+			if bogon.Contains(addr.Unmap()) {
+				return fmt.Sprintf("bogon address %s", addr)
+			}
+		}
+	}
+
+	if len(expected) > 0 {
+		if len(addrs) == 0 {
+			return "no address in expected range"
+		}
+		for _, addr := range addrs {
+			if !slices.ContainsFunc(expected, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) }) {
+				return fmt.Sprintf("unexpected address %s", addr)
+			}
+		}
+		return ""
+	}
+
+	if answer != consensus {
+		return "differs from consensus"
+	}
+
+	return ""
+}
@@ -0,0 +1,175 @@
+package analyzer
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// CompareDualStack pairs IPv4 and IPv6 traceroutes by probe and compares
+// their AS paths and RTTs. ASNs of the probes are taken from asnOf.
+func CompareDualStack(ctx context.Context, v4, v6 []atlas.TracerouteResult, asnOf func(probeID int) int) (atlas.DualStackComparison, error) {
+	var (
+		comparison atlas.DualStackComparison
+		rttDiffs   []float64
+		byProbeV4  = make(map[int]atlas.TracerouteResult)
+		byProbeV6  = make(map[int]atlas.TracerouteResult)
+		seenV4     = make(map[int]int) // ASN -> probes whose v4 path crossed it
+		seenV6     = make(map[int]int)
+	)
+
+	for _, r := range v4 {
+		byProbeV4[r.ProbeID] = r
+	}
+	for _, r := range v6 {
+		byProbeV6[r.ProbeID] = r
+	}
+
+	for probeID, r4 := range byProbeV4 {
+		r6, ok := byProbeV6[probeID]
+		if !ok {
+			comparison.Unpaired++
+			continue
+		}
+
+		p := atlas.DualStackProbe{
+			ProbeID: probeID,
+			ASN:     asnOf(probeID),
+			PathV4:  asPath(ctx, r4),
+			PathV6:  asPath(ctx, r6),
+		}
+		p.RTTV4, p.ReachedV4 = finalRTT(r4)
+		p.RTTV6, p.ReachedV6 = finalRTT(r6)
+		p.DivergeAt = divergence(p.PathV4, p.PathV6)
+
+		if p.DivergeAt < 0 {
+			comparison.Identical++
+		} else {
+			comparison.Divergent++
+		}
+		if p.ReachedV4 && p.ReachedV6 {
+			rttDiffs = append(rttDiffs, p.RTTV6-p.RTTV4)
+		}
+
+		for _, asn := range slices.Compact(slices.Sorted(slices.Values(p.PathV4))) {
+			seenV4[asn]++
+		}
+		for _, asn := range slices.Compact(slices.Sorted(slices.Values(p.PathV6))) {
+			seenV6[asn]++
+		}
+
+		comparison.Probes = append(comparison.Probes, p)
+	}
+
+	for probeID := range byProbeV6 {
+		if _, ok := byProbeV4[probeID]; !ok {
+			comparison.Unpaired++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return comparison, err
+	}
+
+	if len(rttDiffs) > 0 {
+		slices.Sort(rttDiffs)
+		comparison.MedianRTTDiff = percentile(rttDiffs, 50)
+	}
+
+	comparison.OnlyV4 = familyOnly(ctx, seenV4, seenV6)
+	comparison.OnlyV6 = familyOnly(ctx, seenV6, seenV4)
+
+	slices.SortFunc(comparison.Probes, func(a, b atlas.DualStackProbe) int {
+		if c := cmp.Compare(a.ASN, b.ASN); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProbeID, b.ProbeID)
+	})
+
+	return comparison, nil
+}
+
+// asPath returns the ASNs of the responding hops of a traceroute in order,
+// with consecutive duplicates collapsed
+func asPath(ctx context.Context, result atlas.TracerouteResult) []int {
+	var path []int
+
+	for _, hop := range result.Result {
+		for _, reply := range hop.Result {
+			if reply.From == "" || reply.X == "*" {
+				continue
+			}
+
+			asn, err := lookupASN(ctx, reply.From)
+			if err == nil && asn > 0 && (len(path) == 0 || path[len(path)-1] != asn) {
+				path = append(path, asn)
+			}
+			break
+		}
+	}
+
+	return path
+}
+
+// finalRTT returns the lowest RTT of the last responding hop and whether that
+// hop is the destination
+func finalRTT(result atlas.TracerouteResult) (float64, bool) {
+	for i := len(result.Result) - 1; i >= 0; i-- {
+		var (
+			best    float64
+			from    string
+			replied bool
+		)
+		for _, reply := range result.Result[i].Result {
+			if reply.From == "" || reply.X == "*" || reply.RTT <= 0 {
+				continue
+			}
+			if !replied || reply.RTT < best {
+				best, from, replied = reply.RTT, reply.From, true
+			}
+		}
+		if replied {
+			return best, from == result.DstAddr
+		}
+	}
+	return 0, false
+}
+
+// divergence returns the index of the first entry where the paths differ,
+// or -1 when they are identical
+func divergence(a, b []int) int {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	if len(a) != len(b) {
+		return min(len(a), len(b))
+	}
+	return -1
+}
+
+// familyOnly lists the ASNs in seen that never appear in other, most
+// frequent first
+func familyOnly(ctx context.Context, seen, other map[int]int) []atlas.FamilyASN {
+	var only []atlas.FamilyASN
+
+	for asn, probes := range seen {
+		if other[asn] > 0 {
+			continue
+		}
+		name, _ := lookupASNName(ctx, asn)
+		only = append(only, atlas.FamilyASN{ASN: asn, Name: name, Probes: probes})
+	}
+
+	slices.SortFunc(only, func(a, b atlas.FamilyASN) int {
+		if c := cmp.Compare(b.Probes, a.Probes); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ASN, b.ASN)
+	})
+
+	return only
+}
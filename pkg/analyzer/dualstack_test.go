@@ -0,0 +1,175 @@
+package analyzer
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+func TestDivergence(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []int
+		want int
+	}{
+		{"identical", []int{1, 2, 3}, []int{1, 2, 3}, -1},
+		{"both empty", nil, nil, -1},
+		{"differ at the start", []int{1, 2}, []int{4, 2}, 0},
+		{"differ in the middle", []int{1, 2, 3}, []int{1, 5, 3}, 1},
+		{"second is a prefix", []int{1, 2, 3}, []int{1, 2}, 2},
+		{"first is a prefix", []int{1}, []int{1, 2}, 1},
+		{"one empty", nil, []int{1}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := divergence(tt.a, tt.b); got != tt.want {
+				t.Errorf("divergence(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// hop builds a traceroute hop from (from, rtt) replies, an empty from standing for a timeout
+func hop(n int, replies ...any) atlas.HopResult {
+	h := atlas.HopResult{Hop: n}
+	for i := 0; i < len(replies); i += 2 {
+		from, rtt := replies[i].(string), replies[i+1].(float64)
+		if from == "" {
+			h.Result = append(h.Result, atlas.HopReply{X: "*"})
+			continue
+		}
+		h.Result = append(h.Result, atlas.HopReply{From: from, RTT: rtt})
+	}
+	return h
+}
+
+func TestFinalRTT(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      atlas.TracerouteResult
+		wantRTT     float64
+		wantReached bool
+	}{
+		{
+			name:   "no hops",
+			result: atlas.TracerouteResult{DstAddr: "192.0.2.1"},
+		},
+		{
+			name: "lowest RTT of the destination",
+			result: atlas.TracerouteResult{DstAddr: "192.0.2.1", Result: []atlas.HopResult{
+				hop(1, "198.51.100.1", 1.0),
+				hop(2, "192.0.2.1", 9.0, "192.0.2.1", 7.5, "192.0.2.1", 8.0),
+			}},
+			wantRTT: 7.5, wantReached: true,
+		},
+		{
+			name: "trailing timeouts are skipped",
+			result: atlas.TracerouteResult{DstAddr: "192.0.2.1", Result: []atlas.HopResult{
+				hop(1, "198.51.100.1", 3.0),
+				hop(2, "", 0.0, "", 0.0),
+			}},
+			wantRTT: 3.0, wantReached: false,
+		},
+		{
+			name: "all timeouts",
+			result: atlas.TracerouteResult{DstAddr: "192.0.2.1", Result: []atlas.HopResult{
+				hop(1, "", 0.0),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rtt, reached := finalRTT(tt.result)
+			if rtt != tt.wantRTT || reached != tt.wantReached {
+				t.Errorf("finalRTT = (%v, %v), want (%v, %v)", rtt, reached, tt.wantRTT, tt.wantReached)
+			}
+		})
+	}
+}
+
+// useRoutes answers ASN lookups from routes for the duration of the test
+func useRoutes(t *testing.T, routes map[string]int) {
+	t.Helper()
+
+	var table []Route
+	for prefix, asn := range routes {
+		table = append(table, Route{Prefix: netip.MustParsePrefix(prefix), ASN: asn})
+	}
+	SetOffline(true, table)
+	t.Cleanup(func() { SetOffline(false, nil) })
+}
+
+func TestCompareDualStack(t *testing.T) {
+	const eyeball, transitA, transitB, content = 64500, 64510, 64511, 64520
+	useRoutes(t, map[string]int{
+		"192.0.2.0/25":       eyeball,
+		"2001:db8:a::/48":    eyeball,
+		"198.51.100.0/24":    transitA,
+		"2001:db8:5100::/48": transitA,
+		"203.0.113.0/24":     transitB,
+		"192.0.2.128/25":     content,
+		"2001:db8:c::/48":    content,
+	})
+
+	const dst4, dst6 = "192.0.2.200", "2001:db8:c::1"
+	trace := func(probeID int, dst string, hops ...atlas.HopResult) atlas.TracerouteResult {
+		return atlas.TracerouteResult{ProbeID: probeID, DstAddr: dst, Result: hops}
+	}
+
+	v4 := []atlas.TracerouteResult{
+		// Probe 1 takes the same transit over both families
+		trace(1, dst4, hop(1, "192.0.2.1", 1.0), hop(2, "198.51.100.1", 5.0), hop(3, dst4, 20.0)),
+		// Probe 2 crosses transit B over IPv4 only
+		trace(2, dst4, hop(1, "192.0.2.2", 1.0), hop(2, "203.0.113.1", 5.0), hop(3, dst4, 30.0)),
+		// Probe 3 has no IPv6 result
+		trace(3, dst4, hop(1, "192.0.2.3", 1.0), hop(2, dst4, 10.0)),
+	}
+	v6 := []atlas.TracerouteResult{
+		trace(1, dst6, hop(1, "2001:db8:a::1", 1.0), hop(2, "2001:db8:5100::1", 5.0), hop(3, dst6, 24.0)),
+		trace(2, dst6, hop(1, "2001:db8:a::2", 1.0), hop(2, "2001:db8:5100::1", 5.0), hop(3, dst6, 26.0)),
+		// Probe 4 has no IPv4 result
+		trace(4, dst6, hop(1, "2001:db8:a::4", 1.0)),
+	}
+
+	comparison, err := CompareDualStack(context.Background(), v4, v6, func(int) int { return eyeball })
+	if err != nil {
+		t.Fatalf("CompareDualStack: %v", err)
+	}
+
+	if comparison.Identical != 1 || comparison.Divergent != 1 || comparison.Unpaired != 2 {
+		t.Errorf("identical/divergent/unpaired = %d/%d/%d, want 1/1/2",
+			comparison.Identical, comparison.Divergent, comparison.Unpaired)
+	}
+
+	if len(comparison.Probes) != 2 {
+		t.Fatalf("got %d paired probes, want 2", len(comparison.Probes))
+	}
+	same, diverged := comparison.Probes[0], comparison.Probes[1]
+
+	if want := []int{eyeball, transitA, content}; !slices.Equal(same.PathV4, want) || !slices.Equal(same.PathV6, want) {
+		t.Errorf("probe 1 paths = %v / %v, want %v", same.PathV4, same.PathV6, want)
+	}
+	if same.DivergeAt != -1 || !same.ReachedV4 || !same.ReachedV6 || same.RTTV4 != 20 || same.RTTV6 != 24 {
+		t.Errorf("probe 1 = %+v", same)
+	}
+	if diverged.ProbeID != 2 || diverged.DivergeAt != 1 {
+		t.Errorf("probe 2 = %+v, want divergence at index 1", diverged)
+	}
+
+	// RTT differences are +4 and -4, the nearest-rank median is the lower one
+	if comparison.MedianRTTDiff != -4 {
+		t.Errorf("median RTT diff = %v, want -4", comparison.MedianRTTDiff)
+	}
+
+	if len(comparison.OnlyV4) != 1 || comparison.OnlyV4[0].ASN != transitB || comparison.OnlyV4[0].Probes != 1 {
+		t.Errorf("only IPv4 = %+v, want AS%d from 1 probe", comparison.OnlyV4, transitB)
+	}
+	if len(comparison.OnlyV6) != 0 {
+		t.Errorf("only IPv6 = %+v, want none", comparison.OnlyV6)
+	}
+}
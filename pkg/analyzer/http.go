@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// SummarizeHTTP groups HTTP results by the ASN returned by asnOf and counts
+// status codes and time-to-first-byte per ASN, sorted by ASN
+func SummarizeHTTP(results []atlas.HTTPResult, asnOf func(atlas.HTTPResult) int) []atlas.HTTPASNSummary {
+	var (
+		summaries = make(map[int]*atlas.HTTPASNSummary)
+		probes    = make(map[int]map[int]bool)
+		ttfbs     = make(map[int][]float64)
+		rts       = make(map[int][]float64)
+	)
+
+	for _, result := range results {
+		asn := asnOf(result)
+		summary, ok := summaries[asn]
+		if !ok {
+			summary = &atlas.HTTPASNSummary{ASN: asn, StatusCodes: make(map[int]int)}
+			summaries[asn] = summary
+			probes[asn] = make(map[int]bool)
+		}
+		probes[asn][result.ProbeID] = true
+
+		for _, resp := range result.Result {
+			summary.Requests++
+			if resp.Err != "" || resp.DNSErr != "" || resp.Res == 0 {
+				summary.Errors++
+				continue
+			}
+
+			summary.StatusCodes[resp.Res]++
+			if resp.TTFB > 0 {
+				ttfbs[asn] = append(ttfbs[asn], resp.TTFB)
+			}
+			if resp.RT > 0 {
+				rts[asn] = append(rts[asn], resp.RT)
+			}
+		}
+	}
+
+	stats := make([]atlas.HTTPASNSummary, 0, len(summaries))
+	for asn, summary := range summaries {
+		summary.Probes = len(probes[asn])
+
+		if samples := ttfbs[asn]; len(samples) > 0 {
+			slices.Sort(samples)
+			summary.MedianTTFB = percentile(samples, 50)
+			summary.P95TTFB = percentile(samples, 95)
+		}
+		if samples := rts[asn]; len(samples) > 0 {
+			slices.Sort(samples)
+			summary.MedianRT = percentile(samples, 50)
+		}
+
+		stats = append(stats, *summary)
+	}
+
+	slices.SortFunc(stats, func(a, b atlas.HTTPASNSummary) int {
+		return cmp.Compare(a.ASN, b.ASN)
+	})
+
+	return stats
+}
+
+// SummarizeCerts groups SSL certificate results by the ASN returned by asnOf
+// and counts the leaf certificates served per ASN. When expected is not
+// empty, results whose leaf certificate has a different fingerprint are
+// reported as mismatches.
+func SummarizeCerts(results []atlas.SSLCertResult, asnOf func(atlas.SSLCertResult) int, expected string) ([]atlas.CertASNSummary, []atlas.CertMismatch) {
+	var (
+		summaries  = make(map[int]*atlas.CertASNSummary)
+		probes     = make(map[int]map[int]bool)
+		counts     = make(map[int]map[string]*atlas.FingerprintCount)
+		rts        = make(map[int][]float64)
+		mismatches []atlas.CertMismatch
+	)
+
+	for _, result := range results {
+		asn := asnOf(result)
+		summary, ok := summaries[asn]
+		if !ok {
+			summary = &atlas.CertASNSummary{ASN: asn}
+			summaries[asn] = summary
+			probes[asn] = make(map[int]bool)
+			counts[asn] = make(map[string]*atlas.FingerprintCount)
+		}
+		probes[asn][result.ProbeID] = true
+
+		chain, err := result.Chain()
+		if result.Err != "" || result.Alert != nil || err != nil || len(chain) == 0 {
+			summary.Errors++
+			continue
+		}
+
+		if result.RT > 0 {
+			rts[asn] = append(rts[asn], result.RT)
+		}
+
+		leaf := chain[0]
+		// Only the leaf identifies the server: an interception proxy may
+		// forward the genuine intermediates or even the genuine leaf
+		// further down the chain
+		match := expected == "" || leaf.Fingerprint == expected
+		if !match {
+			summary.Mismatches++
+			mismatches = append(mismatches, atlas.CertMismatch{
+				ProbeID:     result.ProbeID,
+				ASN:         asn,
+				DstAddr:     result.DstAddr,
+				Fingerprint: leaf.Fingerprint,
+				Subject:     leaf.Subject,
+			})
+		}
+
+		if fc, ok := counts[asn][leaf.Fingerprint]; ok {
+			fc.Count++
+		} else {
+			counts[asn][leaf.Fingerprint] = &atlas.FingerprintCount{
+				Fingerprint: leaf.Fingerprint,
+				Subject:     leaf.Subject,
+				Count:       1,
+				Match:       match,
+			}
+		}
+	}
+
+	stats := make([]atlas.CertASNSummary, 0, len(summaries))
+	for asn, summary := range summaries {
+		summary.Probes = len(probes[asn])
+
+		if samples := rts[asn]; len(samples) > 0 {
+			slices.Sort(samples)
+			summary.MedianRT = percentile(samples, 50)
+		}
+
+		for _, fc := range counts[asn] {
+			summary.Fingerprints = append(summary.Fingerprints, *fc)
+		}
+		slices.SortFunc(summary.Fingerprints, func(a, b atlas.FingerprintCount) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Fingerprint, b.Fingerprint)
+		})
+
+		stats = append(stats, *summary)
+	}
+
+	slices.SortFunc(stats, func(a, b atlas.CertASNSummary) int {
+		return cmp.Compare(a.ASN, b.ASN)
+	})
+	slices.SortFunc(mismatches, func(a, b atlas.CertMismatch) int {
+		if c := cmp.Compare(a.ASN, b.ASN); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ProbeID, b.ProbeID)
+	})
+
+	return stats, mismatches
+}
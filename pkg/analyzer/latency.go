@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// SummarizeLatency groups ping results with groupOf and computes latency
+// statistics per group, sorted by median RTT with unreachable groups last.
+// Results for which groupOf returns "" are skipped.
+func SummarizeLatency(results []atlas.PingResult, groupOf func(atlas.PingResult) string) []atlas.LatencyStats {
+	groups := make(map[string][]atlas.PingResult)
+	for _, result := range results {
+		if group := groupOf(result); group != "" {
+			groups[group] = append(groups[group], result)
+		}
+	}
+
+	stats := make([]atlas.LatencyStats, 0, len(groups))
+	for group, groupResults := range groups {
+		s := latencyStats(groupResults)
+		s.Group = group
+		stats = append(stats, s)
+	}
+
+	slices.SortFunc(stats, func(a, b atlas.LatencyStats) int {
+		if (a.Received == 0) != (b.Received == 0) {
+			if a.Received == 0 {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(a.Median, b.Median); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Group, b.Group)
+	})
+
+	return stats
+}
+
+// OverallLatency computes latency statistics across all ping results
+func OverallLatency(results []atlas.PingResult) atlas.LatencyStats {
+	s := latencyStats(results)
+	s.Group = "All probes"
+	return s
+}
+
+// latencyStats computes latency statistics over every reply in results
+func latencyStats(results []atlas.PingResult) atlas.LatencyStats {
+	var (
+		stats   atlas.LatencyStats
+		samples []float64
+		probes  = make(map[int]bool)
+	)
+
+	for _, result := range results {
+		probes[result.ProbeID] = true
+		stats.Sent += result.Sent
+		stats.Received += result.Rcvd
+
+		for _, reply := range result.Result {
+			if reply.RTT > 0 && reply.X == "" && reply.Error == "" {
+				samples = append(samples, reply.RTT)
+			}
+		}
+	}
+	stats.Probes = len(probes)
+
+	if stats.Sent > 0 {
+		stats.Loss = float64(stats.Sent-stats.Received) / float64(stats.Sent) * 100
+	}
+
+	if len(samples) > 0 {
+		slices.Sort(samples)
+		stats.Min = samples[0]
+		stats.Max = samples[len(samples)-1]
+		stats.Median = percentile(samples, 50)
+		stats.P95 = percentile(samples, 95)
+	}
+
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted samples using the nearest-rank method
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
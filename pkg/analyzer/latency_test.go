@@ -0,0 +1,157 @@
+package analyzer
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+func TestPercentile(t *testing.T) {
+	ten := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"single sample median", []float64{7}, 50, 7},
+		{"single sample p95", []float64{7}, 95, 7},
+		{"single sample p0", []float64{7}, 0, 7},
+		{"two samples median", []float64{1, 9}, 50, 1},
+		{"two samples p95", []float64{1, 9}, 95, 9},
+		{"median of ten", ten, 50, 5},
+		{"p95 of ten", ten, 95, 10},
+		{"p90 of ten", ten, 90, 9},
+		{"p91 rounds up", ten, 91, 10},
+		{"p0 is the minimum", ten, 0, 1},
+		{"p100 is the maximum", ten, 100, 10},
+		{"median of odd count", []float64{1, 2, 3, 4, 5}, 50, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+// pingResult builds a ping result with one reply per RTT, 0 standing for a timeout
+func pingResult(probeID int, rtts ...float64) atlas.PingResult {
+	result := atlas.PingResult{ProbeID: probeID, Sent: len(rtts)}
+	for _, rtt := range rtts {
+		if rtt == 0 {
+			result.Result = append(result.Result, atlas.PingReply{X: "*"})
+			continue
+		}
+		result.Rcvd++
+		result.Result = append(result.Result, atlas.PingReply{RTT: rtt})
+	}
+	return result
+}
+
+func TestLatencyStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []atlas.PingResult
+		want    atlas.LatencyStats
+	}{
+		{
+			name: "no results",
+			want: atlas.LatencyStats{},
+		},
+		{
+			name:    "single sample",
+			results: []atlas.PingResult{pingResult(1, 12.5)},
+			want:    atlas.LatencyStats{Probes: 1, Sent: 1, Received: 1, Min: 12.5, Median: 12.5, P95: 12.5, Max: 12.5},
+		},
+		{
+			name:    "all lost",
+			results: []atlas.PingResult{pingResult(1, 0, 0, 0)},
+			want:    atlas.LatencyStats{Probes: 1, Sent: 3, Loss: 100},
+		},
+		{
+			name: "samples across probes",
+			results: []atlas.PingResult{
+				pingResult(1, 30, 10, 20),
+				pingResult(2, 40, 0, 50),
+			},
+			want: atlas.LatencyStats{Probes: 2, Sent: 6, Received: 5, Min: 10, Median: 30, P95: 50, Max: 50, Loss: 100.0 / 6},
+		},
+		{
+			name: "repeated probe counts once",
+			results: []atlas.PingResult{
+				pingResult(1, 5),
+				pingResult(1, 15),
+			},
+			want: atlas.LatencyStats{Probes: 1, Sent: 2, Received: 2, Min: 5, Median: 5, P95: 15, Max: 15},
+		},
+		{
+			name: "errored replies are not samples",
+			results: []atlas.PingResult{{
+				ProbeID: 1, Sent: 2, Rcvd: 1,
+				Result: []atlas.PingReply{{RTT: 8}, {Error: "sendto failed"}},
+			}},
+			want: atlas.LatencyStats{Probes: 1, Sent: 2, Received: 1, Min: 8, Median: 8, P95: 8, Max: 8, Loss: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := latencyStats(tt.results)
+			if math.Abs(got.Loss-tt.want.Loss) > 1e-9 {
+				t.Errorf("loss = %v, want %v", got.Loss, tt.want.Loss)
+			}
+			got.Loss = tt.want.Loss
+			if got != tt.want {
+				t.Errorf("latencyStats = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	results := []atlas.PingResult{
+		pingResult(1, 30),
+		pingResult(2, 10),
+		pingResult(3, 0),
+		pingResult(4, 20),
+		pingResult(5, 99),
+	}
+	groups := map[int]string{1: "slow", 2: "fast", 3: "down", 4: "fast", 5: ""}
+
+	stats := SummarizeLatency(results, func(r atlas.PingResult) string { return groups[r.ProbeID] })
+
+	// Sorted by median, unreachable groups last, ungrouped results skipped
+	var order []string
+	for _, s := range stats {
+		order = append(order, s.Group)
+	}
+	if want := []string{"fast", "slow", "down"}; !slices.Equal(order, want) {
+		t.Fatalf("groups = %v, want %v", order, want)
+	}
+
+	if fast := stats[0]; fast.Probes != 2 || fast.Min != 10 || fast.Max != 20 {
+		t.Errorf("fast = %+v, want 2 probes from 10 to 20 ms", fast)
+	}
+	if down := stats[2]; down.Received != 0 || down.Loss != 100 {
+		t.Errorf("down = %+v, want 100%% loss", down)
+	}
+}
+
+func TestOverallLatency(t *testing.T) {
+	stats := OverallLatency([]atlas.PingResult{pingResult(1, 10, 0), pingResult(2, 30)})
+
+	want := atlas.LatencyStats{Group: "All probes", Probes: 2, Sent: 3, Received: 2, Min: 10, Median: 10, P95: 30, Max: 30, Loss: 100.0 / 3}
+	if math.Abs(stats.Loss-want.Loss) > 1e-9 {
+		t.Errorf("loss = %v, want %v", stats.Loss, want.Loss)
+	}
+	stats.Loss = want.Loss
+	if stats != want {
+		t.Errorf("OverallLatency = %+v, want %+v", stats, want)
+	}
+}
@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Route maps a prefix to its origin ASN for offline lookups
+type Route struct {
+	Prefix netip.Prefix
+	ASN    int
+	Holder string
+}
+
+// While offline is set, ASN lookups are answered from offlineRoutes instead of RIPEstat
+var (
+	offline       bool
+	offlineRoutes []Route
+)
+
+// SetOffline stops lookups from querying RIPEstat when enabled. Addresses are
+// then resolved to the origin of the longest matching prefix in routes, and
+// addresses outside every route have no ASN. The lookup caches are bypassed,
+// so only routes decide the result.
+func SetOffline(enabled bool, routes []Route) {
+	offline = enabled
+	offlineRoutes = routes
+}
+
+// lookupRoute returns the origin ASN of ip from the offline routes
+func lookupRoute(ip string) (int, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return 0, fmt.Errorf("invalid IP %s", ip)
+	}
+
+	best := -1
+	for i, route := range offlineRoutes {
+		if route.Prefix.Contains(addr) && (best < 0 || route.Prefix.Bits() > offlineRoutes[best].Prefix.Bits()) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, fmt.Errorf("no route for IP %s", ip)
+	}
+	return offlineRoutes[best].ASN, nil
+}
+
+// routeHolder returns the holder an offline route gives asn, or "AS<asn>"
+func routeHolder(asn int) string {
+	for _, route := range offlineRoutes {
+		if route.ASN == asn && route.Holder != "" {
+			return route.Holder
+		}
+	}
+	return fmt.Sprintf("AS%d", asn)
+}
+
+// ReadRoutes reads a route table with one "<prefix> <asn> [holder]" entry per
+// line, e.g. "192.0.2.0/24 AS64500 Example Net". The ASN may carry an AS
+// prefix, empty lines and # comments are skipped.
+func ReadRoutes(r io.Reader) ([]Route, error) {
+	var (
+		routes []Route
+		lineNo int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected <prefix> <asn> [holder]", lineNo)
+		}
+
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid prefix %s", lineNo, fields[0])
+		}
+
+		asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(fields[1]), "AS"))
+		if err != nil || asn <= 0 {
+			return nil, fmt.Errorf("line %d: invalid ASN %s", lineNo, fields[1])
+		}
+
+		routes = append(routes, Route{
+			Prefix: prefix.Masked(),
+			ASN:    asn,
+			Holder: strings.Join(fields[2:], " "),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+	return routes, nil
+}
+
+// ReadRoutesFile reads a route table file, see ReadRoutes for the format
+func ReadRoutesFile(path string) ([]Route, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open routes file: %w", err)
+	}
+	defer file.Close()
+
+	routes, err := ReadRoutes(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return routes, nil
+}
@@ -1,14 +1,25 @@
 package analyzer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"strings"
 	"time"
 
 	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/httpretry"
 )
 
+// DefaultRIPEstatURL is the root of the RIPEstat data API
+const DefaultRIPEstatURL = "https://stat.ripe.net/data"
+
+// ripestatBaseURL is the RIPEstat API root used for lookups
+var ripestatBaseURL = DefaultRIPEstatURL
+
 // ASNLookupCache caches ASN lookups to avoid repeated API calls
 var ASNLookupCache = make(map[string]int)
 
@@ -18,27 +29,45 @@ var ASNNameCache = make(map[int]string)
 // Semaphore for RIPEstat API rate limiting (max 8 concurrent requests)
 var ripestatSemaphore = make(chan struct{}, 8)
 
-// HTTP/2 client for RIPEstat API
-var ripestatClient = &http.Client{
+// HTTP/2 client for RIPEstat API, retrying transient failures
+var ripestatClient = httpretry.New(&http.Client{
 	Timeout: 10 * time.Second,
 	Transport: &http.Transport{
 		ForceAttemptHTTP2: true, // Enable HTTP/2
 	},
+}, httpretry.DefaultPolicy())
+
+// SetBaseURL points RIPEstat lookups at a different API root, e.g. a test server
+func SetBaseURL(baseURL string) {
+	if baseURL != "" {
+		ripestatBaseURL = strings.TrimSuffix(baseURL, "/")
+	}
 }
 
-// AnalyzeCommonASNs analyzes traceroute results to find common ASNs
-func AnalyzeCommonASNs(results []atlas.TracerouteResult, threshold float64) ([]atlas.ASNInfo, error) {
-	totalProbes := len(results)
-	if totalProbes == 0 {
+// SetRetryPolicy configures how transient RIPEstat failures are retried
+func SetRetryPolicy(policy httpretry.Policy) {
+	ripestatClient.SetPolicy(policy)
+}
+
+// AnalyzeCommonASNs analyzes traceroute results to find common ASNs.
+// Occurrences count probes, so a probe that reported several times (e.g. in
+// merged measurements) counts once per ASN.
+func AnalyzeCommonASNs(ctx context.Context, results []atlas.TracerouteResult, threshold float64) ([]atlas.ASNInfo, error) {
+	if len(results) == 0 {
 		return nil, fmt.Errorf("no results to analyze")
 	}
 
 	// Track ASN occurrences and hop positions
 	asnStats := make(map[int]*asnTracker)
+	seenByProbe := make(map[int]map[int]bool)
 
 	for _, result := range results {
-		// Extract ASN path from this traceroute
-		seenASNs := make(map[int]bool) // Track ASNs seen in this path to avoid double counting
+		// Track ASNs seen by this probe to avoid double counting
+		seenASNs, ok := seenByProbe[result.ProbeID]
+		if !ok {
+			seenASNs = make(map[int]bool)
+			seenByProbe[result.ProbeID] = seenASNs
+		}
 
 		for _, hop := range result.Result {
 			for _, reply := range hop.Result {
@@ -47,7 +76,7 @@ func AnalyzeCommonASNs(results []atlas.TracerouteResult, threshold float64) ([]a
 				}
 
 				// Look up ASN for this IP
-				asn, err := lookupASN(reply.From)
+				asn, err := lookupASN(ctx, reply.From)
 				if err != nil || asn == 0 {
 					continue
 				}
@@ -69,7 +98,12 @@ func AnalyzeCommonASNs(results []atlas.TracerouteResult, threshold float64) ([]a
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Filter ASNs by threshold and prepare results
+	totalProbes := len(seenByProbe)
 	minOccurrences := int(float64(totalProbes) * threshold)
 	var commonASNs []atlas.ASNInfo
 
@@ -96,7 +130,7 @@ func AnalyzeCommonASNs(results []atlas.TracerouteResult, threshold float64) ([]a
 				avgHop = sum / len(stats.hopPositions)
 			}
 
-			asnName, _ := lookupASNName(asn)
+			asnName, _ := lookupASNName(ctx, asn)
 
 			commonASNs = append(commonASNs, atlas.ASNInfo{
 				ASN:         asn,
@@ -132,19 +166,43 @@ type RIPEstatResponse struct {
 	} `json:"data"`
 }
 
+// LookupASN returns the origin ASN of an IP address according to RIPEstat.
+// Results are cached in ASNLookupCache.
+func LookupASN(ctx context.Context, ip string) (int, error) {
+	return lookupASN(ctx, ip)
+}
+
 // lookupASN looks up the ASN for a given IP address using RIPEstat API
-func lookupASN(ip string) (int, error) {
+func lookupASN(ctx context.Context, ip string) (int, error) {
+	// Private, link-local and other non-routable hops have no origin ASN
+	if addr, err := netip.ParseAddr(ip); err == nil && (!addr.IsGlobalUnicast() || addr.IsPrivate()) {
+		return 0, fmt.Errorf("no ASN for non-routable IP %s", ip)
+	}
+
+	if offline {
+		return lookupRoute(ip)
+	}
+
 	// Check cache first
 	if asn, exists := ASNLookupCache[ip]; exists {
 		return asn, nil
 	}
 
 	// Acquire semaphore to respect RIPEstat API rate limit (max 8 concurrent)
-	ripestatSemaphore <- struct{}{}
+	select {
+	case ripestatSemaphore <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
 	defer func() { <-ripestatSemaphore }()
 
-	url := fmt.Sprintf("https://stat.ripe.net/data/prefix-overview/data.json?resource=%s", ip)
-	resp, err := ripestatClient.Get(url)
+	url := fmt.Sprintf("%s/prefix-overview/data.json?resource=%s", ripestatBaseURL, neturl.QueryEscape(ip))
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := ripestatClient.Do(req)
 	if err != nil {
 		return 0, fmt.Errorf("failed to lookup ASN: %w", err)
 	}
@@ -179,7 +237,11 @@ func lookupASN(ip string) (int, error) {
 }
 
 // lookupASNName looks up the name/organization for an ASN
-func lookupASNName(asn int) (string, error) {
+func lookupASNName(ctx context.Context, asn int) (string, error) {
+	if offline {
+		return routeHolder(asn), nil
+	}
+
 	// Check cache first (may have been populated by lookupASN)
 	if name, exists := ASNNameCache[asn]; exists {
 		return name, nil
@@ -187,11 +249,20 @@ func lookupASNName(asn int) (string, error) {
 
 	// If not in cache, query RIPEstat API
 	// We use a dummy IP query with ASN notation
-	ripestatSemaphore <- struct{}{}
+	select {
+	case ripestatSemaphore <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Sprintf("AS%d", asn), ctx.Err()
+	}
 	defer func() { <-ripestatSemaphore }()
 
-	url := fmt.Sprintf("https://stat.ripe.net/data/as-overview/data.json?resource=AS%d", asn)
-	resp, err := ripestatClient.Get(url)
+	url := fmt.Sprintf("%s/as-overview/data.json?resource=AS%d", ripestatBaseURL, asn)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Sprintf("AS%d", asn), nil
+	}
+
+	resp, err := ripestatClient.Do(req)
 	if err != nil {
 		return fmt.Sprintf("AS%d", asn), nil
 	}
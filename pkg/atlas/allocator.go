@@ -0,0 +1,301 @@
+package atlas
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Names of the built-in allocation strategies
+const (
+	AllocatorEqual        = "equal"
+	AllocatorProportional = "proportional"
+	AllocatorWeighted     = "weighted"
+)
+
+// Allocator decides how many probes each ASN contributes to a measurement.
+// Which probes are picked within an ASN is up to AllocationOptions.
+type Allocator interface {
+	// Name identifies the strategy in reports
+	Name() string
+
+	// Params lists the strategy's parameters in a stable order, e.g.
+	// "budget=500", for reports
+	Params() []string
+
+	// Quotas returns the number of probes to allocate from each of asns,
+	// which all have at least one of available[asn] probes
+	Quotas(asns []int, available map[int]int) (map[int]int, error)
+}
+
+// DescribeAllocator formats an allocator for reports, e.g.
+// "weighted (budget=500, max-per-asn=50)"
+func DescribeAllocator(a Allocator) string {
+	params := a.Params()
+	if len(params) == 0 {
+		return a.Name()
+	}
+	return fmt.Sprintf("%s (%s)", a.Name(), strings.Join(params, ", "))
+}
+
+// MaxBudget is the most probes a selection may allocate: full measurements
+// of MaxProbesPerMeasurement, as many as may run at the same time
+const MaxBudget = MaxProbesPerMeasurement * MaxConcurrentMeasurements
+
+// AllocationLimits bound the quotas of every built-in strategy
+type AllocationLimits struct {
+	// Budget is the total number of probes, MaxProbesPerMeasurement when
+	// zero. Larger budgets are split across measurements, see ShardRequest.
+	Budget int
+
+	// MinPerASN probes are allocated from every ASN first, as far as it has them
+	MinPerASN int
+
+	// MaxPerASN caps the probes of a single ASN, zero for no cap
+	MaxPerASN int
+}
+
+// Validate checks the limits against each other and the Atlas quota
+func (l AllocationLimits) Validate() error {
+	switch {
+	case l.Budget < 0 || l.Budget > MaxBudget:
+		return fmt.Errorf("budget %d must be between 1 and %d", l.Budget, MaxBudget)
+	case l.MinPerASN < 0:
+		return fmt.Errorf("minimum per ASN %d must not be negative", l.MinPerASN)
+	case l.MaxPerASN < 0:
+		return fmt.Errorf("maximum per ASN %d must not be negative", l.MaxPerASN)
+	case l.MaxPerASN > 0 && l.MinPerASN > l.MaxPerASN:
+		return fmt.Errorf("minimum per ASN %d exceeds the maximum of %d", l.MinPerASN, l.MaxPerASN)
+	}
+	return nil
+}
+
+// budget returns the total number of probes to allocate
+func (l AllocationLimits) budget() int {
+	if l.Budget == 0 {
+		return MaxProbesPerMeasurement
+	}
+	return l.Budget
+}
+
+// params lists the limits that differ from the defaults
+func (l AllocationLimits) params() []string {
+	var params []string
+	if l.Budget > 0 {
+		params = append(params, fmt.Sprintf("budget=%d", l.Budget))
+	}
+	if l.MinPerASN > 0 {
+		params = append(params, fmt.Sprintf("min-per-asn=%d", l.MinPerASN))
+	}
+	if l.MaxPerASN > 0 {
+		params = append(params, fmt.Sprintf("max-per-asn=%d", l.MaxPerASN))
+	}
+	return params
+}
+
+// distribute fills the budget in proportion to weight. Every ASN first gets
+// MinPerASN probes, then the rest is shared by weight in rounds, each ASN
+// capped by its available probes and MaxPerASN. Whatever a capped ASN cannot
+// take goes to the others in the next round, until the budget is spent or
+// no ASN can take more. ASNs with zero weight only get their minimum.
+func (l AllocationLimits) distribute(asns []int, available map[int]int, weight func(asn int) float64) (map[int]int, error) {
+	quotas := make(map[int]int, len(asns))
+	caps := make(map[int]int, len(asns))
+	remaining := l.budget()
+
+	for _, asn := range asns {
+		caps[asn] = available[asn]
+		if l.MaxPerASN > 0 {
+			caps[asn] = min(caps[asn], l.MaxPerASN)
+		}
+		quotas[asn] = min(caps[asn], l.MinPerASN)
+		remaining -= quotas[asn]
+	}
+	if remaining < 0 {
+		return nil, fmt.Errorf("%d probes per ASN from %d ASNs exceed the budget of %d",
+			l.MinPerASN, len(asns), l.budget())
+	}
+
+	for remaining > 0 {
+		var open []int
+		total := 0.0
+		for _, asn := range asns {
+			if w := weight(asn); w > 0 && quotas[asn] < caps[asn] {
+				open = append(open, asn)
+				total += w
+			}
+		}
+		if len(open) == 0 {
+			break
+		}
+
+		handed := 0
+		for _, asn := range open {
+			share := int(float64(remaining) * weight(asn) / total)
+			share = min(share, caps[asn]-quotas[asn])
+			quotas[asn] += share
+			handed += share
+		}
+
+		// Shares that round down to nothing go one at a time to the
+		// heaviest ASNs, in request order among equals
+		if handed == 0 {
+			slices.SortStableFunc(open, func(a, b int) int {
+				return compareFloat(weight(b), weight(a))
+			})
+			for _, asn := range open[:min(remaining, len(open))] {
+				quotas[asn]++
+				handed++
+			}
+		}
+
+		remaining -= handed
+	}
+
+	return quotas, nil
+}
+
+// compareFloat orders a before b when a is smaller
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// EqualAllocator gives every ASN the same share of the budget. Shares an ASN
+// has no probes for go to the ASNs that do. This is the default strategy.
+type EqualAllocator struct {
+	AllocationLimits
+}
+
+// Name implements Allocator
+func (a EqualAllocator) Name() string { return AllocatorEqual }
+
+// Params implements Allocator
+func (a EqualAllocator) Params() []string { return a.params() }
+
+// Quotas implements Allocator
+func (a EqualAllocator) Quotas(asns []int, available map[int]int) (map[int]int, error) {
+	return a.distribute(asns, available, func(int) float64 { return 1 })
+}
+
+// ProportionalAllocator shares the budget in proportion to the number of
+// probes each ASN has available, so large networks are not underrepresented
+type ProportionalAllocator struct {
+	AllocationLimits
+}
+
+// Name implements Allocator
+func (a ProportionalAllocator) Name() string { return AllocatorProportional }
+
+// Params implements Allocator
+func (a ProportionalAllocator) Params() []string { return a.params() }
+
+// Quotas implements Allocator
+func (a ProportionalAllocator) Quotas(asns []int, available map[int]int) (map[int]int, error) {
+	return a.distribute(asns, available, func(asn int) float64 { return float64(available[asn]) })
+}
+
+// WeightedAllocator shares the budget in proportion to a weight per ASN.
+// ASNs without a weight count as weight 1, weight 0 limits an ASN to
+// MinPerASN probes.
+type WeightedAllocator struct {
+	AllocationLimits
+	Weights map[int]float64
+
+	// Source names where the weights came from in reports, e.g. a file name
+	Source string
+}
+
+// Name implements Allocator
+func (a WeightedAllocator) Name() string { return AllocatorWeighted }
+
+// Params implements Allocator
+func (a WeightedAllocator) Params() []string {
+	params := a.params()
+	if a.Source != "" {
+		params = append(params, "weights="+a.Source)
+	}
+	return params
+}
+
+// Quotas implements Allocator
+func (a WeightedAllocator) Quotas(asns []int, available map[int]int) (map[int]int, error) {
+	return a.distribute(asns, available, func(asn int) float64 {
+		if w, ok := a.Weights[asn]; ok {
+			return w
+		}
+		return 1
+	})
+}
+
+// NewAllocator returns the built-in strategy of the given name
+func NewAllocator(name string, limits AllocationLimits, weights map[int]float64, source string) (Allocator, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case AllocatorEqual, "":
+		return EqualAllocator{limits}, nil
+	case AllocatorProportional:
+		return ProportionalAllocator{limits}, nil
+	case AllocatorWeighted:
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("the weighted strategy needs weights per ASN")
+		}
+		return WeightedAllocator{AllocationLimits: limits, Weights: weights, Source: source}, nil
+	}
+	return nil, fmt.Errorf("unknown allocation strategy %q: must be %s, %s or %s",
+		name, AllocatorEqual, AllocatorProportional, AllocatorWeighted)
+}
+
+// ReadWeightsFile reads ASN weights for WeightedAllocator, one "ASN WEIGHT"
+// pair per line (e.g. "AS5384 2.5" or "7713 1"). Blank lines and text after
+// # are ignored.
+func ReadWeightsFile(path string) (map[int]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open weights file: %w", err)
+	}
+	defer f.Close()
+
+	weights := make(map[int]float64)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"ASN WEIGHT\"", path, n)
+		}
+
+		asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(fields[0]), "AS"))
+		if err != nil || asn <= 0 {
+			return nil, fmt.Errorf("%s:%d: invalid ASN %q", path, n, fields[0])
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || weight < 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+			return nil, fmt.Errorf("%s:%d: invalid weight %q: must be a non-negative number", path, n, fields[1])
+		}
+		if _, dup := weights[asn]; dup {
+			return nil, fmt.Errorf("%s:%d: AS%d listed twice", path, n, asn)
+		}
+		weights[asn] = weight
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read weights file: %w", err)
+	}
+
+	return weights, nil
+}
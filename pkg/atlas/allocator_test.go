@@ -0,0 +1,152 @@
+package atlas
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAllocatorQuotas(t *testing.T) {
+	const a, b, c = 64500, 64501, 64502
+	plenty := map[int]int{a: 100, b: 100, c: 100}
+
+	tests := []struct {
+		name      string
+		allocator Allocator
+		available map[int]int
+		want      map[int]int
+	}{
+		{
+			name:      "equal split",
+			allocator: EqualAllocator{AllocationLimits{Budget: 30}},
+			available: plenty,
+			want:      map[int]int{a: 10, b: 10, c: 10},
+		},
+		{
+			name:      "budget smaller than the ASN count",
+			allocator: EqualAllocator{AllocationLimits{Budget: 2}},
+			available: plenty,
+			want:      map[int]int{a: 1, b: 1, c: 0},
+		},
+		{
+			name:      "remainder to the first ASNs",
+			allocator: EqualAllocator{AllocationLimits{Budget: 11}},
+			available: plenty,
+			want:      map[int]int{a: 4, b: 4, c: 3},
+		},
+		{
+			name:      "min per ASN above what an ASN has",
+			allocator: EqualAllocator{AllocationLimits{Budget: 14, MinPerASN: 5}},
+			available: map[int]int{a: 2, b: 100, c: 100},
+			want:      map[int]int{a: 2, b: 6, c: 6},
+		},
+		{
+			name:      "small ASN leaves its share to the others",
+			allocator: EqualAllocator{AllocationLimits{Budget: 20}},
+			available: map[int]int{a: 100, b: 2, c: 100},
+			want:      map[int]int{a: 9, b: 2, c: 9},
+		},
+		{
+			name:      "max per ASN redistributes leftovers",
+			allocator: EqualAllocator{AllocationLimits{Budget: 20, MaxPerASN: 8}},
+			available: map[int]int{a: 100, b: 3, c: 100},
+			want:      map[int]int{a: 8, b: 3, c: 8},
+		},
+		{
+			name:      "max per ASN below the budget share",
+			allocator: ProportionalAllocator{AllocationLimits{Budget: 30, MaxPerASN: 12}},
+			available: map[int]int{a: 80, b: 10, c: 10},
+			want:      map[int]int{a: 12, b: 9, c: 9},
+		},
+		{
+			name:      "budget above all available probes",
+			allocator: EqualAllocator{AllocationLimits{Budget: 1000}},
+			available: map[int]int{a: 5, b: 1, c: 7},
+			want:      map[int]int{a: 5, b: 1, c: 7},
+		},
+		{
+			name:      "proportional",
+			allocator: ProportionalAllocator{AllocationLimits{Budget: 20}},
+			available: map[int]int{a: 60, b: 30, c: 10},
+			want:      map[int]int{a: 12, b: 6, c: 2},
+		},
+		{
+			name:      "weighted with a missing weight",
+			allocator: WeightedAllocator{AllocationLimits: AllocationLimits{Budget: 20}, Weights: map[int]float64{a: 3}},
+			available: plenty,
+			want:      map[int]int{a: 12, b: 4, c: 4},
+		},
+		{
+			name:      "zero weight gets nothing",
+			allocator: WeightedAllocator{AllocationLimits: AllocationLimits{Budget: 10}, Weights: map[int]float64{a: 0, b: 1}},
+			available: plenty,
+			want:      map[int]int{a: 0, b: 5, c: 5},
+		},
+		{
+			name:      "zero weight gets its minimum",
+			allocator: WeightedAllocator{AllocationLimits: AllocationLimits{Budget: 10, MinPerASN: 2}, Weights: map[int]float64{a: 0, b: 1}},
+			available: plenty,
+			want:      map[int]int{a: 2, b: 4, c: 4},
+		},
+		{
+			name:      "all weights zero",
+			allocator: WeightedAllocator{AllocationLimits: AllocationLimits{Budget: 10, MinPerASN: 1}, Weights: map[int]float64{a: 0, b: 0, c: 0}},
+			available: plenty,
+			want:      map[int]int{a: 1, b: 1, c: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.allocator.Quotas([]int{a, b, c}, tt.available)
+			if err != nil {
+				t.Fatalf("Quotas: %v", err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("Quotas = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocatorMinimumExceedsBudget(t *testing.T) {
+	allocator := EqualAllocator{AllocationLimits{Budget: 5, MinPerASN: 2}}
+	if quotas, err := allocator.Quotas([]int{1, 2, 3}, map[int]int{1: 10, 2: 10, 3: 10}); err == nil {
+		t.Errorf("Quotas = %v, want an error for 3 × 2 probes over a budget of 5", quotas)
+	}
+}
+
+func TestNewAllocator(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  AllocationLimits
+		weights map[int]float64
+		want    string // DescribeAllocator, empty when an error is expected
+	}{
+		{name: "", want: "equal"},
+		{name: "proportional", limits: AllocationLimits{Budget: 500, MaxPerASN: 50}, want: "proportional (budget=500, max-per-asn=50)"},
+		{name: "weighted", weights: map[int]float64{1: 2}, want: "weighted"},
+		{name: "weighted"},
+		{name: "weighted", weights: map[int]float64{}},
+		{name: "random"},
+		{name: "equal", limits: AllocationLimits{Budget: MaxBudget + 1}},
+		{name: "equal", limits: AllocationLimits{MinPerASN: 10, MaxPerASN: 5}},
+		{name: "equal", limits: AllocationLimits{MaxPerASN: -1}},
+	}
+
+	for _, tt := range tests {
+		allocator, err := NewAllocator(tt.name, tt.limits, tt.weights, "")
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("NewAllocator(%q, %+v, %v) = %s, want an error", tt.name, tt.limits, tt.weights, DescribeAllocator(allocator))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewAllocator(%q, %+v): %v", tt.name, tt.limits, err)
+			continue
+		}
+		if got := DescribeAllocator(allocator); got != tt.want {
+			t.Errorf("NewAllocator(%q, %+v) = %s, want %s", tt.name, tt.limits, got, tt.want)
+		}
+	}
+}
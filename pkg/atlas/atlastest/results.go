@@ -0,0 +1,469 @@
+package atlastest
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// Demo ASNs registered by SeedDemo
+const (
+	DemoEyeballA = 64500
+	DemoEyeballB = 64501
+	DemoTransitA = 64510
+	DemoTransitB = 64511
+	DemoContent  = 64520
+)
+
+// SeedDemo registers a small, deterministic topology: probes in two eyeball
+// ASNs whose traceroutes all cross DemoTransitA, with odd probe IDs also
+// crossing DemoTransitB over IPv4 and probe IDs divisible by 3 crossing it
+// over IPv6. Every probe except 2004 is dual-stack; over IPv6 probe 1003 is
+// announced by DemoEyeballB. Host name targets resolve to DemoDNSAnswerV4 and
+// DemoDNSAnswerV6 in DemoContent. Probe 2003 is an anchor, see
+// annotateDemoProbes for the other probe metadata.
+func (s *Server) SeedDemo() {
+	connected := atlas.Status{ID: 1, Name: "Connected"}
+
+	s.AddProbes(
+		atlas.Probe{ID: 1001, AddressV4: "192.0.2.11", AddressV6: "2001:db8:a::11", ASNV4: DemoEyeballA, ASNV6: DemoEyeballA, CountryCode: "NL", Status: connected, IsPublic: true},
+		atlas.Probe{ID: 1002, AddressV4: "192.0.2.12", AddressV6: "2001:db8:a::12", ASNV4: DemoEyeballA, ASNV6: DemoEyeballA, CountryCode: "NL", Status: connected, IsPublic: true},
+		atlas.Probe{ID: 1003, AddressV4: "192.0.2.13", AddressV6: "2001:db8:b::13", ASNV4: DemoEyeballA, ASNV6: DemoEyeballB, CountryCode: "BE", Status: connected, IsPublic: true},
+		atlas.Probe{ID: 2001, AddressV4: "192.0.2.141", AddressV6: "2001:db8:b::141", ASNV4: DemoEyeballB, ASNV6: DemoEyeballB, CountryCode: "DE", Status: connected, IsPublic: true},
+		atlas.Probe{ID: 2002, AddressV4: "192.0.2.142", AddressV6: "2001:db8:b::142", ASNV4: DemoEyeballB, ASNV6: DemoEyeballB, CountryCode: "DE", Status: connected, IsPublic: true},
+		atlas.Probe{ID: 2003, AddressV4: "192.0.2.143", AddressV6: "2001:db8:b::143", ASNV4: DemoEyeballB, ASNV6: DemoEyeballB, CountryCode: "DE", Status: connected, IsPublic: true, IsAnchor: true},
+		atlas.Probe{ID: 2004, AddressV4: "192.0.2.144", ASNV4: DemoEyeballB, CountryCode: "AT", Status: connected, IsPublic: true},
+	)
+	s.annotateDemoProbes()
+
+	s.AddRoute("192.0.2.0/25", DemoEyeballA, "EYEBALL-A Example Broadband")
+	s.AddRoute("192.0.2.128/25", DemoEyeballB, "EYEBALL-B Example Telecom")
+	s.AddRoute("198.51.100.0/24", DemoTransitA, "TRANSIT-A Example Backbone")
+	s.AddRoute("203.0.113.0/24", DemoTransitB, "TRANSIT-B Example Carrier")
+	s.AddRoute("192.0.2.64/27", DemoContent, "CONTENT Example Hosting")
+
+	s.AddRoute("2001:db8:a::/48", DemoEyeballA, "")
+	s.AddRoute("2001:db8:b::/48", DemoEyeballB, "")
+	s.AddRoute("2001:db8:5100::/48", DemoTransitA, "")
+	s.AddRoute("2001:db8:7113::/48", DemoTransitB, "")
+	s.AddRoute("2001:db8::/48", DemoContent, "")
+}
+
+// SeedBulk registers n more IPv4 probes in DemoEyeballA, with IDs from 100001
+// and addresses in 198.18.0.0/15, for selections larger than
+// MaxProbesPerMeasurement
+func (s *Server) SeedBulk(n int) {
+	connected := atlas.Status{ID: 1, Name: "Connected"}
+	base := netip.MustParseAddr("198.18.0.0").As4()
+	start := binary.BigEndian.Uint32(base[:])
+
+	probes := make([]atlas.Probe, n)
+	for i := range probes {
+		var addr [4]byte
+		binary.BigEndian.PutUint32(addr[:], start+uint32(i)+1)
+		probes[i] = atlas.Probe{
+			ID:          100001 + i,
+			AddressV4:   netip.AddrFrom4(addr).String(),
+			ASNV4:       DemoEyeballA,
+			CountryCode: "NL",
+			Status:      connected,
+			IsPublic:    true,
+		}
+	}
+	s.AddProbes(probes...)
+}
+
+// demoLocations are the [longitude, latitude] of the demo probes: 1001 and
+// 1002 share a city, as do 2001 and 2002
+var demoLocations = map[int][2]float64{
+	1001: {4.90, 52.37}, // Amsterdam
+	1002: {4.89, 52.37},
+	1003: {4.35, 50.85},  // Brussels
+	2001: {13.40, 52.52}, // Berlin
+	2002: {13.41, 52.52},
+	2003: {11.58, 48.14}, // Munich
+	2004: {16.37, 48.21}, // Vienna
+}
+
+// annotateDemoProbes fills in the metadata probe filters and geographic
+// sampling look at: locations, prefixes, connectivity tags and uptime.
+// Probe 1002 is behind NAT and probe 2004 was connected only half the time.
+func (s *Server) annotateDemoProbes() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	firstConnected := time.Now().AddDate(-2, 0, 0).Unix()
+	age := time.Now().Unix() - firstConnected
+
+	for i := range s.probes {
+		p := &s.probes[i]
+		location, ok := demoLocations[p.ID]
+		if !ok {
+			continue
+		}
+
+		p.Geometry = &atlas.Geometry{Type: "Point", Coordinates: location[:]}
+		p.FirstConnected = firstConnected
+		p.TotalUptime = age * 99 / 100
+
+		p.PrefixV4 = "192.0.2.0/24"
+		p.Tags = append(p.Tags, atlas.ProbeTag{Name: "IPv4 Works", Slug: atlas.TagIPv4Works})
+		if p.AddressV6 != "" {
+			p.PrefixV6 = netip.MustParsePrefix(p.AddressV6 + "/48").Masked().String()
+			p.Tags = append(p.Tags, atlas.ProbeTag{Name: "IPv6 Works", Slug: atlas.TagIPv6Works})
+		}
+
+		switch p.ID {
+		case 1002:
+			p.Tags = append(p.Tags, atlas.ProbeTag{Name: "IPv4 RFC1918", Slug: atlas.TagIPv4RFC1918})
+		case 2004:
+			p.TotalUptime = age / 2
+		default:
+			p.Tags = append(p.Tags, atlas.ProbeTag{Name: "IPv4 Stable 90d", Slug: atlas.TagIPv4Stable90d})
+		}
+	}
+}
+
+// demoTransitHops are the transit hop addresses of demo traceroutes per address family
+var demoTransitHops = map[int][2]string{
+	4: {"198.51.100.1", "203.0.113.1"},
+	6: {"2001:db8:5100::1", "2001:db8:7113::1"},
+}
+
+// DefaultResult is the default ResultFunc. It dispatches on the definition
+// type to the generator for that type, falling back to TracerouteResult.
+func (s *Server) DefaultResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	switch def.Type {
+	case "ping":
+		return s.PingResult(def, msmID, probe)
+	case "dns":
+		return s.DNSResult(def, msmID, probe)
+	case "http":
+		return s.HTTPResult(def, msmID, probe)
+	case "sslcert":
+		return s.SSLCertResult(def, msmID, probe)
+	default:
+		return s.TracerouteResult(def, msmID, probe)
+	}
+}
+
+// TracerouteResult builds a traceroute result. It builds a path from the
+// probe's own address through DemoTransitA (and DemoTransitB, see SeedDemo)
+// to the measurement target, with RTTs derived from the probe ID. IPv6
+// definitions use the probe's IPv6 address and transit hops.
+func (s *Server) TracerouteResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	base := float64(probe.ID%50) / 10
+
+	af, src, dst := 4, probe.AddressV4, def.Target
+	viaB := probe.ID%2 == 1
+	if def.AF == 6 {
+		af, src = 6, probe.AddressV6
+		viaB = probe.ID%3 == 0
+	}
+	if _, err := netip.ParseAddr(dst); err != nil {
+		dst = DemoDNSAnswerV4.String()
+		if af == 6 {
+			dst = DemoDNSAnswerV6.String()
+		}
+	}
+	transit := demoTransitHops[af]
+
+	path := []string{src, transit[0]}
+	if viaB {
+		path = append(path, transit[1])
+	}
+	path = append(path, dst)
+
+	hops := make([]atlas.HopResult, len(path))
+	for i, from := range path {
+		rtt := base + float64(i+1)*4.2
+		hops[i] = atlas.HopResult{
+			Hop: i + 1,
+			Result: []atlas.HopReply{
+				{From: from, RTT: rtt, Size: 76, TTL: 255 - i},
+				{From: from, RTT: rtt + 0.3, Size: 76, TTL: 255 - i},
+				{From: from, RTT: rtt + 0.1, Size: 76, TTL: 255 - i},
+			},
+		}
+	}
+
+	return atlas.TracerouteResult{
+		ProbeID:   probe.ID,
+		MsmID:     msmID,
+		Timestamp: time.Now().Unix(),
+		From:      src,
+		Type:      "traceroute",
+		AF:        af,
+		Result:    hops,
+		DstName:   def.Target,
+		DstAddr:   dst,
+		SrcAddr:   src,
+	}
+}
+
+// PingResult builds a ping result with RTTs derived from the probe ID. Probes
+// whose ID is a multiple of 4 lose their last packet.
+func (s *Server) PingResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	packets := def.Packets
+	if packets == 0 {
+		packets = 3
+	}
+
+	base := 10 + float64(probe.ID%50)/2
+	replies := make([]atlas.PingReply, packets)
+	min, max, sum, rcvd := 0.0, 0.0, 0.0, 0
+	for i := range replies {
+		if probe.ID%4 == 0 && i == packets-1 {
+			replies[i] = atlas.PingReply{X: "*"}
+			continue
+		}
+		rtt := base + float64(i)*0.4
+		replies[i] = atlas.PingReply{RTT: rtt}
+		if rcvd == 0 || rtt < min {
+			min = rtt
+		}
+		if rtt > max {
+			max = rtt
+		}
+		sum += rtt
+		rcvd++
+	}
+
+	avg := -1.0
+	if rcvd > 0 {
+		avg = sum / float64(rcvd)
+	} else {
+		min, max = -1, -1
+	}
+
+	return atlas.PingResult{
+		ProbeID:   probe.ID,
+		MsmID:     msmID,
+		Timestamp: time.Now().Unix(),
+		From:      probe.AddressV4,
+		Type:      "ping",
+		AF:        4,
+		DstAddr:   def.Target,
+		SrcAddr:   probe.AddressV4,
+		Sent:      packets,
+		Rcvd:      rcvd,
+		Min:       min,
+		Avg:       avg,
+		Max:       max,
+		Result:    replies,
+	}
+}
+
+// Demo DNS answers returned by DNSResult
+var (
+	DemoDNSAnswerV4 = netip.MustParseAddr("192.0.2.80")
+	DemoDNSAnswerV6 = netip.MustParseAddr("2001:db8::80")
+	DemoDNSHijacked = netip.MustParseAddr("10.10.10.10")
+)
+
+// DNSResult builds a DNS result answering A and AAAA queries with
+// DemoDNSAnswerV4 and DemoDNSAnswerV6. Probes whose ID ends in 3 get
+// DemoDNSHijacked for A queries and probes whose ID ends in 4 time out.
+func (s *Server) DNSResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	resolver := def.Target
+	if def.UseProbeResolver {
+		resolver = "192.168.1.1"
+	}
+
+	set := atlas.DNSResultSet{
+		Time:    time.Now().Unix(),
+		AF:      4,
+		DstAddr: resolver,
+		SrcAddr: probe.AddressV4,
+		Proto:   def.Protocol,
+	}
+
+	switch probe.ID % 10 {
+	case 4:
+		set.Error = &atlas.DNSError{Timeout: 5000}
+	default:
+		var answers []netip.Addr
+		switch strings.ToUpper(def.QueryType) {
+		case "A":
+			answers = []netip.Addr{DemoDNSAnswerV4}
+			if probe.ID%10 == 3 {
+				answers = []netip.Addr{DemoDNSHijacked}
+			}
+		case "AAAA":
+			answers = []netip.Addr{DemoDNSAnswerV6}
+		}
+		buf := dnsMessage(uint16(probe.ID), def.QueryArgument, answers)
+		set.Result = &atlas.DNSResponse{
+			RT:      5 + float64(probe.ID%50)/4,
+			Size:    len(buf),
+			Abuf:    base64.StdEncoding.EncodeToString(buf),
+			ID:      probe.ID & 0xffff,
+			ANCount: len(answers),
+			QDCount: 1,
+		}
+	}
+
+	result := atlas.DNSResult{
+		ProbeID:   probe.ID,
+		MsmID:     msmID,
+		Timestamp: time.Now().Unix(),
+		From:      probe.AddressV4,
+		Type:      "dns",
+	}
+	if def.UseProbeResolver {
+		result.ResultSet = []atlas.DNSResultSet{set}
+	} else {
+		result.AF, result.DstAddr, result.SrcAddr, result.Proto = set.AF, set.DstAddr, set.SrcAddr, set.Proto
+		result.Result, result.Error = set.Result, set.Error
+	}
+	return result
+}
+
+// dnsMessage encodes a NOERROR response for name with one A or AAAA record
+// per address, using a compression pointer to the question name
+func dnsMessage(id uint16, name string, answers []netip.Addr) []byte {
+	qtype := uint16(1)
+	if len(answers) > 0 && answers[0].Is6() {
+		qtype = 28
+	}
+
+	buf := binary.BigEndian.AppendUint16(nil, id)
+	buf = binary.BigEndian.AppendUint16(buf, 0x8180) // response, RD, RA
+	buf = binary.BigEndian.AppendUint16(buf, 1)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(answers)))
+	buf = binary.BigEndian.AppendUint32(buf, 0)
+
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label != "" {
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, label...)
+		}
+	}
+	buf = append(buf, 0)
+	buf = binary.BigEndian.AppendUint16(buf, qtype)
+	buf = binary.BigEndian.AppendUint16(buf, 1)
+
+	for _, addr := range answers {
+		rdata := addr.AsSlice()
+		buf = append(buf, 0xc0, 12)
+		buf = binary.BigEndian.AppendUint16(buf, qtype)
+		buf = binary.BigEndian.AppendUint16(buf, 1)
+		buf = binary.BigEndian.AppendUint32(buf, 300)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(rdata)))
+		buf = append(buf, rdata...)
+	}
+
+	return buf
+}
+
+// HTTPResult builds an HTTP result with timings derived from the probe ID.
+// Probes whose ID ends in 3 get a 503 and probes whose ID ends in 4 fail to
+// connect.
+func (s *Server) HTTPResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	scheme := "http"
+	if def.HTTPS {
+		scheme = "https"
+	}
+
+	resp := atlas.HTTPResponse{
+		AF:      4,
+		DstAddr: "192.0.2.80",
+		SrcAddr: probe.AddressV4,
+		Method:  def.Method,
+		Ver:     "1.1",
+	}
+
+	switch probe.ID % 10 {
+	case 4:
+		resp.Err = "connect: timeout"
+	default:
+		resp.Res = 200
+		if probe.ID%10 == 3 {
+			resp.Res = 503
+		}
+		resp.TTC = 10 + float64(probe.ID%50)/5
+		resp.TTFB = resp.TTC + 15
+		resp.RT = resp.TTFB + 5
+		resp.HSize = 250
+		resp.BSize = 1024
+	}
+
+	return atlas.HTTPResult{
+		ProbeID:   probe.ID,
+		MsmID:     msmID,
+		Timestamp: time.Now().Unix(),
+		From:      probe.AddressV4,
+		Type:      "http",
+		URI:       scheme + "://" + def.Target + def.Path,
+		Result:    []atlas.HTTPResponse{resp},
+	}
+}
+
+// Demo certificates served by SSLCertResult
+const (
+	DemoCertFingerprint = "BC:40:44:F2:06:6A:A8:52:9A:3D:CA:F7:45:34:76:68:19:5F:1B:23:51:40:7D:C7:A7:C7:CC:B3:10:14:AD:38"
+
+	demoCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBcTCCARigAwIBAgIBATAKBggqhkjOPQQDAjAXMRUwEwYDVQQDDAxkZW1vLmV4
+YW1wbGUwIBcNMjYxMDE2MjAzMjA3WhgPMjEyNjA5MjIyMDMyMDdaMBcxFTATBgNV
+BAMMDGRlbW8uZXhhbXBsZTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABB4hWgRp
+R08P4rR7xZsOa0fKscPAdVq5Q4C0wHtC+szjijbAqMYFc17+pytncT5mMd7e4nHv
+lNspoC+qGcnTHSWjUzBRMB0GA1UdDgQWBBRXCXDWebYlXx3ku5dOQFSsX6qtsTAf
+BgNVHSMEGDAWgBRXCXDWebYlXx3ku5dOQFSsX6qtsTAPBgNVHRMBAf8EBTADAQH/
+MAoGCCqGSM49BAMCA0cAMEQCIH649fSIJ9NAL1eVxGxShyybSkLHKWgLMuTVFxJ5
+WwL8AiAAz4khW7J/n0zf+gKHw7gBtv5SDyvp9LExACdHgRprQA==
+-----END CERTIFICATE-----
+`
+
+	demoInterceptCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBfDCCASKgAwIBAgIBAjAKBggqhkjOPQQDAjAcMRowGAYDVQQDDBFpbnRlcmNl
+cHQuZXhhbXBsZTAgFw0yNjEwMTYyMDMyMDdaGA8yMTI2MDkyMjIwMzIwN1owHDEa
+MBgGA1UEAwwRaW50ZXJjZXB0LmV4YW1wbGUwWTATBgcqhkjOPQIBBggqhkjOPQMB
+BwNCAATcVD25GDysUMPtea3zjwizZIv1QXmvwBB34bNnFRXep+snOzVEAYESfk2Q
+En2zoXE3uHGWYswkVOVi9q7D5c9do1MwUTAdBgNVHQ4EFgQUnhttfWQi91xY6ZpT
+bAbVJ7hu2eowHwYDVR0jBBgwFoAUnhttfWQi91xY6ZpTbAbVJ7hu2eowDwYDVR0T
+AQH/BAUwAwEB/zAKBggqhkjOPQQDAgNIADBFAiEA3/6OJ9t/TlZcI3h+9ofZoM+z
+nNE+Vb5gIpKM8dcCmWECICZw8VxBbPUVi+H918kr6E4ULPpTr6k7apJva5x08ukP
+-----END CERTIFICATE-----
+`
+)
+
+// SSLCertResult builds an SSL certificate result serving a self-signed
+// certificate with DemoCertFingerprint. Probes whose ID ends in 3 are served
+// a different certificate, as behind a TLS interception proxy, and probes
+// whose ID ends in 4 fail to connect.
+func (s *Server) SSLCertResult(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any {
+	result := atlas.SSLCertResult{
+		ProbeID:   probe.ID,
+		MsmID:     msmID,
+		Timestamp: time.Now().Unix(),
+		From:      probe.AddressV4,
+		Type:      "sslcert",
+		AF:        4,
+		DstName:   def.Target,
+		DstAddr:   "192.0.2.80",
+		DstPort:   strconv.Itoa(def.Port),
+		SrcAddr:   probe.AddressV4,
+		Method:    "TLS",
+	}
+
+	switch probe.ID % 10 {
+	case 4:
+		result.Err = "connect: timeout"
+	case 3:
+		result.Ver = "1.3"
+		result.RT = 30 + float64(probe.ID%50)/5
+		result.Cert = []string{demoInterceptCertPEM}
+	default:
+		result.Ver = "1.3"
+		result.RT = 30 + float64(probe.ID%50)/5
+		result.Cert = []string{demoCertPEM}
+	}
+
+	return result
+}
@@ -0,0 +1,717 @@
+// Package atlastest provides a hermetic fake of the RIPE Atlas and RIPEstat
+// APIs for running the CLI and its packages without network access.
+//
+// Atlas endpoints are served below URL() and RIPEstat endpoints below
+// StatURL(), so a client is wired up with:
+//
+//	srv := atlastest.NewServer()
+//	defer srv.Close()
+//	client := atlas.NewClient("key", atlas.WithBaseURL(srv.URL()))
+//	analyzer.SetBaseURL(srv.StatURL())
+package atlastest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+const (
+	defaultPageSize   = 100
+	apiPrefix         = "/api/v2"
+	statPrefix        = "/data"
+	measurementsRoute = apiPrefix + "/measurements/"
+)
+
+var statusNames = map[int]string{
+	atlas.MeasurementScheduled:        "Scheduled",
+	atlas.MeasurementOngoing:          "Ongoing",
+	atlas.MeasurementStopped:          "Stopped",
+	atlas.MeasurementForcedStop:       "Forced to stop",
+	atlas.MeasurementNoSuitableProbes: "No suitable probes",
+	atlas.MeasurementFailed:           "Failed",
+}
+
+// ResultFunc produces the result a probe reports for a measurement definition
+type ResultFunc func(def atlas.MeasurementDefinition, msmID int, probe atlas.Probe) any
+
+// Server is a fake Atlas + RIPEstat API backed by an httptest.Server.
+//
+// Measurements move from Scheduled to Ongoing after PollsPerStage status
+// requests, then deliver ResultsPerPoll more results on every status
+// request until all probes have reported and the status becomes Stopped.
+type Server struct {
+	*httptest.Server
+
+	// APIKey, when set, must be sent with requests that create or stop measurements
+	APIKey string
+
+	// PollsPerStage is the number of status polls a measurement stays Scheduled
+	PollsPerStage int
+
+	// ResultsPerPoll is the number of results delivered per status poll while Ongoing
+	ResultsPerPoll int
+
+	// Result generates per-probe results, defaults to DefaultResult
+	Result ResultFunc
+
+	// KeyPermissions are the grants reported for the API key, all known
+	// permissions by default
+	KeyPermissions []string
+
+	// KeyValidTo, when set, is when the API key expires
+	KeyValidTo *time.Time
+
+	// Credits is the balance reported by the credits endpoint
+	Credits int64
+
+	// CreateHook, when set, sees every valid create request before it is
+	// accepted. An error rejects the request with 400 and the error as detail.
+	CreateHook func(req atlas.MeasurementRequest) error
+
+	mu           sync.Mutex
+	probes       []atlas.Probe
+	prefixes     []route
+	holders      map[int]string
+	measurements map[int]*measurement
+	nextID       int
+}
+
+// route maps a prefix to its origin ASN for prefix-overview lookups
+type route struct {
+	prefix netip.Prefix
+	asn    int
+}
+
+// measurement is the server-side state of a created measurement
+type measurement struct {
+	status    atlas.MeasurementStatus
+	def       atlas.MeasurementDefinition
+	probes    []atlas.Probe
+	polls     int
+	delivered int
+	results   []any
+}
+
+// NewServer starts a fake server with no probes or routes
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a fake server that is not yet listening, so the
+// caller can change its Listener before calling Start
+func NewUnstartedServer() *Server {
+	s := &Server{
+		PollsPerStage:  1,
+		ResultsPerPoll: 5,
+		holders:        make(map[int]string),
+		measurements:   make(map[int]*measurement),
+		nextID:         1000000,
+		KeyPermissions: []string{
+			atlas.PermissionScheduleMeasurement,
+			atlas.PermissionStopMeasurement,
+			atlas.PermissionViewResults,
+			atlas.PermissionViewCredits,
+		},
+		Credits: 1000000,
+	}
+	s.Result = s.DefaultResult
+	s.Server = httptest.NewUnstartedServer(s.handler())
+	return s
+}
+
+// URL returns the Atlas API root of the fake server
+func (s *Server) URL() string {
+	return s.Server.URL + apiPrefix
+}
+
+// StatURL returns the RIPEstat data API root of the fake server
+func (s *Server) StatURL() string {
+	return s.Server.URL + statPrefix
+}
+
+// AddProbes registers probes returned by probe searches
+func (s *Server) AddProbes(probes ...atlas.Probe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes = append(s.probes, probes...)
+}
+
+// AddRoute registers a prefix originated by asn for prefix-overview lookups,
+// holder is returned by both prefix-overview and as-overview
+func (s *Server) AddRoute(prefix string, asn int, holder string) error {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return fmt.Errorf("invalid prefix %s: %w", prefix, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefixes = append(s.prefixes, route{prefix: p.Masked(), asn: asn})
+	if holder != "" {
+		s.holders[asn] = holder
+	}
+	return nil
+}
+
+// Measurement returns the current status of a measurement created on the server
+func (s *Server) Measurement(id int) (atlas.MeasurementStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.measurements[id]
+	if !ok {
+		return atlas.MeasurementStatus{}, false
+	}
+	return m.status, true
+}
+
+// handler routes requests to the Atlas and RIPEstat handlers
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPrefix+"/probes/", s.handleProbes)
+	mux.HandleFunc("POST "+measurementsRoute, s.handleCreate)
+	mux.HandleFunc("GET "+measurementsRoute+"{id}/", s.handleStatus)
+	mux.HandleFunc("DELETE "+measurementsRoute+"{id}/", s.handleStop)
+	mux.HandleFunc("GET "+measurementsRoute+"{id}/results/", s.handleResults)
+	mux.HandleFunc("GET "+apiPrefix+"/keys/{uuid}/", s.handleKey)
+	mux.HandleFunc("GET "+apiPrefix+"/credits/", s.handleCredits)
+	mux.HandleFunc("GET "+statPrefix+"/prefix-overview/data.json", s.handlePrefixOverview)
+	mux.HandleFunc("GET "+statPrefix+"/as-overview/data.json", s.handleASOverview)
+	return mux
+}
+
+// handleProbes serves paginated probe searches
+func (s *Server) handleProbes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	var matched []atlas.Probe
+	for _, probe := range s.probes {
+		if matchProbe(probe, query) {
+			matched = append(matched, probe)
+		}
+	}
+	s.mu.Unlock()
+
+	writePage(w, r, matched)
+}
+
+// matchProbe applies the probe search filters understood by the fake
+func matchProbe(probe atlas.Probe, query url.Values) bool {
+	if status := query.Get("status"); status != "" && strconv.Itoa(probe.Status.ID) != status {
+		return false
+	}
+	if !inList(query.Get("asn_v4__in"), probe.ASNV4) {
+		return false
+	}
+	if !inList(query.Get("asn_v6__in"), probe.ASNV6) {
+		return false
+	}
+	if !inList(query.Get("id__in"), probe.ID) {
+		return false
+	}
+	for _, tag := range strings.Split(query.Get("tags"), ",") {
+		if tag != "" && !hasTag(probe, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// inList reports whether v is in the comma-separated list, an empty list matches everything
+func inList(list string, v int) bool {
+	if list == "" {
+		return true
+	}
+	return slices.Contains(strings.Split(list, ","), strconv.Itoa(v))
+}
+
+// handleCreate creates one measurement per definition
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	var req atlas.MeasurementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON parse error - "+err.Error())
+		return
+	}
+	if len(req.Definitions) == 0 || len(req.Probes) == 0 {
+		writeError(w, http.StatusBadRequest, "definitions and probes are required")
+		return
+	}
+
+	if n := req.RequestedProbes(); n > atlas.MaxProbesPerMeasurement {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%d probes requested, the maximum is %d", n, atlas.MaxProbesPerMeasurement))
+		return
+	}
+
+	if s.CreateHook != nil {
+		if err := s.CreateHook(req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if active := s.activeMeasurements(); active+len(req.Definitions) > atlas.MaxConcurrentMeasurements {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%d measurements are running, the limit is %d concurrent measurements", active, atlas.MaxConcurrentMeasurements))
+		return
+	}
+
+	probes := s.selectProbes(req.Probes)
+
+	resp := atlas.MeasurementResponse{}
+	for _, def := range req.Definitions {
+		s.nextID++
+		m := &measurement{
+			def:    def,
+			probes: probes,
+			status: atlas.MeasurementStatus{
+				ID:              s.nextID,
+				Type:            def.Type,
+				AF:              def.AF,
+				Target:          def.Target,
+				TargetIP:        def.Target,
+				Description:     def.Description,
+				IsOneoff:        req.IsOneoff,
+				IsPublic:        true,
+				ProbesRequested: len(probes),
+				ProbesScheduled: len(probes),
+				CreationTime:    time.Now().Unix(),
+				StartTime:       time.Now().Unix(),
+			},
+		}
+		m.setStatus(atlas.MeasurementScheduled)
+		if len(probes) == 0 {
+			m.setStatus(atlas.MeasurementNoSuitableProbes)
+		}
+
+		s.measurements[s.nextID] = m
+		resp.Measurements = append(resp.Measurements, s.nextID)
+	}
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// activeMeasurements counts the measurements that have not finished yet.
+// The caller holds s.mu.
+func (s *Server) activeMeasurements() int {
+	active := 0
+	for _, m := range s.measurements {
+		switch m.status.Status.ID {
+		case atlas.MeasurementSpecified, atlas.MeasurementScheduled, atlas.MeasurementOngoing:
+			active++
+		}
+	}
+	return active
+}
+
+// selectProbes resolves probe sets against the registered probes, each
+// probe used at most once. The caller holds s.mu.
+func (s *Server) selectProbes(sets []atlas.ProbeSet) []atlas.Probe {
+	var selected []atlas.Probe
+	seen := make(map[int]bool)
+
+	for _, set := range sets {
+		var candidates []atlas.Probe
+		for _, probe := range s.probes {
+			if probe.Status.ID != 1 || seen[probe.ID] || !matchTags(probe, set.Tags) {
+				continue
+			}
+			if s.inProbeSet(set, probe) {
+				candidates = append(candidates, probe)
+			}
+		}
+
+		if set.Requested > 0 && len(candidates) > set.Requested {
+			candidates = candidates[:set.Requested]
+		}
+		for _, probe := range candidates {
+			seen[probe.ID] = true
+		}
+		selected = append(selected, candidates...)
+	}
+	return selected
+}
+
+// inProbeSet reports whether the probe belongs to the probe set
+func (s *Server) inProbeSet(set atlas.ProbeSet, probe atlas.Probe) bool {
+	switch set.Type {
+	case "probes":
+		return inList(set.Value, probe.ID)
+	case atlas.ProbeSetASN:
+		return inList(set.Value, probe.ASNV4) || inList(set.Value, probe.ASNV6)
+	case atlas.ProbeSetCountry:
+		return strings.EqualFold(set.Value, probe.CountryCode)
+	case atlas.ProbeSetPrefix:
+		prefix, err := netip.ParsePrefix(set.Value)
+		if err != nil {
+			return false
+		}
+		for _, address := range []string{probe.AddressV4, probe.AddressV6} {
+			if addr, err := netip.ParseAddr(address); err == nil && prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	case atlas.ProbeSetMsm:
+		id, _ := strconv.Atoi(set.Value)
+		m, ok := s.measurements[id]
+		return ok && slices.ContainsFunc(m.probes, func(p atlas.Probe) bool { return p.ID == probe.ID })
+	}
+	// The fake has no geography, every probe is in every area
+	return true
+}
+
+// matchTags applies a probe set's tag filter
+func matchTags(probe atlas.Probe, tags *atlas.ProbeTags) bool {
+	if tags == nil {
+		return true
+	}
+	for _, tag := range tags.Include {
+		if !hasTag(probe, tag) {
+			return false
+		}
+	}
+	for _, tag := range tags.Exclude {
+		if hasTag(probe, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag reports whether the probe carries a tag. The IPv4 and IPv6
+// connectivity tags are also derived from the addresses of probes registered
+// without tags.
+func hasTag(probe atlas.Probe, tag string) bool {
+	if probe.HasTag(tag) {
+		return true
+	}
+	switch tag {
+	case atlas.TagIPv4Works:
+		return probe.AddressV4 != ""
+	case atlas.TagIPv6Works:
+		return probe.AddressV6 != ""
+	}
+	return false
+}
+
+// handleList serves the paginated list of measurements created on the server
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+
+	s.mu.Lock()
+	var matched []atlas.MeasurementStatus
+	for _, m := range s.measurements {
+		if !inList(query.Get("status__in"), m.status.Status.ID) {
+			continue
+		}
+		if t := query.Get("type"); t != "" && t != m.status.Type {
+			continue
+		}
+		if t := query.Get("target"); t != "" && t != m.status.Target {
+			continue
+		}
+		if !hasAllTags(m.status.Tags, query.Get("tags")) {
+			continue
+		}
+		matched = append(matched, m.status)
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(matched, func(a, b atlas.MeasurementStatus) int { return b.ID - a.ID })
+	writePage(w, r, matched)
+}
+
+// handleStatus reports the measurement status and advances its simulated progress
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	// /measurements/my/ shares the {id} pattern
+	if r.PathValue("id") == "my" {
+		s.handleList(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	s.advance(m)
+	writeJSON(w, http.StatusOK, m.status)
+}
+
+// advance moves a measurement one poll further through its lifecycle
+func (s *Server) advance(m *measurement) {
+	m.polls++
+
+	switch m.status.Status.ID {
+	case atlas.MeasurementScheduled:
+		if m.polls >= s.PollsPerStage {
+			m.setStatus(atlas.MeasurementOngoing)
+		}
+
+	case atlas.MeasurementOngoing:
+		for i := 0; i < s.ResultsPerPoll && m.delivered < len(m.probes); i++ {
+			m.results = append(m.results, s.Result(m.def, m.status.ID, m.probes[m.delivered]))
+			m.delivered++
+		}
+		m.status.ParticipantCount = m.delivered
+
+		if m.delivered == len(m.probes) {
+			m.setStatus(atlas.MeasurementStopped)
+			m.status.StopTime = time.Now().Unix()
+		}
+	}
+}
+
+// hasAllTags reports whether tags holds every entry of the comma-separated list
+func hasAllTags(tags []string, list string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		if tag != "" && !slices.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleStop forces a measurement to stop, keeping the results delivered so
+// far. Measurements that have already finished are rejected with 400.
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	switch m.status.Status.ID {
+	case atlas.MeasurementSpecified, atlas.MeasurementScheduled, atlas.MeasurementOngoing:
+	default:
+		writeError(w, http.StatusBadRequest, "This measurement has already stopped.")
+		return
+	}
+
+	m.setStatus(atlas.MeasurementForcedStop)
+	m.status.StopTime = time.Now().Unix()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResults returns the results delivered so far
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	results := m.results
+	if results == nil {
+		results = []any{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// lookup finds the measurement named in the request path, writing a 404 if missing
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*measurement, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return nil, false
+	}
+
+	m, ok := s.measurements[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return nil, false
+	}
+	return m, true
+}
+
+// authorized checks the API key on write requests, writing a 403 if it does not match
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if s.APIKey == "" || r.Header.Get("Authorization") == "Key "+s.APIKey {
+		return true
+	}
+	writeError(w, http.StatusForbidden, "Authentication credentials were not provided.")
+	return false
+}
+
+// handleKey describes the API key, which must authenticate the request itself.
+// Any key is accepted when the server has no APIKey.
+func (s *Server) handleKey(w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("uuid")
+	if (s.APIKey != "" && uuid != s.APIKey) || r.Header.Get("Authorization") != "Key "+uuid {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	grants := make([]atlas.KeyGrant, len(s.KeyPermissions))
+	for i, permission := range s.KeyPermissions {
+		grants[i].Permission = permission
+	}
+
+	validFrom := time.Now().AddDate(0, -1, 0).UTC()
+	writeJSON(w, http.StatusOK, atlas.APIKey{
+		UUID:      uuid,
+		Label:     "atlastest",
+		Enabled:   true,
+		IsActive:  true,
+		ValidFrom: &validFrom,
+		ValidTo:   s.KeyValidTo,
+		Grants:    grants,
+	})
+}
+
+// handleCredits reports the credit balance
+func (s *Server) handleCredits(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, atlas.Credits{
+		CurrentBalance:            s.Credits,
+		EstimatedDailyIncome:      21600,
+		EstimatedDailyExpenditure: 3000,
+	})
+}
+
+// handlePrefixOverview answers RIPEstat prefix-overview lookups for IP resources
+func (s *Server) handlePrefixOverview(w http.ResponseWriter, r *http.Request) {
+	type asnEntry struct {
+		ASN    int    `json:"asn"`
+		Holder string `json:"holder"`
+	}
+
+	var resp struct {
+		Data struct {
+			ASNs []asnEntry `json:"asns"`
+		} `json:"data"`
+	}
+	resp.Data.ASNs = []asnEntry{}
+
+	if addr, err := netip.ParseAddr(r.URL.Query().Get("resource")); err == nil {
+		s.mu.Lock()
+		best := -1
+		for i, rt := range s.prefixes {
+			if rt.prefix.Contains(addr) && (best < 0 || rt.prefix.Bits() > s.prefixes[best].prefix.Bits()) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			asn := s.prefixes[best].asn
+			resp.Data.ASNs = append(resp.Data.ASNs, asnEntry{ASN: asn, Holder: s.holders[asn]})
+		}
+		s.mu.Unlock()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleASOverview answers RIPEstat as-overview lookups
+func (s *Server) handleASOverview(w http.ResponseWriter, r *http.Request) {
+	var resp struct {
+		Data struct {
+			Holder string `json:"holder"`
+		} `json:"data"`
+	}
+
+	resource := strings.TrimPrefix(strings.ToUpper(r.URL.Query().Get("resource")), "AS")
+	if asn, err := strconv.Atoi(resource); err == nil {
+		s.mu.Lock()
+		resp.Data.Holder = s.holders[asn]
+		s.mu.Unlock()
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// setStatus updates the status ID and its display name
+func (m *measurement) setStatus(id int) {
+	m.status.Status = atlas.StatusInfo{ID: id, Name: statusNames[id]}
+}
+
+// writePage writes the page of items selected by the page and page_size parameters
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	query := r.URL.Query()
+	pageSize := intParam(query, "page_size", defaultPageSize)
+	pageNum := intParam(query, "page", 1)
+
+	start := min((pageNum-1)*pageSize, len(items))
+	end := min(start+pageSize, len(items))
+
+	page := atlas.Page[T]{
+		Count:   len(items),
+		Results: items[start:end],
+	}
+	if page.Results == nil {
+		page.Results = []T{}
+	}
+	if end < len(items) {
+		next := *r.URL
+		next.Scheme, next.Host = "http", r.Host
+		q := next.Query()
+		q.Set("page", strconv.Itoa(pageNum+1))
+		next.RawQuery = q.Encode()
+		nextURL := next.String()
+		page.Next = &nextURL
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// intParam reads a positive integer query parameter, falling back to def
+func intParam(query url.Values, key string, def int) int {
+	v, err := strconv.Atoi(query.Get(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error body in the Atlas error format
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"status": status,
+			"title":  http.StatusText(status),
+			"detail": detail,
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package atlas
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BatchReport combines the traceroute reports of a batch of targets measured
+// from the same probes
+type BatchReport struct {
+	Reports []Report
+
+	// Failed lists the targets without results
+	Failed []BatchFailure
+}
+
+// BatchFailure is a target of a batch without results and the reason
+type BatchFailure struct {
+	Target string
+	Reason string
+}
+
+// GenerateBatchReport creates the combined matrix of a batch: one row per
+// target with its path statistics and the share of probes whose path crossed
+// each common ASN. An ASN is a column when it met the threshold for at least
+// one target; "-" marks targets where it did not.
+func GenerateBatchReport(batch BatchReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas Batch Traceroute Summary")
+
+	var asns []int
+	for _, report := range batch.Reports {
+		for _, info := range report.CommonASNs {
+			if !slices.Contains(asns, info.ASN) {
+				asns = append(asns, info.ASN)
+			}
+		}
+	}
+	slices.Sort(asns)
+
+	sb.WriteString(fmt.Sprintf("Targets: %d", len(batch.Reports)+len(batch.Failed)))
+	if len(batch.Failed) > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d without results)", len(batch.Failed)))
+	}
+	sb.WriteString("\n\n")
+
+	sb.WriteString(fmt.Sprintf("  %-24s %7s %6s %8s %10s", "Target", "Results", "Paths", "Avg hops", "Incomplete"))
+	for _, asn := range asns {
+		sb.WriteString(fmt.Sprintf(" %9s", fmt.Sprintf("AS%d", asn)))
+	}
+	sb.WriteString("\n")
+
+	for _, report := range batch.Reports {
+		sb.WriteString(fmt.Sprintf("  %-24s %7d %6d %8.1f %10d",
+			truncate(report.Target, 24), report.ResultCount, report.UniquePaths, report.AvgHops, report.IncompletePaths))
+
+		for _, asn := range asns {
+			i := slices.IndexFunc(report.CommonASNs, func(info ASNInfo) bool { return info.ASN == asn })
+			if i < 0 {
+				sb.WriteString(fmt.Sprintf(" %9s", "-"))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf(" %8.1f%%", report.CommonASNs[i].Percentage))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(batch.Failed) > 0 {
+		sb.WriteString("\n  Without results:\n")
+		for _, failure := range batch.Failed {
+			sb.WriteString(fmt.Sprintf("    %s: %s\n", failure.Target, failure.Reason))
+		}
+	}
+
+	if len(batch.Reports) > 0 {
+		sb.WriteString(fmt.Sprintf("\n  Common ASN threshold: %.1f%% of probes\n", batch.Reports[0].Threshold*100))
+	}
+	sb.WriteString("\n" + Separator + "\n")
+
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package atlas
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Certificate is a decoded certificate from an SSL certificate result
+type Certificate struct {
+	Fingerprint string // SHA-256 of the DER encoding, colon separated hex
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+}
+
+// Chain decodes the certificate chain served to the probe, leaf first
+func (r SSLCertResult) Chain() ([]Certificate, error) {
+	chain := make([]Certificate, 0, len(r.Cert))
+
+	for i, block := range r.Cert {
+		der, _ := pem.Decode([]byte(block))
+		if der == nil {
+			return nil, fmt.Errorf("certificate %d: not PEM encoded", i)
+		}
+
+		cert, err := x509.ParseCertificate(der.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", i, err)
+		}
+
+		sum := sha256.Sum256(der.Bytes)
+		chain = append(chain, Certificate{
+			Fingerprint: FormatFingerprint(sum[:]),
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+			NotAfter:    cert.NotAfter,
+		})
+	}
+
+	return chain, nil
+}
+
+// FormatFingerprint renders a digest as upper-case, colon separated hex
+func FormatFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParseFingerprint normalises a SHA-256 fingerprint given as hex with or
+// without colons, in any case
+func ParseFingerprint(s string) (string, error) {
+	clean := strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(s))
+	sum, err := hex.DecodeString(clean)
+	if err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 fingerprint: %s", s)
+	}
+	return FormatFingerprint(sum), nil
+}
@@ -2,180 +2,439 @@ package atlas
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/httpretry"
 )
 
 const (
 	BaseURL = "https://atlas.ripe.net/api/v2"
+
+	// DefaultPollInterval is how often WaitForMeasurement checks on a measurement
+	DefaultPollInterval = 3 * time.Second
+
+	// DefaultPageSize is the page size requested from paginated list endpoints
+	DefaultPageSize = 500
+
+	// MaxASNsPerQuery caps the number of ASNs sent in a single probe search,
+	// longer lists are split into several queries to keep URLs short
+	MaxASNsPerQuery = 50
+
+	// MaxProbeIDsPerQuery caps the number of probe IDs sent in a single probe lookup
+	MaxProbeIDsPerQuery = 200
+
+	// TagIPv6Works is the system tag of probes whose IPv6 connectivity works
+	TagIPv6Works = "system-ipv6-works"
+
+	// maxParallelQueries limits how many probe searches run at the same time
+	maxParallelQueries = 4
 )
 
 // Client is the RIPE Atlas API client
 type Client struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey       string
+	readKey      string
+	baseURL      string
+	pageSize     int
+	pollInterval time.Duration
+	httpClient   *httpretry.Client
+}
+
+// ClientOption configures optional Client behaviour
+type ClientOption func(*Client)
+
+// WithPageSize sets the page size used for paginated list requests
+func WithPageSize(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.pageSize = n
+		}
+	}
+}
+
+// WithBaseURL points the client at a different API root, e.g. a test server
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimSuffix(baseURL, "/")
+		}
+	}
+}
+
+// WithPollInterval sets how often WaitForMeasurement checks on a measurement
+func WithPollInterval(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if d > 0 {
+			c.pollInterval = d
+		}
+	}
+}
+
+// WithReadKey sets a separate API key for requests that only read data,
+// such as probe searches and result downloads
+func WithReadKey(key string) ClientOption {
+	return func(c *Client) {
+		c.readKey = key
+	}
+}
+
+// WithRetryPolicy sets how transient API failures (5xx, 429, network errors) are retried
+func WithRetryPolicy(policy httpretry.Policy) ClientOption {
+	return func(c *Client) {
+		c.httpClient.SetPolicy(policy)
+	}
 }
 
 // NewClient creates a new RIPE Atlas API client
-func NewClient(apiKey string) *Client {
-	return &Client{
-		apiKey: apiKey,
-		httpClient: &http.Client{
+func NewClient(apiKey string, opts ...ClientOption) *Client {
+	c := &Client{
+		apiKey:       apiKey,
+		baseURL:      BaseURL,
+		pageSize:     DefaultPageSize,
+		pollInterval: DefaultPollInterval,
+		httpClient: httpretry.New(&http.Client{
 			Timeout: 30 * time.Second,
-		},
+		}, httpretry.DefaultPolicy()),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
-// GetProbesByASN retrieves probes for given ASNs
-func (c *Client) GetProbesByASN(asns []int) (map[int][]Probe, error) {
-	// Build ASN query parameter
-	asnParam := ""
-	for i, asn := range asns {
-		if i > 0 {
-			asnParam += ","
-		}
-		asnParam += fmt.Sprintf("%d", asn)
+// ListProbes returns an iterator over all probes matching the given query
+// parameters, following the API's next links page by page
+func (c *Client) ListProbes(ctx context.Context, params url.Values) *ProbeIterator {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
 	}
+	query.Set("page_size", strconv.Itoa(c.pageSize))
 
-	url := fmt.Sprintf("%s/probes/?status=1&asn_v4__in=%s", BaseURL, asnParam)
+	return &ProbeIterator{pageIterator[Probe]{
+		ctx:    ctx,
+		client: c,
+		next:   fmt.Sprintf("%s/probes/?%s", c.baseURL, query.Encode()),
+	}}
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// GetProbesByASN retrieves connected probes for given ASNs, keyed by their
+// ASN in address family af (4 or 6).
+// ASN lists longer than MaxASNsPerQuery are split into several searches run in parallel.
+func (c *Client) GetProbesByASN(ctx context.Context, asns []int, af int) (map[int][]Probe, error) {
+	var (
+		mu          sync.Mutex
+		wg          sync.WaitGroup
+		firstErr    error
+		probesByASN = make(map[int][]Probe)
+		sem         = make(chan struct{}, maxParallelQueries)
+	)
+
+	for start := 0; start < len(asns); start += MaxASNsPerQuery {
+		chunk := asns[start:min(start+MaxASNsPerQuery, len(asns))]
+
+		wg.Add(1)
+		go func(chunk []int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			probes, err := c.searchProbesByASN(ctx, chunk, af)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+
+			// Group probes by the ASN of the measured address family
+			for _, probe := range probes {
+				asn := probe.ASNV4
+				if af == 6 {
+					asn = probe.ASNV6
+				}
+				if asn > 0 {
+					probesByASN[asn] = append(probesByASN[asn], probe)
+				}
+			}
+		}(chunk)
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	return probesByASN, nil
+}
+
+// searchProbesByASN collects every page of connected probes for a single ASN
+// chunk. For IPv6 probes are matched on their v6 ASN and must have working IPv6.
+func (c *Client) searchProbesByASN(ctx context.Context, asns []int, af int) ([]Probe, error) {
+	asnParam := make([]string, len(asns))
+	for i, asn := range asns {
+		asnParam[i] = strconv.Itoa(asn)
 	}
 
-	var probeResp ProbeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&probeResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	params := url.Values{}
+	params.Set("status", "1")
+	if af == 6 {
+		params.Set("asn_v6__in", strings.Join(asnParam, ","))
+		params.Set("tags", TagIPv6Works)
+	} else {
+		params.Set("asn_v4__in", strings.Join(asnParam, ","))
+	}
+
+	var probes []Probe
+	it := c.ListProbes(ctx, params)
+	for it.Next() {
+		probes = append(probes, it.Probe())
 	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return probes, nil
+}
+
+// GetProbesByID retrieves probes by ID regardless of their connection status,
+// keyed by probe ID. Unknown IDs are silently absent from the result.
+func (c *Client) GetProbesByID(ctx context.Context, ids []int) (map[int]Probe, error) {
+	probes := make(map[int]Probe, len(ids))
 
-	// Group probes by ASN
-	probesByASN := make(map[int][]Probe)
-	for _, probe := range probeResp.Results {
-		asn := probe.ASNV4
-		if asn > 0 {
-			probesByASN[asn] = append(probesByASN[asn], probe)
+	for start := 0; start < len(ids); start += MaxProbeIDsPerQuery {
+		chunk := ids[start:min(start+MaxProbeIDsPerQuery, len(ids))]
+
+		idParam := make([]string, len(chunk))
+		for i, id := range chunk {
+			idParam[i] = strconv.Itoa(id)
+		}
+
+		params := url.Values{}
+		params.Set("id__in", strings.Join(idParam, ","))
+
+		it := c.ListProbes(ctx, params)
+		for it.Next() {
+			probe := it.Probe()
+			probes[probe.ID] = probe
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
 		}
 	}
 
-	return probesByASN, nil
+	return probes, nil
 }
 
-// CreateMeasurement creates a new traceroute measurement
-func (c *Client) CreateMeasurement(req MeasurementRequest) (int, error) {
-	url := fmt.Sprintf("%s/measurements/", BaseURL)
+// CreateMeasurement creates a new measurement and returns the ID of the
+// first definition's measurement
+func (c *Client) CreateMeasurement(ctx context.Context, req MeasurementRequest) (int, error) {
+	ids, err := c.CreateMeasurements(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// CreateMeasurements creates one measurement per definition in req and
+// returns their IDs in definition order
+func (c *Client) CreateMeasurements(ctx context.Context, req MeasurementRequest) ([]int, error) {
+	url := fmt.Sprintf("%s/measurements/", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	httpReq, err := c.newRequest(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Key %s", c.apiKey))
-
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return 0, fmt.Errorf("failed to execute request: %w", err)
+		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusCreated {
-		return 0, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(resp, body)
 	}
 
 	var msmResp MeasurementResponse
 	if err := json.Unmarshal(body, &msmResp); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(msmResp.Measurements) == 0 {
-		return 0, fmt.Errorf("no measurement ID returned")
+		return nil, fmt.Errorf("no measurement ID returned")
 	}
 
-	return msmResp.Measurements[0], nil
+	return msmResp.Measurements, nil
 }
 
 // GetMeasurementStatus retrieves the status of a measurement
-func (c *Client) GetMeasurementStatus(measurementID int) (*MeasurementStatus, error) {
-	url := fmt.Sprintf("%s/measurements/%d/", BaseURL, measurementID)
+func (c *Client) GetMeasurementStatus(ctx context.Context, measurementID int) (*MeasurementStatus, error) {
+	url := fmt.Sprintf("%s/measurements/%d/", c.baseURL, measurementID)
+
+	var status MeasurementStatus
+	if err := c.getJSON(ctx, url, &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// ListMeasurements returns an iterator over the measurements owned by the API key's account
+func (c *Client) ListMeasurements(ctx context.Context, filter MeasurementFilter) *MeasurementIterator {
+	query := filter.values()
+	query.Set("page_size", strconv.Itoa(c.pageSize))
+
+	return &MeasurementIterator{pageIterator[MeasurementStatus]{
+		ctx:    ctx,
+		client: c,
+		next:   fmt.Sprintf("%s/measurements/my/?%s", c.baseURL, query.Encode()),
+	}}
+}
+
+// GetMeasurementResults retrieves the results of a traceroute measurement
+func (c *Client) GetMeasurementResults(ctx context.Context, measurementID int) ([]TracerouteResult, error) {
+	return getResults[TracerouteResult](ctx, c, measurementID)
+}
+
+// GetPingResults retrieves the results of a ping measurement
+func (c *Client) GetPingResults(ctx context.Context, measurementID int) ([]PingResult, error) {
+	return getResults[PingResult](ctx, c, measurementID)
+}
+
+// GetDNSResults retrieves the results of a DNS measurement
+func (c *Client) GetDNSResults(ctx context.Context, measurementID int) ([]DNSResult, error) {
+	return getResults[DNSResult](ctx, c, measurementID)
+}
+
+// GetHTTPResults retrieves the results of an HTTP measurement
+func (c *Client) GetHTTPResults(ctx context.Context, measurementID int) ([]HTTPResult, error) {
+	return getResults[HTTPResult](ctx, c, measurementID)
+}
+
+// GetSSLCertResults retrieves the results of an SSL certificate measurement
+func (c *Client) GetSSLCertResults(ctx context.Context, measurementID int) ([]SSLCertResult, error) {
+	return getResults[SSLCertResult](ctx, c, measurementID)
+}
+
+// getResults retrieves the results of a measurement decoded as T
+func getResults[T any](ctx context.Context, c *Client, measurementID int) ([]T, error) {
+	url := fmt.Sprintf("%s/measurements/%d/results/", c.baseURL, measurementID)
+
+	var results []T
+	if err := c.getJSON(ctx, url, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// GetMeasurementResultsRaw retrieves the results of a measurement as undecoded JSON,
+// one element per probe result, for any measurement type
+func (c *Client) GetMeasurementResultsRaw(ctx context.Context, measurementID int) ([]json.RawMessage, error) {
+	return getResults[json.RawMessage](ctx, c, measurementID)
+}
+
+// StopMeasurement stops a running measurement so it no longer consumes credits
+func (c *Client) StopMeasurement(ctx context.Context, measurementID int) error {
+	url := fmt.Sprintf("%s/measurements/%d/", c.baseURL, measurementID)
+
+	req, err := c.newRequest(ctx, "DELETE", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, nil)
 	}
 
-	var status MeasurementStatus
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	return nil
+}
+
+// newRequest builds an API request, authenticated when the client has a key.
+// GET requests use the read key when one is set.
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return &status, nil
-}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-// GetMeasurementResults retrieves the results of a measurement
-func (c *Client) GetMeasurementResults(measurementID int) ([]TracerouteResult, error) {
-	url := fmt.Sprintf("%s/measurements/%d/results/", BaseURL, measurementID)
+	key := c.apiKey
+	if method == http.MethodGet && c.readKey != "" {
+		key = c.readKey
+	}
+	if key != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Key %s", key))
+	}
+
+	return req, nil
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// getJSON performs a GET request and decodes a 200 response into out
+func (c *Client) getJSON(ctx context.Context, url string, out any) error {
+	req, err := c.newRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
 
+	return c.doJSON(req, out)
+}
+
+// doJSON sends a request and decodes a 200 response into out
+func (c *Client) doJSON(req *http.Request, out any) error {
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return newAPIError(resp, nil)
 	}
 
-	var results []TracerouteResult
-	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return results, nil
+	return nil
 }
 
 // WaitForMeasurement polls the measurement status until it completes or times out
 // It uses a hybrid approach: checks both result completeness and status changes
-func (c *Client) WaitForMeasurement(measurementID int, expectedProbes int, timeout time.Duration) error {
-	ticker := time.NewTicker(3 * time.Second)
+func (c *Client) WaitForMeasurement(ctx context.Context, measurementID int, expectedProbes int, timeout time.Duration) error {
+	ticker := time.NewTicker(c.pollInterval)
 	defer ticker.Stop()
 
 	timeoutCh := time.After(timeout)
@@ -183,35 +442,34 @@ func (c *Client) WaitForMeasurement(measurementID int, expectedProbes int, timeo
 	for {
 		select {
 		case <-ticker.C:
-			status, err := c.GetMeasurementStatus(measurementID)
+			status, err := c.GetMeasurementStatus(ctx, measurementID)
 			if err != nil {
 				return fmt.Errorf("failed to get measurement status: %w", err)
 			}
 
 			// Fast path: Check if all probes have reported results
 			// This allows early completion without waiting for status to change from Ongoing to Stopped
-			results, err := c.GetMeasurementResults(measurementID)
+			results, err := c.GetMeasurementResultsRaw(ctx, measurementID)
 			if err == nil && len(results) == expectedProbes {
 				// All probes have reported, measurement is effectively complete
 				return nil
 			}
 
 			// Slow path: Check official status changes
-			// Status ID: 0=Specified, 1=Scheduled, 2=Ongoing, 4=Stopped, 5=Forced to stop, 6=No suitable probes, 7=Failed, 8=Archived
-			if status.Status.ID == 4 || status.Status.ID == 5 {
+			switch status.Status.ID {
+			case MeasurementStopped, MeasurementForcedStop:
 				return nil
-			}
-
-			if status.Status.ID == 6 {
-				return fmt.Errorf("measurement failed: no suitable probes")
-			}
-
-			if status.Status.ID == 7 {
-				return fmt.Errorf("measurement failed")
+			case MeasurementNoSuitableProbes:
+				return ErrNoSuitableProbes
+			case MeasurementFailed:
+				return ErrMeasurementFailed
 			}
 
 		case <-timeoutCh:
-			return fmt.Errorf("timeout waiting for measurement to complete")
+			return ErrWaitTimeout
+
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
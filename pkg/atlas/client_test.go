@@ -0,0 +1,213 @@
+package atlas_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+// newTestClient starts a fake Atlas with the demo topology and returns a
+// client polling it every few milliseconds
+func newTestClient(t *testing.T, opts ...atlas.ClientOption) (*atlastest.Server, *atlas.Client) {
+	t.Helper()
+
+	srv := atlastest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SeedDemo()
+
+	opts = append([]atlas.ClientOption{
+		atlas.WithBaseURL(srv.URL()),
+		atlas.WithPollInterval(5 * time.Millisecond),
+	}, opts...)
+	return srv, atlas.NewClient("test", opts...)
+}
+
+// createTraceroute creates a one-off traceroute from the given probes
+func createTraceroute(t *testing.T, client *atlas.Client, probeIDs string, requested int) int {
+	t.Helper()
+
+	id, err := client.CreateMeasurement(context.Background(), atlas.MeasurementRequest{
+		Definitions: []atlas.MeasurementDefinition{{Type: "traceroute", AF: 4, Target: "192.0.2.65", Protocol: "ICMP"}},
+		Probes:      []atlas.ProbeSet{{Type: "probes", Value: probeIDs, Requested: requested}},
+		IsOneoff:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateMeasurement: %v", err)
+	}
+	return id
+}
+
+func TestGetProbesByASNPaginates(t *testing.T) {
+	_, client := newTestClient(t, atlas.WithPageSize(2))
+
+	probesByASN, err := client.GetProbesByASN(context.Background(), []int{atlastest.DemoEyeballA, atlastest.DemoEyeballB}, 4)
+	if err != nil {
+		t.Fatalf("GetProbesByASN: %v", err)
+	}
+
+	for asn, want := range map[int][]int{
+		atlastest.DemoEyeballA: {1001, 1002, 1003},
+		atlastest.DemoEyeballB: {2001, 2002, 2003, 2004},
+	} {
+		var got []int
+		for _, probe := range probesByASN[asn] {
+			got = append(got, probe.ID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("AS%d probes = %v, want %v", asn, got, want)
+		}
+	}
+}
+
+func TestGetProbesByASNIPv6(t *testing.T) {
+	_, client := newTestClient(t, atlas.WithPageSize(1))
+
+	probesByASN, err := client.GetProbesByASN(context.Background(), []int{atlastest.DemoEyeballB}, 6)
+	if err != nil {
+		t.Fatalf("GetProbesByASN: %v", err)
+	}
+
+	// Probe 1003 is in DemoEyeballB over IPv6 only, 2004 has no IPv6
+	var got []int
+	for _, probe := range probesByASN[atlastest.DemoEyeballB] {
+		got = append(got, probe.ID)
+	}
+	slices.Sort(got)
+	if want := []int{1003, 2001, 2002, 2003}; !slices.Equal(got, want) {
+		t.Errorf("IPv6 probes = %v, want %v", got, want)
+	}
+}
+
+func TestListProbesCount(t *testing.T) {
+	srv, client := newTestClient(t, atlas.WithPageSize(10))
+	srv.SeedBulk(25)
+
+	it := client.ListProbes(context.Background(), nil)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("ListProbes: %v", err)
+	}
+	if n != 32 || it.Count() != 32 {
+		t.Errorf("iterated %d probes with count %d, want 32", n, it.Count())
+	}
+}
+
+func TestMeasurementLifecycle(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.ResultsPerPoll = 1
+	ctx := context.Background()
+
+	id := createTraceroute(t, client, "1001,1002,2001", 3)
+
+	status, err := client.GetMeasurementStatus(ctx, id)
+	if err != nil {
+		t.Fatalf("GetMeasurementStatus: %v", err)
+	}
+	if status.Type != "traceroute" || status.ProbesRequested != 3 {
+		t.Errorf("status = %+v, want a traceroute with 3 probes", status)
+	}
+
+	if err := client.WaitForMeasurement(ctx, id, 3, 5*time.Second); err != nil {
+		t.Fatalf("WaitForMeasurement: %v", err)
+	}
+
+	results, err := client.GetMeasurementResults(ctx, id)
+	if err != nil {
+		t.Fatalf("GetMeasurementResults: %v", err)
+	}
+	var probes []int
+	for _, result := range results {
+		if result.MsmID != id {
+			t.Errorf("result of measurement %d, want %d", result.MsmID, id)
+		}
+		probes = append(probes, result.ProbeID)
+	}
+	slices.Sort(probes)
+	if want := []int{1001, 1002, 2001}; !slices.Equal(probes, want) {
+		t.Errorf("results from probes %v, want %v", probes, want)
+	}
+}
+
+func TestWaitForMeasurementTimeout(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.ResultsPerPoll = 0 // never completes
+
+	id := createTraceroute(t, client, "1001", 1)
+
+	err := client.WaitForMeasurement(context.Background(), id, 1, 50*time.Millisecond)
+	if !errors.Is(err, atlas.ErrWaitTimeout) {
+		t.Errorf("WaitForMeasurement = %v, want ErrWaitTimeout", err)
+	}
+}
+
+func TestWaitForMeasurementNoSuitableProbes(t *testing.T) {
+	_, client := newTestClient(t)
+
+	id := createTraceroute(t, client, "999999", 1)
+
+	err := client.WaitForMeasurement(context.Background(), id, 1, 5*time.Second)
+	if !errors.Is(err, atlas.ErrNoSuitableProbes) {
+		t.Errorf("WaitForMeasurement = %v, want ErrNoSuitableProbes", err)
+	}
+}
+
+func TestStopMeasurement(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.ResultsPerPoll = 1
+	ctx := context.Background()
+
+	id := createTraceroute(t, client, "1001,1002,2001", 3)
+
+	// Two polls: Scheduled to Ongoing, then a first result
+	for range 2 {
+		if _, err := client.GetMeasurementStatus(ctx, id); err != nil {
+			t.Fatalf("GetMeasurementStatus: %v", err)
+		}
+	}
+
+	if err := client.StopMeasurement(ctx, id); err != nil {
+		t.Fatalf("StopMeasurement: %v", err)
+	}
+	if status, _ := srv.Measurement(id); status.Status.ID != atlas.MeasurementForcedStop {
+		t.Errorf("status after stop = %+v, want forced stop", status.Status)
+	}
+
+	// A stopped measurement counts as complete and keeps its partial results
+	if err := client.WaitForMeasurement(ctx, id, 3, 5*time.Second); err != nil {
+		t.Fatalf("WaitForMeasurement after stop: %v", err)
+	}
+	results, err := client.GetMeasurementResults(ctx, id)
+	if err != nil {
+		t.Fatalf("GetMeasurementResults: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("got %d results after stop, want 1", len(results))
+	}
+
+	if err := client.StopMeasurement(ctx, 42); !errors.As(err, new(*atlas.APIError)) {
+		t.Errorf("stopping an unknown measurement = %v, want an APIError", err)
+	}
+}
+
+func TestCreateMeasurementAuth(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.APIKey = "secret"
+
+	_, err := client.CreateMeasurement(context.Background(), atlas.MeasurementRequest{
+		Definitions: []atlas.MeasurementDefinition{{Type: "traceroute", AF: 4, Target: "192.0.2.65"}},
+		Probes:      []atlas.ProbeSet{{Type: "probes", Value: "1001", Requested: 1}},
+		IsOneoff:    true,
+	})
+	if !errors.Is(err, atlas.ErrAuth) {
+		t.Errorf("CreateMeasurement with a wrong key = %v, want ErrAuth", err)
+	}
+}
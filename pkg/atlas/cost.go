@@ -0,0 +1,130 @@
+package atlas
+
+import "fmt"
+
+// Quotas RIPE Atlas applies to every user, besides MaxProbesPerMeasurement.
+// MaxConcurrentMeasurements and MaxOneoffsPerTarget count every measurement
+// not yet finished, see Scheduler.
+const (
+	MaxDailyResults           = 100000
+	MaxDailyCredits           = 1000000
+	MaxResultsPerSecond       = 50 // per measurement, probes divided by spread
+	MaxConcurrentMeasurements = 100
+	MaxOneoffsPerTarget       = 25 // concurrent one-offs of the same type and target
+)
+
+// defaultPackets is the packet count Atlas uses when a definition leaves it at zero
+const defaultPackets = 3
+
+// packetCosts are the credits per packet of the types that send a
+// configurable number of packets, and typeCosts the credits per result of
+// the others
+var (
+	packetCosts = map[string]int64{
+		"ping":       1,
+		"traceroute": 10,
+		"ntp":        3,
+	}
+	typeCosts = map[string]int64{
+		"dns":     10,
+		"http":    10,
+		"sslcert": 10,
+	}
+)
+
+// DefinitionCost is the estimated cost of one measurement definition
+type DefinitionCost struct {
+	Type      string
+	AF        int
+	Target    string
+	Results   int   // one result per probe
+	PerResult int64 // credits per result
+	Credits   int64
+}
+
+// CostEstimate is the estimated credit cost and result count of a request
+type CostEstimate struct {
+	Definitions []DefinitionCost
+	Probes      int
+	Results     int
+	Credits     int64
+}
+
+// EstimateCost estimates what a measurement request will spend as probes ×
+// packets × the per-type cost. Atlas does the actual accounting, which also
+// depends on packet size and protocol, so this is a guide rather than a
+// quote. Probes that turn out to be unavailable are not charged.
+func EstimateCost(req MeasurementRequest) CostEstimate {
+	var estimate CostEstimate
+
+	estimate.Probes = req.RequestedProbes()
+
+	for _, def := range req.Definitions {
+		perResult := resultCost(def)
+		cost := DefinitionCost{
+			Type:      def.Type,
+			AF:        def.AF,
+			Target:    def.Target,
+			Results:   estimate.Probes,
+			PerResult: perResult,
+			Credits:   perResult * int64(estimate.Probes),
+		}
+		estimate.Definitions = append(estimate.Definitions, cost)
+		estimate.Results += cost.Results
+		estimate.Credits += cost.Credits
+	}
+
+	return estimate
+}
+
+// resultCost returns the estimated credits of a single result: packets ×
+// the per-packet cost, or the per-result cost for types without packets
+func resultCost(def MeasurementDefinition) int64 {
+	if cost, ok := packetCosts[def.Type]; ok {
+		packets := int64(def.Packets)
+		if packets == 0 {
+			packets = defaultPackets
+		}
+		return packets * cost
+	}
+	if cost, ok := typeCosts[def.Type]; ok {
+		return cost
+	}
+	return 10
+}
+
+// QuotaWarnings lists the Atlas quotas the request would exceed. Requests
+// for more than MaxProbesPerMeasurement probes are checked as the shards
+// ShardRequest splits them into. How many measurements may run at the same
+// time depends on the account, see CountActiveMeasurements.
+func (e CostEstimate) QuotaWarnings(req MeasurementRequest) []string {
+	var warnings []string
+
+	shards := ShardRequest(req, MaxProbesPerMeasurement)
+	for _, shard := range shards {
+		if probes := shard.RequestedProbes(); probes > MaxProbesPerMeasurement {
+			warnings = append(warnings, fmt.Sprintf("%d probes requested by one probe set, at most %d are allowed per measurement",
+				probes, MaxProbesPerMeasurement))
+		}
+	}
+	if e.Results > MaxDailyResults {
+		warnings = append(warnings, fmt.Sprintf("%d results exceed the daily limit of %d", e.Results, MaxDailyResults))
+	}
+	if e.Credits > MaxDailyCredits {
+		warnings = append(warnings, fmt.Sprintf("%d credits exceed the daily limit of %d", e.Credits, MaxDailyCredits))
+	}
+	for _, def := range req.Definitions {
+		if def.Spread == 0 {
+			continue
+		}
+		for _, shard := range shards {
+			if probes := shard.RequestedProbes(); probes/def.Spread > MaxResultsPerSecond {
+				warnings = append(warnings, fmt.Sprintf("%d probes over a %ds spread exceed %d results per second",
+					probes, def.Spread, MaxResultsPerSecond))
+				break
+			}
+		}
+	}
+
+	return warnings
+}
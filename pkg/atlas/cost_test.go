@@ -0,0 +1,62 @@
+package atlas
+
+import "testing"
+
+func TestEstimateCost(t *testing.T) {
+	probes := []ProbeSet{{Type: "probes", Value: "1,2,3,4", Requested: 4}, {Type: "asn", Value: "64500", Requested: 6}}
+	largePackets := 2000
+
+	tests := []struct {
+		name      string
+		def       MeasurementDefinition
+		perResult int64
+	}{
+		{name: "ping with default packets", def: MeasurementDefinition{Type: "ping"}, perResult: 3},
+		{name: "ping with 10 packets", def: MeasurementDefinition{Type: "ping", Packets: 10, Size: &largePackets}, perResult: 10},
+		{name: "traceroute", def: MeasurementDefinition{Type: "traceroute", Packets: 3}, perResult: 30},
+		{name: "traceroute with one packet", def: MeasurementDefinition{Type: "traceroute", Packets: 1}, perResult: 10},
+		{name: "ntp", def: MeasurementDefinition{Type: "ntp", Packets: 2}, perResult: 6},
+		{name: "dns over TCP", def: MeasurementDefinition{Type: "dns", Protocol: "TCP"}, perResult: 10},
+		{name: "http", def: MeasurementDefinition{Type: "http"}, perResult: 10},
+		{name: "sslcert", def: MeasurementDefinition{Type: "sslcert"}, perResult: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, oneoff := range []bool{false, true} {
+				estimate := EstimateCost(MeasurementRequest{
+					Definitions: []MeasurementDefinition{tt.def},
+					Probes:      probes,
+					IsOneoff:    oneoff,
+				})
+				if estimate.Probes != 10 || estimate.Results != 10 {
+					t.Errorf("oneoff %v: %d probes, %d results, want 10 each", oneoff, estimate.Probes, estimate.Results)
+				}
+				if got := estimate.Definitions[0].PerResult; got != tt.perResult {
+					t.Errorf("oneoff %v: %d credits per result, want %d", oneoff, got, tt.perResult)
+				}
+				if estimate.Credits != 10*tt.perResult {
+					t.Errorf("oneoff %v: %d credits, want %d", oneoff, estimate.Credits, 10*tt.perResult)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimateCostSumsDefinitions(t *testing.T) {
+	estimate := EstimateCost(MeasurementRequest{
+		Definitions: []MeasurementDefinition{
+			{Type: "traceroute", AF: 4, Packets: 3},
+			{Type: "traceroute", AF: 6, Packets: 3},
+			{Type: "ping", AF: 4, Packets: 5},
+		},
+		Probes: []ProbeSet{{Type: "probes", Value: "1,2", Requested: 2}},
+	})
+
+	if estimate.Results != 6 || estimate.Credits != 2*(30+30+5) {
+		t.Errorf("estimate = %d results, %d credits, want 6 and %d", estimate.Results, estimate.Credits, 2*(30+30+5))
+	}
+	if len(estimate.Definitions) != 3 || estimate.Definitions[2].Credits != 10 {
+		t.Errorf("definitions = %+v, want 3 with the ping costing 10", estimate.Definitions)
+	}
+}
@@ -0,0 +1,249 @@
+package atlas
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// DNS record types decoded by ParseDNSMessage
+const (
+	dnsTypeA     = 1
+	dnsTypeNS    = 2
+	dnsTypeCNAME = 5
+	dnsTypeSOA   = 6
+	dnsTypePTR   = 12
+	dnsTypeMX    = 15
+	dnsTypeTXT   = 16
+	dnsTypeAAAA  = 28
+)
+
+var dnsTypeNames = map[uint16]string{
+	dnsTypeA:     "A",
+	dnsTypeNS:    "NS",
+	dnsTypeCNAME: "CNAME",
+	dnsTypeSOA:   "SOA",
+	dnsTypePTR:   "PTR",
+	dnsTypeMX:    "MX",
+	dnsTypeTXT:   "TXT",
+	dnsTypeAAAA:  "AAAA",
+}
+
+var dnsRcodeNames = []string{"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED"}
+
+// errDNSTruncated is returned when the message ends in the middle of a field
+var errDNSTruncated = errors.New("dns: message truncated")
+
+// DNSMessage is the part of a DNS response needed for answer analysis
+type DNSMessage struct {
+	ID        uint16
+	Rcode     int
+	Truncated bool
+	Answers   []DNSAnswer
+}
+
+// DNSAnswer is a resource record from the answer section
+type DNSAnswer struct {
+	Name string
+	Type string
+	TTL  uint32
+	Data string // presentation format of the record data
+}
+
+// RcodeName returns the mnemonic of the response code
+func (m DNSMessage) RcodeName() string {
+	if m.Rcode >= 0 && m.Rcode < len(dnsRcodeNames) {
+		return dnsRcodeNames[m.Rcode]
+	}
+	return "RCODE" + strconv.Itoa(m.Rcode)
+}
+
+// Decode parses the base64 encoded answer buffer of the response
+func (r DNSResponse) Decode() (*DNSMessage, error) {
+	buf, err := base64.StdEncoding.DecodeString(r.Abuf)
+	if err != nil {
+		return nil, fmt.Errorf("dns: invalid abuf: %w", err)
+	}
+	return ParseDNSMessage(buf)
+}
+
+// ParseDNSMessage decodes the header and answer section of a wire-format DNS
+// message. Record types without a decoder are returned as hex data.
+func ParseDNSMessage(buf []byte) (*DNSMessage, error) {
+	if len(buf) < 12 {
+		return nil, errDNSTruncated
+	}
+
+	flags := binary.BigEndian.Uint16(buf[2:4])
+	msg := &DNSMessage{
+		ID:        binary.BigEndian.Uint16(buf[0:2]),
+		Rcode:     int(flags & 0x000f),
+		Truncated: flags&0x0200 != 0,
+	}
+	qdcount := int(binary.BigEndian.Uint16(buf[4:6]))
+	ancount := int(binary.BigEndian.Uint16(buf[6:8]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := readDNSName(buf, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4 // QTYPE and QCLASS
+		if off > len(buf) {
+			return nil, errDNSTruncated
+		}
+	}
+
+	for i := 0; i < ancount; i++ {
+		name, next, err := readDNSName(buf, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next
+		if off+10 > len(buf) {
+			return nil, errDNSTruncated
+		}
+
+		rrtype := binary.BigEndian.Uint16(buf[off : off+2])
+		ttl := binary.BigEndian.Uint32(buf[off+4 : off+8])
+		rdlen := int(binary.BigEndian.Uint16(buf[off+8 : off+10]))
+		off += 10
+		if off+rdlen > len(buf) {
+			return nil, errDNSTruncated
+		}
+
+		data, err := decodeRData(buf, off, rdlen, rrtype)
+		if err != nil {
+			return nil, err
+		}
+		off += rdlen
+
+		msg.Answers = append(msg.Answers, DNSAnswer{
+			Name: name,
+			Type: dnsTypeName(rrtype),
+			TTL:  ttl,
+			Data: data,
+		})
+	}
+
+	return msg, nil
+}
+
+// decodeRData renders the record data at buf[off:off+rdlen] in presentation format
+func decodeRData(buf []byte, off, rdlen int, rrtype uint16) (string, error) {
+	rdata := buf[off : off+rdlen]
+
+	switch rrtype {
+	case dnsTypeA, dnsTypeAAAA:
+		addr, ok := netip.AddrFromSlice(rdata)
+		if !ok {
+			return "", fmt.Errorf("dns: invalid address length %d", rdlen)
+		}
+		return addr.String(), nil
+
+	case dnsTypeNS, dnsTypeCNAME, dnsTypePTR:
+		name, _, err := readDNSName(buf, off)
+		return name, err
+
+	case dnsTypeMX:
+		if rdlen < 3 {
+			return "", errDNSTruncated
+		}
+		name, _, err := readDNSName(buf, off+2)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), name), nil
+
+	case dnsTypeTXT:
+		var parts []string
+		for i := 0; i < len(rdata); {
+			n := int(rdata[i])
+			if i+1+n > len(rdata) {
+				return "", errDNSTruncated
+			}
+			parts = append(parts, strconv.Quote(string(rdata[i+1:i+1+n])))
+			i += 1 + n
+		}
+		return strings.Join(parts, " "), nil
+
+	case dnsTypeSOA:
+		mname, next, err := readDNSName(buf, off)
+		if err != nil {
+			return "", err
+		}
+		rname, next, err := readDNSName(buf, next)
+		if err != nil {
+			return "", err
+		}
+		if next+20 > off+rdlen {
+			return "", errDNSTruncated
+		}
+		return fmt.Sprintf("%s %s %d", mname, rname, binary.BigEndian.Uint32(buf[next:next+4])), nil
+	}
+
+	return fmt.Sprintf("\\# %d %x", rdlen, rdata), nil
+}
+
+// readDNSName reads a possibly compressed domain name starting at off and
+// returns it with a trailing dot, plus the offset just past the name
+func readDNSName(buf []byte, off int) (string, int, error) {
+	var (
+		labels []string
+		end    = -1  // offset after the name, fixed at the first pointer
+		start  = off // where the labels being read start
+	)
+
+	for {
+		if off >= len(buf) {
+			return "", 0, errDNSTruncated
+		}
+		n := int(buf[off])
+
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+
+		case n&0xc0 == 0xc0:
+			if off+2 > len(buf) {
+				return "", 0, errDNSTruncated
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			// Compression pointers must point before the labels that led to
+			// them, so every jump goes further back and loops are impossible
+			ptr := int(binary.BigEndian.Uint16(buf[off:off+2]) & 0x3fff)
+			if ptr >= start {
+				return "", 0, fmt.Errorf("dns: compression pointer at %d does not point backwards", off)
+			}
+			off, start = ptr, ptr
+
+		case n&0xc0 != 0:
+			return "", 0, fmt.Errorf("dns: unsupported label type 0x%02x", n&0xc0)
+
+		default:
+			if off+1+n > len(buf) {
+				return "", 0, errDNSTruncated
+			}
+			labels = append(labels, string(buf[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+// dnsTypeName returns the mnemonic of a record type
+func dnsTypeName(rrtype uint16) string {
+	if name, ok := dnsTypeNames[rrtype]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(rrtype))
+}
@@ -0,0 +1,168 @@
+package atlas
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// dnsHeader encodes a response header with the given section counts
+func dnsHeader(qdcount, ancount uint16) []byte {
+	buf := binary.BigEndian.AppendUint16(nil, 0x1234)
+	buf = binary.BigEndian.AppendUint16(buf, 0x8183) // response, RD, RA, NXDOMAIN
+	buf = binary.BigEndian.AppendUint16(buf, qdcount)
+	buf = binary.BigEndian.AppendUint16(buf, ancount)
+	return binary.BigEndian.AppendUint32(buf, 0)
+}
+
+// dnsName encodes a domain name without compression
+func dnsName(name string) []byte {
+	var buf []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	return append(buf, 0)
+}
+
+// dnsQuestion encodes a question for name, which starts at offset 12
+func dnsQuestion(name string) []byte {
+	buf := dnsName(name)
+	buf = binary.BigEndian.AppendUint16(buf, dnsTypeA)
+	return binary.BigEndian.AppendUint16(buf, 1)
+}
+
+// dnsRR encodes a resource record whose owner is the question name at offset 12
+func dnsRR(rrtype uint16, rdata []byte) []byte {
+	return dnsRRLen(rrtype, len(rdata), rdata)
+}
+
+// dnsRRLen is dnsRR with an RDLENGTH that may differ from len(rdata)
+func dnsRRLen(rrtype uint16, rdlen int, rdata []byte) []byte {
+	buf := []byte{0xc0, 12}
+	buf = binary.BigEndian.AppendUint16(buf, rrtype)
+	buf = binary.BigEndian.AppendUint16(buf, 1)
+	buf = binary.BigEndian.AppendUint32(buf, 300)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(rdlen))
+	return append(buf, rdata...)
+}
+
+// dnsResponse is a response for example.com with the given answer records
+func dnsResponse(answers ...[]byte) []byte {
+	return slices.Concat(append([][]byte{dnsHeader(1, uint16(len(answers))), dnsQuestion("example.com")}, answers...)...)
+}
+
+func TestParseDNSMessage(t *testing.T) {
+	soa := slices.Concat(dnsName("ns1.example.com"), []byte{0xc0, 12}, make([]byte, 20))
+	binary.BigEndian.PutUint32(soa[len(soa)-20:], 2024010101)
+
+	tests := []struct {
+		name string
+		buf  []byte
+		want []DNSAnswer
+	}{
+		{
+			name: "A",
+			buf:  dnsResponse(dnsRR(dnsTypeA, []byte{192, 0, 2, 1})),
+			want: []DNSAnswer{{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.1"}},
+		},
+		{
+			name: "AAAA",
+			buf:  dnsResponse(dnsRR(dnsTypeAAAA, []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1})),
+			want: []DNSAnswer{{Name: "example.com.", Type: "AAAA", TTL: 300, Data: "2001:db8::1"}},
+		},
+		{
+			name: "MX with a compressed exchange",
+			buf:  dnsResponse(dnsRR(dnsTypeMX, []byte{0, 10, 4, 'm', 'a', 'i', 'l', 0xc0, 12})),
+			want: []DNSAnswer{{Name: "example.com.", Type: "MX", TTL: 300, Data: "10 mail.example.com."}},
+		},
+		{
+			name: "TXT with two strings",
+			buf:  dnsResponse(dnsRR(dnsTypeTXT, []byte{5, 'v', '=', 's', 'p', 'f', 2, 'h', 'i'})),
+			want: []DNSAnswer{{Name: "example.com.", Type: "TXT", TTL: 300, Data: `"v=spf" "hi"`}},
+		},
+		{
+			name: "SOA",
+			buf:  dnsResponse(dnsRR(dnsTypeSOA, soa)),
+			want: []DNSAnswer{{Name: "example.com.", Type: "SOA", TTL: 300, Data: "ns1.example.com. example.com. 2024010101"}},
+		},
+		{
+			name: "CNAME followed by A",
+			buf: dnsResponse(
+				dnsRR(dnsTypeCNAME, []byte{3, 'w', 'w', 'w', 0xc0, 12}),
+				dnsRR(dnsTypeA, []byte{192, 0, 2, 2}),
+			),
+			want: []DNSAnswer{
+				{Name: "example.com.", Type: "CNAME", TTL: 300, Data: "www.example.com."},
+				{Name: "example.com.", Type: "A", TTL: 300, Data: "192.0.2.2"},
+			},
+		},
+		{
+			name: "unknown type as hex",
+			buf:  dnsResponse(dnsRR(99, []byte{0xab, 0xcd})),
+			want: []DNSAnswer{{Name: "example.com.", Type: "TYPE99", TTL: 300, Data: `\# 2 abcd`}},
+		},
+		{
+			name: "no answers",
+			buf:  dnsResponse(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseDNSMessage(tt.buf)
+			if err != nil {
+				t.Fatalf("ParseDNSMessage: %v", err)
+			}
+			if msg.ID != 0x1234 || msg.RcodeName() != "NXDOMAIN" || msg.Truncated {
+				t.Errorf("header = %+v, want ID 0x1234 and NXDOMAIN", msg)
+			}
+			if !slices.Equal(msg.Answers, tt.want) {
+				t.Errorf("answers = %+v, want %+v", msg.Answers, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDNSMessageMalformed(t *testing.T) {
+	// A name at offset 12 made of a label and a pointer back to that label
+	labelLoop := slices.Concat(dnsHeader(1, 0), []byte{1, 'a', 0xc0, 12}, []byte{0, 1, 0, 1})
+
+	tests := []struct {
+		name      string
+		buf       []byte
+		truncated bool // want errDNSTruncated
+	}{
+		{name: "empty", buf: nil, truncated: true},
+		{name: "short header", buf: dnsHeader(0, 0)[:11], truncated: true},
+		{name: "question without type and class", buf: slices.Concat(dnsHeader(1, 0), dnsName("example.com")), truncated: true},
+		{name: "question name past the end", buf: slices.Concat(dnsHeader(1, 0), []byte{7, 'e', 'x'}), truncated: true},
+		{name: "answer count past the end", buf: slices.Concat(dnsHeader(1, 1), dnsQuestion("example.com")), truncated: true},
+		{name: "answer header cut short", buf: dnsResponse(dnsRR(dnsTypeA, []byte{192, 0, 2, 1}))[:35], truncated: true},
+		{name: "oversized RDLENGTH", buf: dnsResponse(dnsRRLen(dnsTypeA, 0xffff, []byte{192, 0, 2, 1})), truncated: true},
+		{name: "RDATA cut short", buf: dnsResponse(dnsRRLen(dnsTypeAAAA, 16, []byte{0x20, 0x01})), truncated: true},
+		{name: "A of the wrong length", buf: dnsResponse(dnsRR(dnsTypeA, []byte{192, 0, 2}))},
+		{name: "MX without exchange", buf: dnsResponse(dnsRR(dnsTypeMX, []byte{0, 10})), truncated: true},
+		{name: "TXT string past RDATA", buf: dnsResponse(dnsRR(dnsTypeTXT, []byte{9, 'v', '='})), truncated: true},
+		{name: "SOA without serial", buf: dnsResponse(dnsRR(dnsTypeSOA, slices.Concat(dnsName("ns1.example.com"), []byte{0xc0, 12}, make([]byte, 8)))), truncated: true},
+		{name: "pointer to itself", buf: slices.Concat(dnsHeader(1, 0), []byte{0xc0, 12}, []byte{0, 1, 0, 1})},
+		{name: "forward pointer", buf: slices.Concat(dnsHeader(1, 0), []byte{0xc0, 16, 0, 1}, dnsName("example.com"))},
+		{name: "pointer loop through a label", buf: labelLoop},
+		{name: "pointer cut short", buf: slices.Concat(dnsHeader(1, 0), []byte{0xc0}), truncated: true},
+		{name: "extended label type", buf: slices.Concat(dnsHeader(1, 0), []byte{0x41, 0}, []byte{0, 1, 0, 1})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseDNSMessage(tt.buf)
+			if err == nil {
+				t.Fatalf("ParseDNSMessage = %+v, want an error", msg)
+			}
+			if got := errors.Is(err, errDNSTruncated); got != tt.truncated {
+				t.Errorf("ParseDNSMessage = %v, truncated %v, want %v", err, got, tt.truncated)
+			}
+		})
+	}
+}
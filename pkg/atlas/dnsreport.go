@@ -0,0 +1,82 @@
+package atlas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DNSReport represents a DNS answer consistency report. The embedded Report
+// holds the measurement information and probe distribution.
+type DNSReport struct {
+	Report
+	DNSAnalysis
+	Query     string
+	QueryType string
+	Resolver  string // empty when probes used their own resolvers
+	Expected  []string
+}
+
+// GenerateDNSReport creates a formatted text report for DNS measurements
+func GenerateDNSReport(report DNSReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas DNS Answer Report")
+	writeMeasurementInfo(&sb, report.Report)
+	writeProbeDistribution(&sb, report.Report)
+
+	sb.WriteString("DNS Query:\n")
+	sb.WriteString(fmt.Sprintf("  • Question: %s IN %s\n", report.Query, report.QueryType))
+	if report.Resolver != "" {
+		sb.WriteString(fmt.Sprintf("  • Resolver: %s\n", report.Resolver))
+	} else {
+		sb.WriteString("  • Resolver: probe's own resolvers\n")
+	}
+	if len(report.Expected) > 0 {
+		sb.WriteString(fmt.Sprintf("  • Expected: %s\n", strings.Join(report.Expected, ", ")))
+	}
+	if report.Responses > 0 {
+		sb.WriteString(fmt.Sprintf("  • Most common answer: %s (%d/%d responses)\n",
+			report.Consensus, report.ConsensusCount, report.Responses))
+	}
+	sb.WriteString("\n" + Separator + "\n\n")
+
+	sb.WriteString("Answers by Source ASN:\n\n")
+	for _, s := range report.ByASN {
+		sb.WriteString(fmt.Sprintf("  AS%-6d %d probes, %d responses, %d errors",
+			s.ASN, s.Probes, s.Responses, s.Errors))
+		if s.Responses > 0 {
+			sb.WriteString(fmt.Sprintf(", median %.1f ms, max %.1f ms", s.MedianRT, s.MaxRT))
+		}
+		sb.WriteString("\n")
+
+		for _, a := range s.Answers {
+			mark := "✓"
+			if a.Reason != "" {
+				mark = "✗"
+			}
+			sb.WriteString(fmt.Sprintf("    %s %-44s %4d\n", mark, truncate(a.Answer, 44), a.Count))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(Separator + "\n\n")
+
+	sb.WriteString(fmt.Sprintf("Flagged Responses (%d):\n\n", len(report.Findings)))
+	if len(report.Findings) == 0 {
+		sb.WriteString("  No inconsistent or suspicious answers found.\n\n")
+	} else {
+		for _, f := range report.Findings {
+			resolver := f.Resolver
+			if resolver == "" {
+				resolver = "unknown resolver"
+			}
+			sb.WriteString(fmt.Sprintf("  ⚠️  Probe %-7d AS%-6d via %-16s %s\n", f.ProbeID, f.ASN, resolver, f.Reason))
+			sb.WriteString(fmt.Sprintf("      Answer: %s\n", f.Answer))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(Separator + "\n")
+
+	return sb.String()
+}
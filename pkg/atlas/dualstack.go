@@ -0,0 +1,128 @@
+package atlas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DualStackReport represents an IPv4 versus IPv6 path comparison report. The
+// embedded Report holds the measurement information and probe distribution.
+type DualStackReport struct {
+	Report
+	DualStackComparison
+	CommonV4 []ASNInfo
+	CommonV6 []ASNInfo
+}
+
+// GenerateDualStackReport creates a formatted text report comparing paired
+// IPv4 and IPv6 traceroutes
+func GenerateDualStackReport(report DualStackReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas Dual-Stack Path Report")
+	writeMeasurementInfo(&sb, report.Report)
+	writeProbeDistribution(&sb, report.Report)
+
+	compared := len(report.Probes)
+	sb.WriteString("Dual-Stack Summary:\n")
+	sb.WriteString(fmt.Sprintf("  • Probes compared: %d", compared))
+	if report.Unpaired > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d with results in only one family)", report.Unpaired))
+	}
+	sb.WriteString("\n")
+	if compared > 0 {
+		sb.WriteString(fmt.Sprintf("  • Identical AS paths: %d (%.1f%%)\n",
+			report.Identical, float64(report.Identical)/float64(compared)*100))
+		sb.WriteString(fmt.Sprintf("  • Divergent AS paths: %d (%.1f%%)\n",
+			report.Divergent, float64(report.Divergent)/float64(compared)*100))
+		sb.WriteString(fmt.Sprintf("  • Median RTT difference (IPv6 − IPv4): %+.1f ms\n", report.MedianRTTDiff))
+	}
+	sb.WriteString("\n" + Separator + "\n\n")
+
+	sb.WriteString(fmt.Sprintf("Common ASNs (Threshold: %.1f%%):\n\n", report.Threshold*100))
+	writeCommonASNList(&sb, "IPv4", report.CommonV4)
+	writeCommonASNList(&sb, "IPv6", report.CommonV6)
+	sb.WriteString(Separator + "\n\n")
+
+	sb.WriteString("Single-Family ASNs:\n\n")
+	writeFamilyASNs(&sb, "Only over IPv4", report.OnlyV4)
+	writeFamilyASNs(&sb, "Only over IPv6", report.OnlyV6)
+	sb.WriteString(Separator + "\n\n")
+
+	sb.WriteString("Per-Probe Comparison:\n\n")
+	sb.WriteString(fmt.Sprintf("  %-8s %-8s %9s %9s %9s\n", "Probe", "ASN", "RTT v4", "RTT v6", "Δ"))
+	for _, p := range report.Probes {
+		mark := "="
+		if p.DivergeAt >= 0 {
+			mark = "≠"
+		}
+		sb.WriteString(fmt.Sprintf("  %-8d AS%-6d %9s %9s %9s  %s\n",
+			p.ProbeID, p.ASN, formatRTT(p.RTTV4, p.ReachedV4), formatRTT(p.RTTV6, p.ReachedV6),
+			formatRTTDiff(p), mark))
+		if p.DivergeAt >= 0 {
+			sb.WriteString(fmt.Sprintf("      v4: %s\n", formatASPath(p.PathV4)))
+			sb.WriteString(fmt.Sprintf("      v6: %s\n", formatASPath(p.PathV6)))
+		}
+	}
+	sb.WriteString("\n  * target not reached, RTT of the last responding hop\n")
+	sb.WriteString("\n" + Separator + "\n")
+
+	return sb.String()
+}
+
+// writeCommonASNList writes the common ASNs of one address family on a single line each
+func writeCommonASNList(sb *strings.Builder, family string, asns []ASNInfo) {
+	sb.WriteString(fmt.Sprintf("  %s:\n", family))
+	if len(asns) == 0 {
+		sb.WriteString("    No common ASNs found meeting the threshold.\n\n")
+		return
+	}
+	for _, asn := range asns {
+		sb.WriteString(fmt.Sprintf("    AS%-6d %-36s %5.1f%%\n", asn.ASN, truncate(asn.Name, 36), asn.Percentage))
+	}
+	sb.WriteString("\n")
+}
+
+// writeFamilyASNs writes the ASNs seen over only one address family
+func writeFamilyASNs(sb *strings.Builder, title string, asns []FamilyASN) {
+	sb.WriteString(fmt.Sprintf("  %s:\n", title))
+	if len(asns) == 0 {
+		sb.WriteString("    None\n\n")
+		return
+	}
+	for _, asn := range asns {
+		sb.WriteString(fmt.Sprintf("    AS%-6d %-36s %d probes\n", asn.ASN, truncate(asn.Name, 36), asn.Probes))
+	}
+	sb.WriteString("\n")
+}
+
+// formatRTT formats the RTT of the final hop, marking paths that did not reach the target
+func formatRTT(rtt float64, reached bool) string {
+	switch {
+	case rtt == 0:
+		return "-"
+	case !reached:
+		return fmt.Sprintf("%.1f*", rtt)
+	}
+	return fmt.Sprintf("%.1f ms", rtt)
+}
+
+// formatRTTDiff formats the IPv6 minus IPv4 RTT of a probe that reached the target in both families
+func formatRTTDiff(p DualStackProbe) string {
+	if !p.ReachedV4 || !p.ReachedV6 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f", p.RTTV6-p.RTTV4)
+}
+
+// formatASPath renders an AS path as "AS1 → AS2 → AS3"
+func formatASPath(path []int) string {
+	if len(path) == 0 {
+		return "(no responding hops)"
+	}
+	parts := make([]string, len(path))
+	for i, asn := range path {
+		parts[i] = fmt.Sprintf("AS%d", asn)
+	}
+	return strings.Join(parts, " → ")
+}
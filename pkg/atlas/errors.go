@@ -0,0 +1,149 @@
+package atlas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for conditions callers commonly need to tell apart.
+// Use errors.Is to test for them, an *APIError matches ErrAuth,
+// ErrQuotaExceeded and ErrRateLimited depending on its status and detail.
+var (
+	ErrAuth              = errors.New("authentication failed")
+	ErrQuotaExceeded     = errors.New("quota exceeded")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrNoSuitableProbes  = errors.New("no suitable probes")
+	ErrMeasurementFailed = errors.New("measurement failed")
+	ErrWaitTimeout       = errors.New("timeout waiting for measurement to complete")
+)
+
+// APIError is returned when the Atlas API answers with an unexpected status
+type APIError struct {
+	StatusCode int
+	URL        string
+	Title      string
+	Detail     string
+	Errors     []APIErrorDetail
+	Body       string // raw response body, kept when it could not be parsed
+}
+
+// APIErrorDetail is a single entry of the errors[] list in an Atlas error body
+type APIErrorDetail struct {
+	Detail string `json:"detail"`
+	Source struct {
+		Pointer string `json:"pointer"`
+	} `json:"source"`
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("API returned status %d", e.StatusCode))
+
+	switch {
+	case e.Detail != "":
+		sb.WriteString(": " + e.Detail)
+	case e.Title != "":
+		sb.WriteString(": " + e.Title)
+	case e.Body != "":
+		sb.WriteString(": " + e.Body)
+	}
+
+	for _, detail := range e.Errors {
+		if detail.Source.Pointer != "" {
+			sb.WriteString(fmt.Sprintf("; %s: %s", detail.Source.Pointer, detail.Detail))
+		} else {
+			sb.WriteString("; " + detail.Detail)
+		}
+	}
+
+	return sb.String()
+}
+
+// Is reports whether the API error corresponds to one of the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrQuotaExceeded:
+		// A 429 is a short-lived rate limit, not a quota of the account
+		return e.StatusCode != http.StatusTooManyRequests && e.mentions(quotaDetails...)
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// quotaDetails are phrases of the Atlas error details that refuse a
+// measurement for lack of credits or for the limits on measurements running
+// at the same time. Validation errors that merely mention a limit, e.g.
+// "max hops exceeded", must not match.
+var quotaDetails = []string{
+	"not enough credit",
+	"insufficient credit",
+	"spending limit",
+	"concurrent measurement",
+	"too many measurements",
+	"measurement limit",
+}
+
+// mentions reports whether any of the error texts contains one of the phrases
+func (e *APIError) mentions(phrases ...string) bool {
+	texts := []string{e.Detail, e.Body}
+	for _, detail := range e.Errors {
+		texts = append(texts, detail.Detail)
+	}
+
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, phrase := range phrases {
+			if strings.Contains(text, phrase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a response, reading the body if body is nil
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	if body == nil {
+		body, _ = io.ReadAll(resp.Body)
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+	}
+	if resp.Request != nil {
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	// Atlas wraps errors in {"error": {...}}, some endpoints return the fields at the top level
+	var envelope struct {
+		Error *struct {
+			Title  string           `json:"title"`
+			Detail string           `json:"detail"`
+			Errors []APIErrorDetail `json:"errors"`
+		} `json:"error"`
+		Detail string `json:"detail"`
+	}
+
+	switch {
+	case json.Unmarshal(body, &envelope) != nil:
+		apiErr.Body = strings.TrimSpace(string(body))
+	case envelope.Error != nil:
+		apiErr.Title = envelope.Error.Title
+		apiErr.Detail = envelope.Error.Detail
+		apiErr.Errors = envelope.Error.Errors
+	case envelope.Detail != "":
+		apiErr.Detail = envelope.Detail
+	default:
+		apiErr.Body = strings.TrimSpace(string(body))
+	}
+
+	return apiErr
+}
@@ -0,0 +1,105 @@
+package atlas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   []error
+	}{
+		{
+			name:   "unauthorized",
+			status: http.StatusUnauthorized,
+			body:   `{"error": {"detail": "Authentication credentials were not provided."}}`,
+			want:   []error{ErrAuth},
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			body:   `{"detail": "You do not have permission to perform this action."}`,
+			want:   []error{ErrAuth},
+		},
+		{
+			name:   "not enough credits",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"detail": "You do not have enough credits. Not enough credit to schedule this measurement."}}`,
+			want:   []error{ErrQuotaExceeded},
+		},
+		{
+			name:   "concurrent measurements",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"errors": [{"detail": "You have reached the limit of 100 concurrent measurements"}]}}`,
+			want:   []error{ErrQuotaExceeded},
+		},
+		{
+			name:   "daily spending limit",
+			status: http.StatusForbidden,
+			body:   `{"error": {"detail": "The daily spending limit of this account is reached"}}`,
+			want:   []error{ErrAuth, ErrQuotaExceeded},
+		},
+		{
+			name:   "validation error mentioning a limit",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"errors": [{"source": {"pointer": "/definitions/0/max_hops"}, "detail": "max hops exceeded"}]}}`,
+		},
+		{
+			name:   "validation error mentioning credits",
+			status: http.StatusBadRequest,
+			body:   `{"error": {"detail": "Invalid credits value"}}`,
+		},
+		{
+			name:   "rate limit",
+			status: http.StatusTooManyRequests,
+			body:   `{"detail": "Request was throttled, quota exceeded."}`,
+			want:   []error{ErrRateLimited},
+		},
+		{
+			name:   "unparsed body",
+			status: http.StatusBadGateway,
+			body:   `<html>Bad Gateway</html>`,
+		},
+	}
+
+	sentinels := []error{ErrAuth, ErrQuotaExceeded, ErrRateLimited, ErrNoSuitableProbes, ErrMeasurementFailed}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			err := fmt.Errorf("wrapped: %w", newAPIError(resp, nil))
+
+			for _, sentinel := range sentinels {
+				want := false
+				for _, w := range tt.want {
+					want = want || w == sentinel
+				}
+				if got := errors.Is(err, sentinel); got != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", err, sentinel, got, want)
+				}
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("errors.As did not find the APIError with status %d", tt.status)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	body := `{"error": {"title": "Bad Request", "detail": "Invalid definition", "errors": [{"source": {"pointer": "/definitions/0/target"}, "detail": "This field is required."}]}}`
+	resp := &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader(body))}
+
+	want := "API returned status 400: Invalid definition; /definitions/0/target: This field is required."
+	if got := newAPIError(resp, nil).Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
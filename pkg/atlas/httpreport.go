@@ -0,0 +1,119 @@
+package atlas
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// HTTPReport represents an HTTP reachability report. The embedded Report
+// holds the measurement information and probe distribution.
+type HTTPReport struct {
+	Report
+	URL   string
+	ByASN []HTTPASNSummary
+}
+
+// CertReport represents an SSL certificate report. The embedded Report holds
+// the measurement information and probe distribution.
+type CertReport struct {
+	Report
+	Expected   string // expected SHA-256 fingerprint, empty when not checked
+	ByASN      []CertASNSummary
+	Mismatches []CertMismatch
+}
+
+// GenerateHTTPReport creates a formatted text report for HTTP measurements
+func GenerateHTTPReport(report HTTPReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas HTTP Report")
+	writeMeasurementInfo(&sb, report.Report)
+	writeProbeDistribution(&sb, report.Report)
+
+	sb.WriteString(fmt.Sprintf("Responses by Source ASN (%s):\n\n", report.URL))
+	sb.WriteString(fmt.Sprintf("  %-9s %6s %8s %6s %11s %11s %11s  %s\n",
+		"ASN", "Probes", "Requests", "Errors", "TTFB med", "TTFB p95", "Total med", "Status codes"))
+
+	for _, s := range report.ByASN {
+		if s.Requests == s.Errors {
+			sb.WriteString(fmt.Sprintf("  AS%-7d %6d %8d %6d %11s %11s %11s  %s\n",
+				s.ASN, s.Probes, s.Requests, s.Errors, "-", "-", "-", "-"))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  AS%-7d %6d %8d %6d %8.1f ms %8.1f ms %8.1f ms  %s\n",
+			s.ASN, s.Probes, s.Requests, s.Errors, s.MedianTTFB, s.P95TTFB, s.MedianRT, formatStatusCodes(s.StatusCodes)))
+	}
+
+	sb.WriteString("\n" + Separator + "\n")
+
+	return sb.String()
+}
+
+// GenerateCertReport creates a formatted text report for SSL certificate measurements
+func GenerateCertReport(report CertReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas TLS Certificate Report")
+	writeMeasurementInfo(&sb, report.Report)
+	writeProbeDistribution(&sb, report.Report)
+
+	if report.Expected != "" {
+		sb.WriteString(fmt.Sprintf("Expected fingerprint (SHA-256):\n  %s\n\n", report.Expected))
+		sb.WriteString(Separator + "\n\n")
+	}
+
+	sb.WriteString("Certificates by Source ASN:\n\n")
+	for _, s := range report.ByASN {
+		sb.WriteString(fmt.Sprintf("  AS%-6d %d probes, %d errors, %d mismatches",
+			s.ASN, s.Probes, s.Errors, s.Mismatches))
+		if s.MedianRT > 0 {
+			sb.WriteString(fmt.Sprintf(", median handshake %.1f ms", s.MedianRT))
+		}
+		sb.WriteString("\n")
+
+		for _, fc := range s.Fingerprints {
+			mark := "✓"
+			if !fc.Match {
+				mark = "✗"
+			}
+			sb.WriteString(fmt.Sprintf("    %s %s  %d\n", mark, fc.Fingerprint, fc.Count))
+			sb.WriteString(fmt.Sprintf("      %s\n", fc.Subject))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(Separator + "\n\n")
+
+	if report.Expected != "" {
+		sb.WriteString(fmt.Sprintf("Fingerprint Mismatches (%d):\n\n", len(report.Mismatches)))
+		if len(report.Mismatches) == 0 {
+			sb.WriteString("  All probes were served the expected certificate.\n\n")
+		} else {
+			for _, m := range report.Mismatches {
+				sb.WriteString(fmt.Sprintf("  ⚠️  Probe %-7d AS%-6d → %s\n", m.ProbeID, m.ASN, m.DstAddr))
+				sb.WriteString(fmt.Sprintf("      %s\n", m.Fingerprint))
+				sb.WriteString(fmt.Sprintf("      %s\n", m.Subject))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(Separator + "\n")
+	}
+
+	return sb.String()
+}
+
+// formatStatusCodes renders status code counts as "200×5, 503×1"
+func formatStatusCodes(codes map[int]int) string {
+	keys := make([]int, 0, len(codes))
+	for code := range codes {
+		keys = append(keys, code)
+	}
+	slices.Sort(keys)
+
+	parts := make([]string, len(keys))
+	for i, code := range keys {
+		parts[i] = fmt.Sprintf("%d×%d", code, codes[code])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,109 @@
+package atlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Permissions an API key can be granted, as named in the grants of GetKey
+const (
+	PermissionScheduleMeasurement = "measurements.schedule_measurement"
+	PermissionStopMeasurement     = "measurements.stop_measurement"
+	PermissionViewResults         = "measurements.view_results"
+	PermissionViewCredits         = "credits.view_credits"
+)
+
+// APIKey describes an API key as returned by the keys endpoint
+type APIKey struct {
+	UUID      string     `json:"uuid"`
+	Label     string     `json:"label"`
+	Enabled   bool       `json:"enabled"`
+	IsActive  bool       `json:"is_active"`
+	ValidFrom *time.Time `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"` // nil if the key never expires
+	Grants    []KeyGrant `json:"grants"`
+}
+
+// KeyGrant is a single permission granted to an API key
+type KeyGrant struct {
+	Permission string `json:"permission"`
+	Target     *struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"target"` // nil for grants that are not limited to one object
+}
+
+// HasPermission reports whether the key holds an unrestricted grant of permission
+func (k *APIKey) HasPermission(permission string) bool {
+	return slices.ContainsFunc(k.Grants, func(g KeyGrant) bool {
+		return g.Permission == permission && g.Target == nil
+	})
+}
+
+// Usable reports whether the key is enabled and within its validity period at
+// time now, and if not, why
+func (k *APIKey) Usable(now time.Time) (bool, string) {
+	switch {
+	case !k.Enabled:
+		return false, "disabled"
+	case k.ValidFrom != nil && now.Before(*k.ValidFrom):
+		return false, fmt.Sprintf("not valid before %s", k.ValidFrom.UTC().Format(time.DateTime))
+	case k.ValidTo != nil && !now.Before(*k.ValidTo):
+		return false, fmt.Sprintf("expired %s", k.ValidTo.UTC().Format(time.DateTime))
+	case !k.IsActive:
+		return false, "inactive"
+	}
+	return true, ""
+}
+
+// Credits is the credit balance of the account owning the API key
+type Credits struct {
+	CurrentBalance            int64 `json:"current_balance"`
+	EstimatedDailyIncome      int64 `json:"estimated_daily_income"`
+	EstimatedDailyExpenditure int64 `json:"estimated_daily_expenditure"`
+}
+
+// GetKey looks up an API key, authenticating with the key itself. A key that
+// does not exist matches ErrAuth.
+func (c *Client) GetKey(ctx context.Context, key string) (*APIKey, error) {
+	req, err := c.newRequest(ctx, "GET", fmt.Sprintf("%s/keys/%s/", c.baseURL, url.PathEscape(key)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Key %s", key))
+
+	var apiKey APIKey
+	if err := c.doJSON(req, &apiKey); err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: key not found", ErrAuth)
+		}
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	return &apiKey, nil
+}
+
+// GetCredits returns the credit balance of the account owning the client's
+// key. It authenticates with the main key even when a read key is set, as
+// that is the key measurements are paid with.
+func (c *Client) GetCredits(ctx context.Context) (*Credits, error) {
+	req, err := c.newRequest(ctx, "GET", c.baseURL+"/credits/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Key %s", c.apiKey))
+	}
+
+	var credits Credits
+	if err := c.doJSON(req, &credits); err != nil {
+		return nil, fmt.Errorf("failed to get credits: %w", err)
+	}
+	return &credits, nil
+}
@@ -0,0 +1,111 @@
+package atlas_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+func TestGetKey(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.APIKey = "test"
+	srv.KeyPermissions = []string{atlas.PermissionViewResults}
+
+	key, err := client.GetKey(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("GetKey: %v", err)
+	}
+	if key.UUID != "test" || !key.Enabled || !key.IsActive {
+		t.Errorf("key = %+v, want the enabled key test", key)
+	}
+	if !key.HasPermission(atlas.PermissionViewResults) || key.HasPermission(atlas.PermissionScheduleMeasurement) {
+		t.Errorf("grants = %+v, want view results only", key.Grants)
+	}
+
+	if _, err := client.GetKey(context.Background(), "unknown"); !errors.Is(err, atlas.ErrAuth) {
+		t.Errorf("GetKey of an unknown key = %v, want ErrAuth", err)
+	}
+}
+
+func TestGetCreditsUsesMainKey(t *testing.T) {
+	srv, client := newTestClient(t, atlas.WithReadKey("reader"))
+	srv.APIKey = "test"
+	srv.Credits = 4242
+
+	credits, err := client.GetCredits(context.Background())
+	if err != nil {
+		t.Fatalf("GetCredits with a read key: %v", err)
+	}
+	if credits.CurrentBalance != 4242 {
+		t.Errorf("balance = %d, want 4242", credits.CurrentBalance)
+	}
+
+	srv.APIKey = "other"
+	if _, err := client.GetCredits(context.Background()); !errors.Is(err, atlas.ErrAuth) {
+		t.Errorf("GetCredits with a wrong key = %v, want ErrAuth", err)
+	}
+}
+
+func TestAPIKeyUsable(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		key        atlas.APIKey
+		wantUsable bool
+		wantReason string
+	}{
+		{"enabled and active", atlas.APIKey{Enabled: true, IsActive: true}, true, ""},
+		{"within its validity", atlas.APIKey{Enabled: true, IsActive: true, ValidFrom: &past, ValidTo: &future}, true, ""},
+		{"disabled", atlas.APIKey{IsActive: true}, false, "disabled"},
+		{"not yet valid", atlas.APIKey{Enabled: true, IsActive: true, ValidFrom: &future}, false, "not valid before 2024-06-01 13:00:00"},
+		{"expired", atlas.APIKey{Enabled: true, IsActive: true, ValidTo: &past}, false, "expired 2024-06-01 11:00:00"},
+		{"expires now", atlas.APIKey{Enabled: true, IsActive: true, ValidTo: &now}, false, "expired 2024-06-01 12:00:00"},
+		{"inactive", atlas.APIKey{Enabled: true}, false, "inactive"},
+		{"disabled wins over expired", atlas.APIKey{ValidTo: &past}, false, "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usable, reason := tt.key.Usable(now)
+			if usable != tt.wantUsable || reason != tt.wantReason {
+				t.Errorf("Usable = (%v, %q), want (%v, %q)", usable, reason, tt.wantUsable, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHasPermission(t *testing.T) {
+	restricted := atlas.KeyGrant{Permission: atlas.PermissionStopMeasurement}
+	restricted.Target = &struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}{Type: "measurement", ID: "1000001"}
+
+	key := atlas.APIKey{Grants: []atlas.KeyGrant{
+		{Permission: atlas.PermissionScheduleMeasurement},
+		restricted,
+	}}
+
+	tests := []struct {
+		permission string
+		want       bool
+	}{
+		{atlas.PermissionScheduleMeasurement, true},
+		{atlas.PermissionStopMeasurement, false}, // only granted for one measurement
+		{atlas.PermissionViewCredits, false},
+	}
+
+	for _, tt := range tests {
+		if got := key.HasPermission(tt.permission); got != tt.want {
+			t.Errorf("HasPermission(%s) = %v, want %v", tt.permission, got, tt.want)
+		}
+	}
+	if (&atlas.APIKey{}).HasPermission(atlas.PermissionScheduleMeasurement) {
+		t.Error("a key without grants has a permission")
+	}
+}
@@ -0,0 +1,64 @@
+package atlas
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LatencyReport represents a ping latency report. The embedded Report holds
+// the measurement information and probe distribution.
+type LatencyReport struct {
+	Report
+	Overall   LatencyStats
+	ByASN     []LatencyStats
+	ByCountry []LatencyStats
+}
+
+// GenerateLatencyReport creates a formatted text report for ping measurements
+func GenerateLatencyReport(report LatencyReport) string {
+	var sb strings.Builder
+
+	writeHeader(&sb, "RIPE Atlas Ping Latency Report")
+	writeMeasurementInfo(&sb, report.Report)
+	writeProbeDistribution(&sb, report.Report)
+
+	sb.WriteString("Latency by Source ASN:\n\n")
+	writeLatencyTable(&sb, "ASN", report.ByASN)
+
+	sb.WriteString("Latency by Country:\n\n")
+	writeLatencyTable(&sb, "Country", report.ByCountry)
+
+	sb.WriteString("Overall:\n\n")
+	writeLatencyTable(&sb, "", []LatencyStats{report.Overall})
+
+	sb.WriteString(Separator + "\n")
+
+	return sb.String()
+}
+
+// writeLatencyTable writes one row of latency statistics per group
+func writeLatencyTable(sb *strings.Builder, groupHeader string, stats []LatencyStats) {
+	sb.WriteString(fmt.Sprintf("  %-22s %6s %9s %9s %9s %9s %7s\n",
+		groupHeader, "Probes", "Min", "Median", "P95", "Max", "Loss"))
+
+	for _, s := range stats {
+		if s.Received == 0 {
+			sb.WriteString(fmt.Sprintf("  %-22s %6d %9s %9s %9s %9s %6.1f%%\n",
+				truncate(s.Group, 22), s.Probes, "-", "-", "-", "-", s.Loss))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %-22s %6d %6.1f ms %6.1f ms %6.1f ms %6.1f ms %6.1f%%\n",
+			truncate(s.Group, 22), s.Probes, s.Min, s.Median, s.P95, s.Max, s.Loss))
+	}
+
+	sb.WriteString("\n")
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
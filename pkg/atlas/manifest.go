@@ -0,0 +1,92 @@
+package atlas
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SelectionManifest records the vantage points and targets of a run, so a
+// later run can use exactly the same probes. Probes Atlas picked for
+// server-side selectors are only known after the measurement ran; replay
+// those with an "msm" probe set of one of the recorded measurements.
+type SelectionManifest struct {
+	CreatedAt    time.Time             `json:"created_at"`
+	Seed         int64                 `json:"seed"`
+	Allocator    string                `json:"allocator,omitempty"`
+	Probes       []int                 `json:"probes"`
+	Allocations  []ManifestAllocation  `json:"allocations,omitempty"`
+	Selectors    []ProbeSet            `json:"selectors,omitempty"`
+	Measurements []ManifestMeasurement `json:"measurements"`
+}
+
+// ManifestAllocation is the probes picked from one ASN
+type ManifestAllocation struct {
+	ASN       int   `json:"asn"`
+	Available int   `json:"available"`
+	Probes    []int `json:"probes"`
+}
+
+// ManifestMeasurement is one measurement of a run, without ID for dry runs
+type ManifestMeasurement struct {
+	ID     int    `json:"id,omitempty"`
+	Type   string `json:"type"`
+	AF     int    `json:"af"`
+	Target string `json:"target"`
+}
+
+// ProbeAllocations returns the allocations recorded in the manifest
+func (m *SelectionManifest) ProbeAllocations() []ProbeAllocation {
+	allocations := make([]ProbeAllocation, len(m.Allocations))
+	for i, alloc := range m.Allocations {
+		allocations[i] = ProbeAllocation{
+			ASN:       alloc.ASN,
+			Available: alloc.Available,
+			Allocated: len(alloc.Probes),
+			ProbeIDs:  alloc.Probes,
+		}
+	}
+	return allocations
+}
+
+// MeasurementIDs returns the IDs of the recorded measurements, none for dry runs
+func (m *SelectionManifest) MeasurementIDs() []int {
+	var ids []int
+	for _, msm := range m.Measurements {
+		if msm.ID != 0 {
+			ids = append(ids, msm.ID)
+		}
+	}
+	return ids
+}
+
+// WriteManifestFile writes a selection manifest as indented JSON
+func WriteManifestFile(path string, m *SelectionManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest file: %w", err)
+	}
+	return nil
+}
+
+// ReadManifestFile reads a selection manifest written by WriteManifestFile
+func ReadManifestFile(path string) (*SelectionManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	var m SelectionManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", path, err)
+	}
+	if len(m.Probes) == 0 && len(m.Selectors) == 0 {
+		return nil, fmt.Errorf("manifest %s lists no probes", path)
+	}
+	return &m, nil
+}
@@ -0,0 +1,87 @@
+package atlas
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	want := &SelectionManifest{
+		CreatedAt: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Seed:      42,
+		Allocator: "equal",
+		Probes:    []int{1001, 1002, 2001},
+		Allocations: []ManifestAllocation{
+			{ASN: 64500, Available: 3, Probes: []int{1001, 1002}},
+			{ASN: 64501, Available: 4, Probes: []int{2001}},
+		},
+		Selectors: []ProbeSet{{Type: ProbeSetCountry, Value: "DE", Requested: 5, Tags: &ProbeTags{Include: []string{TagIPv4Works}}}},
+		Measurements: []ManifestMeasurement{
+			{ID: 1000001, Type: "traceroute", AF: 4, Target: "192.0.2.65"},
+			{ID: 1000002, Type: "traceroute", AF: 4, Target: "192.0.2.65"},
+			{Type: "ping", AF: 6, Target: "2001:db8::1"},
+		},
+	}
+
+	if err := WriteManifestFile(path, want); err != nil {
+		t.Fatalf("WriteManifestFile: %v", err)
+	}
+	got, err := ReadManifestFile(path)
+	if err != nil {
+		t.Fatalf("ReadManifestFile: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+
+	allocations := got.ProbeAllocations()
+	if len(allocations) != 2 || allocations[0].Allocated != 2 || !slices.Equal(allocations[1].ProbeIDs, []int{2001}) {
+		t.Errorf("allocations = %+v", allocations)
+	}
+	if ids := got.MeasurementIDs(); !slices.Equal(ids, []int{1000001, 1000002}) {
+		t.Errorf("measurement IDs = %v, want the two created ones", ids)
+	}
+}
+
+func TestReadManifestFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"not JSON", "probes: [1]", "failed to decode manifest"},
+		{"no probes", `{"seed": 1, "probes": [], "measurements": []}`, "lists no probes"},
+		{"selectors only", `{"probes": [], "selectors": [{"type": "country", "value": "DE", "requested": 5}]}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ReadManifestFile(path)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ReadManifestFile: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadManifestFile = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadManifestFile(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("reading a missing manifest = %v, want a not exist error", err)
+	}
+}
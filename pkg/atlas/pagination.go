@@ -0,0 +1,105 @@
+package atlas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Page is the envelope the Atlas API wraps around paginated list responses
+type Page[T any] struct {
+	Count    int     `json:"count"`
+	Next     *string `json:"next"`
+	Previous *string `json:"previous"`
+	Results  []T     `json:"results"`
+}
+
+// pageIterator walks a paginated list endpoint, following next links lazily
+type pageIterator[T any] struct {
+	ctx    context.Context
+	client *Client
+	next   string
+	buf    []T
+	cur    T
+	count  int
+	err    error
+}
+
+// Next advances to the next item, fetching the following page when needed.
+// It returns false when the list is exhausted or an error occurred.
+func (it *pageIterator[T]) Next() bool {
+	for len(it.buf) == 0 {
+		if it.err != nil || it.next == "" {
+			return false
+		}
+		it.fetch()
+	}
+
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Err returns the first error encountered while fetching pages
+func (it *pageIterator[T]) Err() error {
+	return it.err
+}
+
+// Count returns the total item count reported by the API (available after the first page)
+func (it *pageIterator[T]) Count() int {
+	return it.count
+}
+
+// fetch retrieves the page pointed to by it.next
+func (it *pageIterator[T]) fetch() {
+	req, err := it.client.newRequest(it.ctx, "GET", it.next, nil)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	resp, err := it.client.httpClient.Do(req)
+	if err != nil {
+		it.err = fmt.Errorf("failed to execute request: %w", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		it.err = newAPIError(resp, nil)
+		return
+	}
+
+	var page Page[T]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		it.err = fmt.Errorf("failed to decode response: %w", err)
+		return
+	}
+
+	it.buf = page.Results
+	it.count = page.Count
+	it.next = ""
+	if page.Next != nil {
+		it.next = *page.Next
+	}
+}
+
+// ProbeIterator iterates over the probes matched by a probe search
+type ProbeIterator struct {
+	pageIterator[Probe]
+}
+
+// Probe returns the current probe
+func (it *ProbeIterator) Probe() Probe {
+	return it.cur
+}
+
+// MeasurementIterator iterates over a list of measurements
+type MeasurementIterator struct {
+	pageIterator[MeasurementStatus]
+}
+
+// Measurement returns the current measurement
+func (it *MeasurementIterator) Measurement() MeasurementStatus {
+	return it.cur
+}
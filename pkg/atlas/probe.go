@@ -3,13 +3,38 @@ package atlas
 import (
 	"fmt"
 	"math/rand"
+	"slices"
 )
 
 const MaxProbesPerMeasurement = 1000
 
+// AllocationOptions tune how many probes AllocateProbesWithOptions takes from
+// each ASN and which ones it picks
+type AllocationOptions struct {
+	// PreferAnchors picks an ASN's anchors before its other probes
+	PreferAnchors bool
+
+	// Sampling is SamplingRandom (the default when empty) for a uniform pick,
+	// or SamplingGeo to spread picks across locations
+	Sampling string
+
+	// Allocator decides how many probes each ASN contributes, EqualAllocator
+	// when nil
+	Allocator Allocator
+
+	// Rand is the source of random picks, the global math/rand source when
+	// nil. A seeded source makes the picks reproducible for the same probes.
+	Rand *rand.Rand
+}
+
 // AllocateProbes allocates probes from multiple ASNs
 // Returns allocation details and any ASNs without probes
 func AllocateProbes(probesByASN map[int][]Probe, requestedASNs []int) ([]ProbeAllocation, []int, error) {
+	return AllocateProbesWithOptions(probesByASN, requestedASNs, AllocationOptions{})
+}
+
+// AllocateProbesWithOptions is AllocateProbes with control over the quotas and picks
+func AllocateProbesWithOptions(probesByASN map[int][]Probe, requestedASNs []int, opts AllocationOptions) ([]ProbeAllocation, []int, error) {
 	if len(requestedASNs) == 0 {
 		return nil, nil, fmt.Errorf("no ASNs provided")
 	}
@@ -28,68 +53,43 @@ func AllocateProbes(probesByASN map[int][]Probe, requestedASNs []int) ([]ProbeAl
 	}
 
 	if len(asnsWithProbes) == 0 {
-		return nil, asnsWithoutProbes, fmt.Errorf("no ASNs have available probes")
+		return nil, asnsWithoutProbes, fmt.Errorf("%w: no ASNs have available probes", ErrNoSuitableProbes)
 	}
 
-	// Calculate initial quota per ASN
-	quotaPerASN := MaxProbesPerMeasurement / len(asnsWithProbes)
+	allocator := opts.Allocator
+	if allocator == nil {
+		allocator = EqualAllocator{}
+	}
 
-	// First pass: allocate based on quota
-	allocations := make([]ProbeAllocation, 0, len(asnsWithProbes))
-	totalAllocated := 0
+	available := make(map[int]int, len(asnsWithProbes))
+	for _, asn := range asnsWithProbes {
+		available[asn] = len(probesByASN[asn])
+	}
+	quotas, err := allocator.Quotas(asnsWithProbes, available)
+	if err != nil {
+		return nil, asnsWithoutProbes, fmt.Errorf("%s allocation: %w", allocator.Name(), err)
+	}
 
+	allocations := make([]ProbeAllocation, 0, len(asnsWithProbes))
 	for _, asn := range asnsWithProbes {
 		probes := probesByASN[asn]
-		available := len(probes)
-		allocated := min(available, quotaPerASN)
+		allocated := min(len(probes), quotas[asn])
 
-		allocation := ProbeAllocation{
+		allocations = append(allocations, ProbeAllocation{
 			ASN:       asn,
-			Available: available,
+			Available: len(probes),
 			Allocated: allocated,
-			ProbeIDs:  selectRandomProbes(probes, allocated),
-		}
-
-		allocations = append(allocations, allocation)
-		totalAllocated += allocated
-	}
-
-	// Second pass: redistribute remaining quota (greedy allocation)
-	remaining := MaxProbesPerMeasurement - totalAllocated
-
-	for remaining > 0 {
-		distributed := false
-
-		for i := range allocations {
-			if allocations[i].Allocated < allocations[i].Available {
-				// This ASN has more probes available
-				additionalProbes := min(allocations[i].Available-allocations[i].Allocated, remaining)
-
-				// Get additional random probes
-				allProbes := probesByASN[allocations[i].ASN]
-				allocations[i].Allocated += additionalProbes
-				allocations[i].ProbeIDs = selectRandomProbes(allProbes, allocations[i].Allocated)
-
-				remaining -= additionalProbes
-				distributed = true
-
-				if remaining == 0 {
-					break
-				}
-			}
-		}
-
-		// If we couldn't distribute any more, break to avoid infinite loop
-		if !distributed {
-			break
-		}
+			ProbeIDs:  selectRandomProbes(probes, allocated, opts),
+		})
 	}
 
 	return allocations, asnsWithoutProbes, nil
 }
 
-// selectRandomProbes randomly selects n probes from the list without replacement
-func selectRandomProbes(probes []Probe, n int) []int {
+// selectRandomProbes randomly selects n probes from the list without replacement,
+// spread across locations with SamplingGeo and anchors first when
+// opts.PreferAnchors is set
+func selectRandomProbes(probes []Probe, n int, opts AllocationOptions) []int {
 	if n >= len(probes) {
 		// Return all probe IDs
 		ids := make([]int, len(probes))
@@ -107,10 +107,20 @@ func selectRandomProbes(probes []Probe, n int) []int {
 
 	// Shuffle using Fisher-Yates algorithm
 	for i := len(indices) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
+		j := intn(opts.Rand, i+1)
 		indices[i], indices[j] = indices[j], indices[i]
 	}
 
+	if opts.Sampling == SamplingGeo {
+		indices = geoSpread(probes, indices)
+	}
+
+	if opts.PreferAnchors {
+		slices.SortStableFunc(indices, func(a, b int) int {
+			return compareBool(probes[b].IsAnchor, probes[a].IsAnchor)
+		})
+	}
+
 	// Take first n probe IDs
 	result := make([]int, n)
 	for i := 0; i < n; i++ {
@@ -120,6 +130,25 @@ func selectRandomProbes(probes []Probe, n int) []int {
 	return result
 }
 
+// intn returns a random int in [0, n) from rng, or the global source when rng is nil
+func intn(rng *rand.Rand, n int) int {
+	if rng == nil {
+		return rand.Intn(n)
+	}
+	return rng.Intn(n)
+}
+
+// compareBool orders false before true
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -0,0 +1,69 @@
+package atlas
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// System tags used by probe quality filters, besides TagIPv6Works
+const (
+	TagIPv4Works     = "system-ipv4-works"
+	TagIPv4RFC1918   = "system-ipv4-rfc1918"
+	TagIPv4Stable90d = "system-ipv4-stable-90d"
+	TagIPv6Stable90d = "system-ipv6-stable-90d"
+)
+
+// ProbeStatusConnected is the Status.ID of probes currently connected to Atlas
+const ProbeStatusConnected = 1
+
+// HasTag reports whether the probe carries the tag with the given slug
+func (p Probe) HasTag(slug string) bool {
+	return slices.ContainsFunc(p.Tags, func(t ProbeTag) bool { return t.Slug == slug })
+}
+
+// UptimeRatio returns the fraction of time the probe has been connected since
+// it first connected, or 0 when the API did not report it
+func (p Probe) UptimeRatio(now time.Time) float64 {
+	if p.FirstConnected <= 0 || p.TotalUptime <= 0 {
+		return 0
+	}
+	age := now.Unix() - p.FirstConnected
+	if age <= 0 {
+		return 0
+	}
+	return math.Min(float64(p.TotalUptime)/float64(age), 1)
+}
+
+// ProbeFilter drops probes unsuitable as vantage points before allocation
+type ProbeFilter struct {
+	IncludeTags    []string // probes must carry all of these
+	ExcludeTags    []string // probes must carry none of these
+	MinUptime      float64  // minimum UptimeRatio, 0 disables
+	ExcludeAnchors bool
+}
+
+// Accept reports whether the probe passes the filter. Probes reported as
+// anything but connected are always dropped, those without a status kept.
+func (f ProbeFilter) Accept(p Probe) bool {
+	if p.Status.Name != "" && p.Status.ID != ProbeStatusConnected {
+		return false
+	}
+	for _, tag := range f.IncludeTags {
+		if !p.HasTag(tag) {
+			return false
+		}
+	}
+	for _, tag := range f.ExcludeTags {
+		if p.HasTag(tag) {
+			return false
+		}
+	}
+	if f.MinUptime > 0 && p.UptimeRatio(time.Now()) < f.MinUptime {
+		return false
+	}
+	if f.ExcludeAnchors && p.IsAnchor {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package atlas
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestUptimeRatio(t *testing.T) {
+	now := time.Unix(1_000_000, 0)
+
+	tests := []struct {
+		name           string
+		firstConnected int64
+		totalUptime    int64
+		want           float64
+	}{
+		{"never connected", 0, 0, 0},
+		{"no uptime reported", 500_000, 0, 0},
+		{"first connected now", now.Unix(), 10, 0},
+		{"first connected in the future", now.Unix() + 10, 10, 0},
+		{"always up", 500_000, 500_000, 1},
+		{"half the time", 600_000, 200_000, 0.5},
+		{"uptime beyond the age is capped", 900_000, 200_000, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Probe{FirstConnected: tt.firstConnected, TotalUptime: tt.totalUptime}
+			if got := p.UptimeRatio(now); got != tt.want {
+				t.Errorf("UptimeRatio = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeFilterAccept(t *testing.T) {
+	connected := Status{ID: ProbeStatusConnected, Name: "Connected"}
+	firstConnected := time.Now().AddDate(-1, 0, 0).Unix()
+	age := time.Now().Unix() - firstConnected
+	tags := func(slugs ...string) []ProbeTag {
+		var tags []ProbeTag
+		for _, slug := range slugs {
+			tags = append(tags, ProbeTag{Slug: slug})
+		}
+		return tags
+	}
+
+	// stable is up 99% of the time, flaky half of it
+	stable := Probe{ID: 1, Status: connected, FirstConnected: firstConnected, TotalUptime: age * 99 / 100, Tags: tags(TagIPv4Works, TagIPv4Stable90d)}
+	flaky := Probe{ID: 2, Status: connected, FirstConnected: firstConnected, TotalUptime: age / 2, Tags: tags(TagIPv4Works)}
+	natted := Probe{ID: 3, Status: connected, Tags: tags(TagIPv4Works, TagIPv4RFC1918)}
+	anchor := Probe{ID: 4, Status: connected, FirstConnected: firstConnected, TotalUptime: age, IsAnchor: true, Tags: tags(TagIPv4Works, TagIPv4Stable90d)}
+	disconnected := Probe{ID: 5, Status: Status{ID: 2, Name: "Disconnected"}, Tags: tags(TagIPv4Works)}
+	neverConnected := Probe{ID: 6, Status: Status{ID: 0, Name: "Never Connected"}}
+	unreported := Probe{ID: 7}
+	probes := []Probe{stable, flaky, natted, anchor, disconnected, neverConnected, unreported}
+
+	tests := []struct {
+		name   string
+		filter ProbeFilter
+		want   []int
+	}{
+		{"no filter", ProbeFilter{}, []int{1, 2, 3, 4, 7}},
+		{"include tag", ProbeFilter{IncludeTags: []string{TagIPv4Works}}, []int{1, 2, 3, 4}},
+		{"include all tags", ProbeFilter{IncludeTags: []string{TagIPv4Works, TagIPv4Stable90d}}, []int{1, 4}},
+		{"exclude tag", ProbeFilter{ExcludeTags: []string{TagIPv4RFC1918}}, []int{1, 2, 4, 7}},
+		{"exclude any tag", ProbeFilter{ExcludeTags: []string{TagIPv4RFC1918, TagIPv4Stable90d}}, []int{2, 7}},
+		{"min uptime", ProbeFilter{MinUptime: 0.9}, []int{1, 4}},
+		{"min uptime of half", ProbeFilter{MinUptime: 0.5}, []int{1, 2, 4}},
+		{"exclude anchors", ProbeFilter{ExcludeAnchors: true}, []int{1, 2, 3, 7}},
+		{"tags and uptime", ProbeFilter{IncludeTags: []string{TagIPv4Works}, MinUptime: 0.9}, []int{1, 4}},
+		{"tags and anchors", ProbeFilter{ExcludeTags: []string{TagIPv4RFC1918}, ExcludeAnchors: true}, []int{1, 2, 7}},
+		{"uptime and anchors", ProbeFilter{MinUptime: 0.9, ExcludeAnchors: true}, []int{1}},
+		{"everything", ProbeFilter{
+			IncludeTags:    []string{TagIPv4Works},
+			ExcludeTags:    []string{TagIPv4RFC1918},
+			MinUptime:      0.4,
+			ExcludeAnchors: true,
+		}, []int{1, 2}},
+		{"nothing passes", ProbeFilter{IncludeTags: []string{TagIPv6Works}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, p := range probes {
+				if tt.filter.Accept(p) {
+					got = append(got, p.ID)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("accepted %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,13 +15,17 @@ const (
 // Report represents a complete analysis report
 type Report struct {
 	MeasurementID     int
+	MeasurementIDs    []int // all merged measurements when more than one was analyzed
 	Target            string
+	AF                int // address family of the measurement, 0 when unknown
 	CreatedAt         time.Time
 	Duration          time.Duration
 	RequestedASNs     []int
 	ASNsWithProbes    []int
 	ASNsWithoutProbes []int
 	Allocations       []ProbeAllocation
+	Allocator         string   // allocation strategy and parameters, e.g. "equal (budget=500)"
+	Selectors         []string // server-side probe sets, e.g. "country DE (10 probes)"
 	CommonASNs        []ASNInfo
 	Threshold         float64
 	TotalProbes       int
@@ -29,29 +33,93 @@ type Report struct {
 	AvgHops           float64
 	MaxHops           int
 	IncompletePaths   int
+	Partial           bool // measurement was stopped before all probes reported
+	ResultCount       int
 }
 
 // GenerateReport creates a formatted text report
 func GenerateReport(report Report) string {
 	var sb strings.Builder
 
-	// Header
+	writeHeader(&sb, "RIPE Atlas Traceroute Analysis Report")
+	writeMeasurementInfo(&sb, report)
+	writeProbeDistribution(&sb, report)
+
+	// Common Path Analysis
+	sb.WriteString(fmt.Sprintf("Common Path Analysis (Threshold: %.1f%% = %d/%d probes):\n\n",
+		report.Threshold*100,
+		int(report.Threshold*float64(report.TotalProbes)),
+		report.TotalProbes))
+
+	if len(report.CommonASNs) == 0 {
+		sb.WriteString("  No common ASNs found meeting the threshold.\n\n")
+	} else {
+		for i, asn := range report.CommonASNs {
+			sb.WriteString(fmt.Sprintf("  %d. AS%d - %s\n", i+1, asn.ASN, asn.Name))
+			sb.WriteString(fmt.Sprintf("     Frequency: %.1f%% (%d/%d probes)\n",
+				asn.Percentage, asn.Occurrences, report.TotalProbes))
+			sb.WriteString(fmt.Sprintf("     Average position: Hop %d-%d\n\n",
+				asn.AvgHopStart, asn.AvgHopEnd))
+		}
+	}
+
+	sb.WriteString(Separator + "\n\n")
+
+	// Path Diversity Summary
+	sb.WriteString("Path Diversity Summary:\n")
+	sb.WriteString(fmt.Sprintf("  • Unique paths: %d\n", report.UniquePaths))
+	sb.WriteString(fmt.Sprintf("  • Average hops: %.1f\n", report.AvgHops))
+	sb.WriteString(fmt.Sprintf("  • Max hops reached: %d\n", report.MaxHops))
+	sb.WriteString(fmt.Sprintf("  • Incomplete paths: %d (%.1f%%)\n\n",
+		report.IncompletePaths,
+		float64(report.IncompletePaths)/float64(max(report.ResultCount, 1))*100))
+
+	sb.WriteString(Separator + "\n")
+
+	return sb.String()
+}
+
+// writeHeader writes the boxed report title
+func writeHeader(sb *strings.Builder, title string) {
 	sb.WriteString(BoxTop + "\n")
-	sb.WriteString(centerText("RIPE Atlas Traceroute Analysis Report", 62) + "\n")
+	sb.WriteString(centerText(title, 62) + "\n")
 	sb.WriteString(BoxBottom + "\n\n")
+}
 
-	// Measurement Information
+// writeMeasurementInfo writes the measurement information section
+func writeMeasurementInfo(sb *strings.Builder, report Report) {
 	sb.WriteString("Measurement Information:\n")
-	sb.WriteString(fmt.Sprintf("  • Measurement ID: %d\n", report.MeasurementID))
+	if len(report.MeasurementIDs) > 1 {
+		sb.WriteString(fmt.Sprintf("  • Measurement IDs: %s\n", joinInts(report.MeasurementIDs)))
+	} else {
+		sb.WriteString(fmt.Sprintf("  • Measurement ID: %d\n", report.MeasurementID))
+	}
 	sb.WriteString(fmt.Sprintf("  • Target: %s\n", report.Target))
-	sb.WriteString(fmt.Sprintf("  • Status: Completed ✓\n"))
+	if report.AF != 0 {
+		sb.WriteString(fmt.Sprintf("  • Address family: IPv%d\n", report.AF))
+	}
+	if report.Partial {
+		sb.WriteString(fmt.Sprintf("  • Status: Stopped early (partial results: %d/%d probes)\n", report.ResultCount, report.TotalProbes))
+	} else {
+		sb.WriteString(fmt.Sprintf("  • Status: Completed ✓\n"))
+	}
 	sb.WriteString(fmt.Sprintf("  • Created: %s\n", report.CreatedAt.Format("2006-01-02 15:04:05 MST")))
 	sb.WriteString(fmt.Sprintf("  • Duration: %s\n", formatDuration(report.Duration)))
-	sb.WriteString(fmt.Sprintf("  • View online: https://atlas.ripe.net/measurements/%d\n\n", report.MeasurementID))
+	if len(report.MeasurementIDs) > 1 {
+		sb.WriteString("  • View online:\n")
+		for _, id := range report.MeasurementIDs {
+			sb.WriteString(fmt.Sprintf("      https://atlas.ripe.net/measurements/%d\n", id))
+		}
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("  • View online: https://atlas.ripe.net/measurements/%d\n\n", report.MeasurementID))
+	}
 
 	sb.WriteString(Separator + "\n\n")
+}
 
-	// Probe Distribution
+// writeProbeDistribution writes the per-ASN probe allocation section
+func writeProbeDistribution(sb *strings.Builder, report Report) {
 	sb.WriteString("Probe Distribution:\n")
 	sb.WriteString(fmt.Sprintf("  Requested ASNs: %d\n", len(report.RequestedASNs)))
 
@@ -66,6 +134,9 @@ func GenerateReport(report Report) string {
 	}
 
 	sb.WriteString("\n  Probe Allocation:\n")
+	if report.Allocator != "" {
+		sb.WriteString(fmt.Sprintf("    Strategy: %s\n", report.Allocator))
+	}
 	for _, alloc := range report.Allocations {
 		percentage := float64(alloc.Allocated) / float64(report.TotalProbes) * 100
 		bar := createProgressBar(percentage, 20)
@@ -73,43 +144,14 @@ func GenerateReport(report Report) string {
 			alloc.ASN, bar, alloc.Allocated, percentage))
 	}
 
+	for _, selector := range report.Selectors {
+		sb.WriteString(fmt.Sprintf("    Selected by Atlas: %s\n", selector))
+	}
+
 	sb.WriteString(fmt.Sprintf("    %s\n", strings.Repeat("─", 45)))
 	sb.WriteString(fmt.Sprintf("    Total:  %32d probes\n\n", report.TotalProbes))
 
 	sb.WriteString(Separator + "\n\n")
-
-	// Common Path Analysis
-	sb.WriteString(fmt.Sprintf("Common Path Analysis (Threshold: %.1f%% = %d/%d probes):\n\n",
-		report.Threshold*100,
-		int(report.Threshold*float64(report.TotalProbes)),
-		report.TotalProbes))
-
-	if len(report.CommonASNs) == 0 {
-		sb.WriteString("  No common ASNs found meeting the threshold.\n\n")
-	} else {
-		for i, asn := range report.CommonASNs {
-			sb.WriteString(fmt.Sprintf("  %d. AS%d - %s\n", i+1, asn.ASN, asn.Name))
-			sb.WriteString(fmt.Sprintf("     Frequency: %.1f%% (%d/%d probes)\n",
-				asn.Percentage, asn.Occurrences, report.TotalProbes))
-			sb.WriteString(fmt.Sprintf("     Average position: Hop %d-%d\n\n",
-				asn.AvgHopStart, asn.AvgHopEnd))
-		}
-	}
-
-	sb.WriteString(Separator + "\n\n")
-
-	// Path Diversity Summary
-	sb.WriteString("Path Diversity Summary:\n")
-	sb.WriteString(fmt.Sprintf("  • Unique paths: %d\n", report.UniquePaths))
-	sb.WriteString(fmt.Sprintf("  • Average hops: %.1f\n", report.AvgHops))
-	sb.WriteString(fmt.Sprintf("  • Max hops reached: %d\n", report.MaxHops))
-	sb.WriteString(fmt.Sprintf("  • Incomplete paths: %d (%.1f%%)\n\n",
-		report.IncompletePaths,
-		float64(report.IncompletePaths)/float64(report.TotalProbes)*100))
-
-	sb.WriteString(Separator + "\n")
-
-	return sb.String()
 }
 
 // centerText centers text within a given width
@@ -145,6 +187,15 @@ func formatIntList(nums []int) string {
 	return strings.Join(strs, ", ")
 }
 
+// joinInts formats a list of integers as a comma-separated string
+func joinInts(nums []int) string {
+	strs := make([]string, len(nums))
+	for i, n := range nums {
+		strs[i] = fmt.Sprintf("%d", n)
+	}
+	return strings.Join(strs, ", ")
+}
+
 // formatDuration formats a duration in a human-readable way
 func formatDuration(d time.Duration) string {
 	if d < time.Minute {
@@ -154,3 +205,69 @@ func formatDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%.1f hours", d.Hours())
 }
+
+// FormatMeasurementStatus renders the status of a single measurement
+func FormatMeasurementStatus(status MeasurementStatus) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Measurement %d:\n", status.ID))
+	sb.WriteString(fmt.Sprintf("  • Type: %s (IPv%d)\n", status.Type, status.AF))
+	sb.WriteString(fmt.Sprintf("  • Target: %s", status.Target))
+	if status.TargetIP != "" && status.TargetIP != status.Target {
+		sb.WriteString(fmt.Sprintf(" (%s)", status.TargetIP))
+	}
+	sb.WriteString("\n")
+	if status.Description != "" {
+		sb.WriteString(fmt.Sprintf("  • Description: %s\n", status.Description))
+	}
+	sb.WriteString(fmt.Sprintf("  • Status: %s\n", status.Status.Name))
+	sb.WriteString(fmt.Sprintf("  • One-off: %t\n", status.IsOneoff))
+	sb.WriteString(fmt.Sprintf("  • Probes: %d requested, %d scheduled, %d participated\n",
+		status.ProbesRequested, status.ProbesScheduled, status.ParticipantCount))
+	if len(status.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("  • Tags: %s\n", strings.Join(status.Tags, ", ")))
+	}
+	if status.StartTime > 0 {
+		sb.WriteString(fmt.Sprintf("  • Started: %s\n", formatUnix(status.StartTime)))
+	}
+	if status.StopTime > 0 {
+		sb.WriteString(fmt.Sprintf("  • Stopped: %s\n", formatUnix(status.StopTime)))
+	}
+	sb.WriteString(fmt.Sprintf("  • View online: https://atlas.ripe.net/measurements/%d\n", status.ID))
+
+	return sb.String()
+}
+
+// FormatTracerouteResults renders one summary line per traceroute result
+func FormatTracerouteResults(results []TracerouteResult) string {
+	var sb strings.Builder
+
+	for _, result := range results {
+		lastFrom, lastRTT := "", 0.0
+		for _, hop := range result.Result {
+			for _, reply := range hop.Result {
+				if reply.From != "" && reply.X != "*" {
+					lastFrom, lastRTT = reply.From, reply.RTT
+					break
+				}
+			}
+		}
+
+		mark := "✗"
+		if lastFrom != "" && lastFrom == result.DstAddr {
+			mark = "✓"
+		}
+
+		sb.WriteString(fmt.Sprintf("  %s Probe %-7d %-16s → %-16s %2d hops  %7.1f ms\n",
+			mark, result.ProbeID, result.From, result.DstAddr, len(result.Result), lastRTT))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n  %d results\n", len(results)))
+
+	return sb.String()
+}
+
+// formatUnix formats a Unix timestamp like the report timestamps
+func formatUnix(ts int64) string {
+	return time.Unix(ts, 0).Format("2006-01-02 15:04:05 MST")
+}
@@ -0,0 +1,127 @@
+package atlas
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+)
+
+// ReadRawResults reads results from r without decoding them, accepting either
+// a JSON array (as returned by the results API) or NDJSON with one result per
+// line
+func ReadRawResults(r io.Reader) ([]json.RawMessage, error) {
+	br := bufio.NewReader(r)
+
+	// Skip leading whitespace and peek at the first byte to tell the two formats apart
+	var first []byte
+	for {
+		var err error
+		first, err = br.Peek(1)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results: %w", err)
+		}
+		if !unicode.IsSpace(rune(first[0])) {
+			break
+		}
+		br.ReadByte()
+	}
+
+	dec := json.NewDecoder(br)
+
+	if first[0] == '[' {
+		var results []json.RawMessage
+		if err := dec.Decode(&results); err != nil {
+			return nil, fmt.Errorf("failed to decode results: %w", err)
+		}
+		return results, nil
+	}
+
+	var results []json.RawMessage
+	for {
+		var result json.RawMessage
+		err := dec.Decode(&result)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode result %d: %w", len(results)+1, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ReadResults decodes traceroute results from r, in the formats ReadRawResults accepts
+func ReadResults(r io.Reader) ([]TracerouteResult, error) {
+	raw, err := ReadRawResults(r)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeResults[TracerouteResult](raw)
+}
+
+// DecodeResults decodes raw results, as returned by GetMeasurementResultsRaw
+// or ReadRawResults, into typed results
+func DecodeResults[T any](raw []json.RawMessage) ([]T, error) {
+	results := make([]T, len(raw))
+	for i, data := range raw {
+		if err := json.Unmarshal(data, &results[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode result %d: %w", i+1, err)
+		}
+	}
+	return results, nil
+}
+
+// ReadRawResultsFile reads undecoded results from a JSON or NDJSON file
+func ReadRawResultsFile(path string) ([]json.RawMessage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results file: %w", err)
+	}
+	defer file.Close()
+
+	results, err := ReadRawResults(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
+
+// ReadResultsFile reads traceroute results from a JSON or NDJSON file
+func ReadResultsFile(path string) ([]TracerouteResult, error) {
+	raw, err := ReadRawResultsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := DecodeResults[TracerouteResult](raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return results, nil
+}
+
+// WriteResultsFile saves results as a JSON array readable by ReadResultsFile.
+// The results are written as the API returned them, including the fields
+// TracerouteResult does not decode.
+func WriteResultsFile(path string, results []json.RawMessage) error {
+	if results == nil {
+		results = []json.RawMessage{}
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package atlas
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resultWithExtras is a traceroute result with fields TracerouteResult does not decode
+const resultWithExtras = `{"fw": 5080, "lts": 12, "prb_id": 1001, "msm_id": 7, "from": "192.0.2.11", "type": "traceroute", "dst_addr": "192.0.2.65", "src_addr": "10.0.0.2", "paris_id": 3, "result": [{"hop": 1, "result": [{"from": "198.51.100.1", "rtt": 1.5, "ttl": 255, "x": "*"}]}]}`
+
+func TestReadResultsFormats(t *testing.T) {
+	tests := map[string]string{
+		"array":  "  [" + resultWithExtras + ",\n" + resultWithExtras + "]\n",
+		"ndjson": resultWithExtras + "\n\n" + resultWithExtras + "\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			results, err := ReadResults(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("ReadResults: %v", err)
+			}
+			if len(results) != 2 || results[1].ProbeID != 1001 || results[1].Result[0].Result[0].From != "198.51.100.1" {
+				t.Errorf("ReadResults = %+v", results)
+			}
+		})
+	}
+
+	if results, err := ReadResults(strings.NewReader(" \n")); err != nil || len(results) != 0 {
+		t.Errorf("ReadResults of an empty file = %v, %v", results, err)
+	}
+	if _, err := ReadResults(strings.NewReader(resultWithExtras + "\n{")); err == nil {
+		t.Error("ReadResults accepted a truncated NDJSON line")
+	}
+}
+
+func TestWriteResultsFileKeepsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+
+	raw, err := ReadRawResults(strings.NewReader(resultWithExtras))
+	if err != nil {
+		t.Fatalf("ReadRawResults: %v", err)
+	}
+	if err := WriteResultsFile(path, raw); err != nil {
+		t.Fatalf("WriteResultsFile: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []map[string]any
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("written file is not a JSON array: %v", err)
+	}
+	for _, field := range []string{"fw", "lts", "paris_id"} {
+		if _, ok := written[0][field]; !ok {
+			t.Errorf("written result lacks %q: %s", field, data)
+		}
+	}
+
+	results, err := ReadResultsFile(path)
+	if err != nil || len(results) != 1 || results[0].MsmID != 7 {
+		t.Errorf("ReadResultsFile = %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,105 @@
+package atlas
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/netip"
+)
+
+// Sampling modes of AllocationOptions.Sampling
+const (
+	SamplingRandom = "random"
+	SamplingGeo    = "geo"
+)
+
+// GeoCellDegrees is the size of the latitude/longitude grid cells geo
+// sampling spreads picks across, roughly 100 km at mid latitudes
+const GeoCellDegrees = 1.0
+
+// geoSpread reorders shuffled probe indices so that consecutive picks come
+// from different locations. Probes are bucketed by country and grid cell,
+// then taken round-robin, one per bucket per round, so a city hosting most
+// of an ASN's probes gets no more than its turn.
+func geoSpread(probes []Probe, indices []int) []int {
+	var (
+		buckets = make(map[string][]int)
+		order   []string // bucket keys in order of first appearance, which is random
+	)
+
+	for _, i := range indices {
+		key := geoCell(probes[i])
+		if _, ok := buckets[key]; !ok {
+			order = append(order, key)
+		}
+		buckets[key] = append(buckets[key], i)
+	}
+
+	spread := make([]int, 0, len(indices))
+	for len(spread) < len(indices) {
+		for _, key := range order {
+			if bucket := buckets[key]; len(bucket) > 0 {
+				spread = append(spread, bucket[0])
+				buckets[key] = bucket[1:]
+			}
+		}
+	}
+
+	return spread
+}
+
+// geoCell returns the bucket key of a probe: its country and, when the probe
+// has a location, the grid cell it is in
+func geoCell(p Probe) string {
+	if p.Geometry == nil || len(p.Geometry.Coordinates) < 2 {
+		return p.CountryCode
+	}
+	lon, lat := p.Geometry.Coordinates[0], p.Geometry.Coordinates[1]
+	return fmt.Sprintf("%s/%d/%d", p.CountryCode,
+		int(math.Floor(lat/GeoCellDegrees)), int(math.Floor(lon/GeoCellDegrees)))
+}
+
+// DedupByPrefix keeps one probe per /24 (af 4) or /48 (af 6) of the probe
+// address, so vantage points are not redundant. Which probe of a prefix is
+// kept is random, drawn from rng (the global source when nil), anchors win
+// when preferAnchors is set. Probes without an address in the family are kept.
+func DedupByPrefix(probes []Probe, af int, preferAnchors bool, rng *rand.Rand) []Probe {
+	bits := 24
+	if af == 6 {
+		bits = 48
+	}
+
+	kept := make(map[netip.Prefix]int) // prefix -> index in deduped
+	var deduped []Probe
+
+	perm := rand.Perm
+	if rng != nil {
+		perm = rng.Perm
+	}
+
+	for _, i := range perm(len(probes)) {
+		p := probes[i]
+
+		address := p.AddressV4
+		if af == 6 {
+			address = p.AddressV6
+		}
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			deduped = append(deduped, p)
+			continue
+		}
+
+		prefix, _ := addr.Prefix(bits)
+		j, seen := kept[prefix]
+		switch {
+		case !seen:
+			kept[prefix] = len(deduped)
+			deduped = append(deduped, p)
+		case preferAnchors && p.IsAnchor && !deduped[j].IsAnchor:
+			deduped[j] = p
+		}
+	}
+
+	return deduped
+}
@@ -0,0 +1,195 @@
+package atlas
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// located returns a probe in country at [lon, lat]
+func located(id int, country string, lon, lat float64) Probe {
+	return Probe{ID: id, CountryCode: country, Geometry: &Geometry{Type: "Point", Coordinates: []float64{lon, lat}}}
+}
+
+func TestGeoCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		probe Probe
+		want  string
+	}{
+		{"located", located(1, "NL", 4.90, 52.37), "NL/52/4"},
+		{"same cell", located(2, "NL", 4.10, 52.99), "NL/52/4"},
+		{"negative coordinates round down", located(3, "AR", -58.38, -34.60), "AR/-35/-59"},
+		{"no geometry", Probe{ID: 4, CountryCode: "DE"}, "DE"},
+		{"incomplete coordinates", Probe{ID: 5, CountryCode: "DE", Geometry: &Geometry{Coordinates: []float64{13.4}}}, "DE"},
+		{"nothing known", Probe{ID: 6}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := geoCell(tt.probe); got != tt.want {
+				t.Errorf("geoCell = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeoSpread(t *testing.T) {
+	probes := []Probe{
+		located(0, "NL", 4.90, 52.37), // Amsterdam
+		located(1, "NL", 4.89, 52.37),
+		located(2, "NL", 4.91, 52.36),
+		located(3, "DE", 13.40, 52.52), // Berlin
+		located(4, "DE", 13.41, 52.52),
+		{ID: 5, CountryCode: "BE"}, // no location, bucketed by country
+		{ID: 6, CountryCode: "BE"},
+		located(7, "DE", 11.58, 48.14), // Munich
+	}
+
+	tests := []struct {
+		name    string
+		indices []int
+		want    []int
+	}{
+		{
+			name:    "round-robin in order of first appearance",
+			indices: []int{0, 1, 2, 3, 4, 5, 6, 7},
+			want:    []int{0, 3, 5, 7, 1, 4, 6, 2},
+		},
+		{
+			name:    "shuffled input",
+			indices: []int{7, 2, 6, 0, 3, 1, 5, 4},
+			want:    []int{7, 2, 6, 3, 0, 5, 4, 1},
+		},
+		{
+			name:    "single bucket keeps its order",
+			indices: []int{2, 0, 1},
+			want:    []int{2, 0, 1},
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := geoSpread(probes, tt.indices); !slices.Equal(got, tt.want) {
+				t.Errorf("geoSpread(%v) = %v, want %v", tt.indices, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectRandomProbesGeo(t *testing.T) {
+	// Eight probes in Amsterdam, one in Berlin, one anchor in Munich
+	var probes []Probe
+	for id := 1; id <= 8; id++ {
+		probes = append(probes, located(id, "NL", 4.9, 52.37))
+	}
+	probes = append(probes, located(9, "DE", 13.40, 52.52))
+	munich := located(10, "DE", 11.58, 48.14)
+	munich.IsAnchor = true
+	probes = append(probes, munich)
+
+	for seed := int64(1); seed <= 20; seed++ {
+		opts := AllocationOptions{Sampling: SamplingGeo, Rand: rand.New(rand.NewSource(seed))}
+		picked := selectRandomProbes(probes, 3, opts)
+
+		// Every location gets its turn before Amsterdam gets a second one
+		slices.Sort(picked)
+		if len(picked) != 3 || picked[0] > 8 || picked[1] != 9 || picked[2] != 10 {
+			t.Errorf("seed %d: picked %v, want one Amsterdam probe, 9 and 10", seed, picked)
+		}
+
+		again := selectRandomProbes(probes, 3, AllocationOptions{Sampling: SamplingGeo, Rand: rand.New(rand.NewSource(seed))})
+		slices.Sort(again)
+		if !slices.Equal(picked, again) {
+			t.Errorf("seed %d: picked %v then %v", seed, picked, again)
+		}
+
+		// Anchors come first whatever the location order
+		anchorFirst := selectRandomProbes(probes, 1, AllocationOptions{Sampling: SamplingGeo, PreferAnchors: true, Rand: rand.New(rand.NewSource(seed))})
+		if !slices.Equal(anchorFirst, []int{10}) {
+			t.Errorf("seed %d: picked %v with anchors preferred, want the anchor", seed, anchorFirst)
+		}
+	}
+}
+
+func TestDedupByPrefix(t *testing.T) {
+	probes := []Probe{
+		{ID: 1, AddressV4: "192.0.2.10", AddressV6: "2001:db8:a::1"},
+		{ID: 2, AddressV4: "192.0.2.20", AddressV6: "2001:db8:a:ff::2"},
+		{ID: 3, AddressV4: "192.0.2.30", AddressV6: "2001:db8:b::3", IsAnchor: true},
+		{ID: 4, AddressV4: "198.51.100.1"},
+		{ID: 5, AddressV6: "2001:db8:c::5"},
+		{ID: 6}, // no address at all
+	}
+
+	ids := func(probes []Probe) []int {
+		var ids []int
+		for _, p := range probes {
+			ids = append(ids, p.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	tests := []struct {
+		name          string
+		af            int
+		preferAnchors bool
+		fixed         []int // IDs always kept
+		oneOf         []int // exactly one of these is kept
+	}{
+		{"ipv4 /24", 4, false, []int{4, 5, 6}, []int{1, 2, 3}},
+		{"ipv4 /24 preferring anchors", 4, true, []int{3, 4, 5, 6}, nil},
+		{"ipv6 /48", 6, false, []int{3, 4, 5, 6}, []int{1, 2}},
+		{"ipv6 /48 preferring anchors", 6, true, []int{3, 4, 5, 6}, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				deduped := DedupByPrefix(probes, tt.af, tt.preferAnchors, rand.New(rand.NewSource(seed)))
+				got := ids(deduped)
+
+				var rest []int
+				for _, id := range got {
+					if !slices.Contains(tt.fixed, id) {
+						rest = append(rest, id)
+					}
+				}
+				if len(got)-len(rest) != len(tt.fixed) {
+					t.Fatalf("seed %d: kept %v, want all of %v", seed, got, tt.fixed)
+				}
+				if len(tt.oneOf) == 0 && len(rest) != 0 || len(tt.oneOf) > 0 && (len(rest) != 1 || !slices.Contains(tt.oneOf, rest[0])) {
+					t.Errorf("seed %d: kept %v, want %v and one of %v", seed, got, tt.fixed, tt.oneOf)
+				}
+
+				again := DedupByPrefix(probes, tt.af, tt.preferAnchors, rand.New(rand.NewSource(seed)))
+				if !slices.Equal(ids(again), got) {
+					t.Errorf("seed %d: kept %v then %v", seed, got, ids(again))
+				}
+			}
+		})
+	}
+}
+
+func TestDedupByPrefixVariesWithSeed(t *testing.T) {
+	probes := []Probe{
+		{ID: 1, AddressV4: "192.0.2.10"},
+		{ID: 2, AddressV4: "192.0.2.20"},
+	}
+
+	kept := make(map[int]bool)
+	for seed := int64(1); seed <= 20; seed++ {
+		deduped := DedupByPrefix(probes, 4, false, rand.New(rand.NewSource(seed)))
+		if len(deduped) != 1 {
+			t.Fatalf("seed %d: kept %d probes, want 1", seed, len(deduped))
+		}
+		kept[deduped[0].ID] = true
+	}
+	if len(kept) != 2 {
+		t.Errorf("20 seeds always kept probe %v, want the pick to vary", kept)
+	}
+}
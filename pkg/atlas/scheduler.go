@@ -0,0 +1,273 @@
+package atlas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scheduler creates measurement requests as fast as the Atlas concurrency
+// quotas allow and waits for them to complete. At most
+// MaxConcurrentMeasurements run at a time, and at most MaxOneoffsPerTarget
+// one-offs of the same type against the same target; both count the
+// measurements already running on the account. Requests that do not fit wait
+// for running measurements to complete, later requests that fit go first.
+type Scheduler struct {
+	Client *Client
+
+	// Active are the account's measurements that were running before the
+	// scheduler started, see ActiveMeasurements
+	Active []MeasurementStatus
+
+	// Created, when set, is called after request i was created
+	Created func(i int, ids []int)
+
+	// Finished, when set, is called when a measurement completed or waiting
+	// for it failed
+	Finished func(id int, err error)
+
+	// Timeout is how long Run waits without any measurement completing
+	// before it asks KeepWaiting, DefaultSchedulerTimeout when zero
+	Timeout time.Duration
+
+	// KeepWaiting, when set, decides whether to wait another Timeout for
+	// the unfinished measurements. Run gives up with ErrWaitTimeout when it
+	// is nil, returns false or fails.
+	KeepWaiting func(ctx context.Context, unfinished []int) (bool, error)
+}
+
+// DefaultSchedulerTimeout is the Scheduler timeout when none is set
+const DefaultSchedulerTimeout = 5 * time.Minute
+
+// schedulerKey identifies measurements counted against MaxOneoffsPerTarget
+type schedulerKey struct {
+	Type   string
+	Target string
+}
+
+// finished reports the end of a wait for a measurement
+type finished struct {
+	id  int
+	err error
+}
+
+// Run creates every request and waits for all measurements to complete. It
+// returns the measurement IDs of each request, in the order of reqs. When ctx
+// is cancelled or a request cannot be created it returns the IDs created so
+// far, the measurements among them that have not completed, and the error.
+// Measurements that end without results, e.g. for lack of suitable probes,
+// are passed to Finished and do not stop the run. When no measurement
+// completes within Timeout and KeepWaiting declines, Run returns the same way
+// with ErrWaitTimeout.
+func (s *Scheduler) Run(ctx context.Context, reqs []MeasurementRequest) ([][]int, []int, error) {
+	// Stops the goroutines waiting for measurements when Run returns early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultSchedulerTimeout
+	}
+
+	active := len(s.Active)
+	perTarget := make(map[schedulerKey]int)
+	for _, m := range s.Active {
+		if m.IsOneoff {
+			perTarget[schedulerKey{m.Type, m.Target}]++
+		}
+	}
+
+	total := 0
+	for _, req := range reqs {
+		if len(req.Definitions) > MaxConcurrentMeasurements {
+			return nil, nil, fmt.Errorf("%w: %d definitions in one request, at most %d measurements may run at the same time",
+				ErrQuotaExceeded, len(req.Definitions), MaxConcurrentMeasurements)
+		}
+		total += len(req.Definitions)
+	}
+
+	ids := make([][]int, len(reqs))
+	running := make(map[int][]schedulerKey)
+	done := make(chan finished, total)
+
+	pending := make([]int, len(reqs))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	unfinished := func() []int {
+		var remaining []int
+		for _, reqIDs := range ids {
+			for _, id := range reqIDs {
+				if _, ok := running[id]; ok {
+					remaining = append(remaining, id)
+				}
+			}
+		}
+		return remaining
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for len(pending) > 0 || len(running) > 0 {
+		var waiting []int
+		for _, i := range pending {
+			req := reqs[i]
+			keys := schedulerKeys(req)
+			if !fits(active, perTarget, req, keys) {
+				waiting = append(waiting, i)
+				continue
+			}
+
+			// As in a single create, a cancelled POST could leave a
+			// measurement behind whose ID is lost, so the create runs to
+			// completion and cancellation is honoured before the next one
+			if ctx.Err() != nil {
+				return ids, unfinished(), ctx.Err()
+			}
+			created, err := s.Client.CreateMeasurements(context.WithoutCancel(ctx), req)
+			if err != nil {
+				return ids, unfinished(), fmt.Errorf("failed to create measurement: %w", err)
+			}
+			ids[i] = created
+			if s.Created != nil {
+				s.Created(i, created)
+			}
+
+			for j, id := range created {
+				var idKeys []schedulerKey
+				if j < len(keys) {
+					idKeys = keys[j : j+1]
+				}
+				running[id] = idKeys
+				active++
+				for _, key := range idKeys {
+					perTarget[key]++
+				}
+
+				go func(id, expected int) {
+					done <- finished{id, s.wait(ctx, id, expected)}
+				}(id, req.RequestedProbes())
+			}
+		}
+		pending = waiting
+
+		if len(running) == 0 {
+			if len(pending) > 0 {
+				return ids, nil, fmt.Errorf("%w: %d measurements already running leave no room for %d more",
+					ErrQuotaExceeded, len(s.Active), len(pending))
+			}
+			break
+		}
+
+		select {
+		case f := <-done:
+			if ctx.Err() != nil {
+				return ids, unfinished(), ctx.Err()
+			}
+			for _, key := range running[f.id] {
+				perTarget[key]--
+			}
+			delete(running, f.id)
+			active--
+			if s.Finished != nil {
+				s.Finished(f.id, f.err)
+			}
+			timer.Reset(timeout)
+
+		case <-timer.C:
+			remaining := unfinished()
+			if s.KeepWaiting == nil {
+				return ids, remaining, fmt.Errorf("%w: %d measurements still running", ErrWaitTimeout, len(remaining))
+			}
+			ok, err := s.KeepWaiting(ctx, remaining)
+			if ctx.Err() != nil {
+				return ids, remaining, ctx.Err()
+			}
+			if err != nil || !ok {
+				return ids, remaining, fmt.Errorf("%w: %d measurements still running", ErrWaitTimeout, len(remaining))
+			}
+			timer.Reset(timeout)
+
+		case <-ctx.Done():
+			return ids, unfinished(), ctx.Err()
+		}
+	}
+
+	return ids, nil, nil
+}
+
+// wait polls a measurement until it completes or Run returns
+func (s *Scheduler) wait(ctx context.Context, id, expectedProbes int) error {
+	for {
+		err := s.Client.WaitForMeasurement(ctx, id, expectedProbes, waitWindow)
+		if !errors.Is(err, ErrWaitTimeout) {
+			return err
+		}
+	}
+}
+
+// waitWindow is how long a single WaitForMeasurement call of the scheduler lasts
+const waitWindow = 5 * time.Minute
+
+// schedulerKeys returns the MaxOneoffsPerTarget key of each definition of a
+// one-off request, none for periodic requests
+func schedulerKeys(req MeasurementRequest) []schedulerKey {
+	if !req.IsOneoff {
+		return nil
+	}
+	keys := make([]schedulerKey, len(req.Definitions))
+	for i, def := range req.Definitions {
+		keys[i] = schedulerKey{def.Type, def.Target}
+	}
+	return keys
+}
+
+// fits reports whether req can be created next to the running measurements
+func fits(active int, perTarget map[schedulerKey]int, req MeasurementRequest, keys []schedulerKey) bool {
+	if active+len(req.Definitions) > MaxConcurrentMeasurements {
+		return false
+	}
+
+	added := make(map[schedulerKey]int)
+	for _, key := range keys {
+		added[key]++
+		if perTarget[key]+added[key] > MaxOneoffsPerTarget {
+			return false
+		}
+	}
+	return true
+}
+
+// ActiveMeasurements returns the account's measurements that are specified,
+// scheduled or ongoing
+func (c *Client) ActiveMeasurements(ctx context.Context) ([]MeasurementStatus, error) {
+	it := c.ListMeasurements(ctx, MeasurementFilter{
+		StatusIDs: []int{MeasurementSpecified, MeasurementScheduled, MeasurementOngoing},
+	})
+
+	var active []MeasurementStatus
+	for it.Next() {
+		active = append(active, it.Measurement())
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list active measurements: %w", err)
+	}
+	return active, nil
+}
+
+// CountActiveMeasurements returns how many of the account's measurements are
+// specified, scheduled or ongoing, which all count towards
+// MaxConcurrentMeasurements
+func (c *Client) CountActiveMeasurements(ctx context.Context) (int, error) {
+	it := c.ListMeasurements(ctx, MeasurementFilter{
+		StatusIDs: []int{MeasurementSpecified, MeasurementScheduled, MeasurementOngoing},
+	})
+	it.Next()
+	if err := it.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list active measurements: %w", err)
+	}
+	return it.Count(), nil
+}
@@ -0,0 +1,153 @@
+package atlas_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas"
+)
+
+// oneoffRequests returns n one-off traceroutes from probe 1001, to target
+// when it is set and to a different target each otherwise
+func oneoffRequests(n int, target string) []atlas.MeasurementRequest {
+	reqs := make([]atlas.MeasurementRequest, n)
+	for i := range reqs {
+		dst := target
+		if dst == "" {
+			dst = fmt.Sprintf("192.0.2.%d", i+1)
+		}
+		reqs[i] = atlas.MeasurementRequest{
+			Definitions: []atlas.MeasurementDefinition{{Type: "traceroute", AF: 4, Target: dst, Protocol: "ICMP"}},
+			Probes:      []atlas.ProbeSet{{Type: "probes", Value: "1001", Requested: 1}},
+			IsOneoff:    true,
+		}
+	}
+	return reqs
+}
+
+// runScheduler runs reqs and returns the most measurements that ran at once
+func runScheduler(t *testing.T, scheduler *atlas.Scheduler, reqs []atlas.MeasurementRequest) int {
+	t.Helper()
+
+	running, peak := 0, 0
+	scheduler.Created = func(i int, ids []int) {
+		running += len(ids)
+		peak = max(peak, running)
+	}
+	scheduler.Finished = func(id int, err error) {
+		running--
+		if err != nil {
+			t.Errorf("measurement %d: %v", id, err)
+		}
+	}
+
+	ids, unfinished, err := scheduler.Run(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(unfinished) != 0 {
+		t.Errorf("Run left %v unfinished", unfinished)
+	}
+	for i, reqIDs := range ids {
+		if len(reqIDs) != 1 {
+			t.Errorf("request %d created %v, want one measurement", i, reqIDs)
+		}
+	}
+	return len(scheduler.Active) + peak
+}
+
+func TestSchedulerConcurrencyQuota(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.PollsPerStage = 40 // keep measurements running while the first 100 are created
+
+	scheduler := &atlas.Scheduler{Client: client}
+	if peak := runScheduler(t, scheduler, oneoffRequests(120, "")); peak != atlas.MaxConcurrentMeasurements {
+		t.Errorf("%d measurements ran at once, want %d", peak, atlas.MaxConcurrentMeasurements)
+	}
+}
+
+func TestSchedulerPerTargetQuota(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.PollsPerStage = 40
+
+	scheduler := &atlas.Scheduler{Client: client}
+	if peak := runScheduler(t, scheduler, oneoffRequests(40, "192.0.2.65")); peak != atlas.MaxOneoffsPerTarget {
+		t.Errorf("%d one-offs to the same target ran at once, want %d", peak, atlas.MaxOneoffsPerTarget)
+	}
+}
+
+func TestSchedulerCountsActiveMeasurements(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.PollsPerStage = 40
+
+	// Measurements of the account that were running before the scheduler started
+	active := make([]atlas.MeasurementStatus, 20)
+	for i := range active {
+		active[i] = atlas.MeasurementStatus{ID: i + 1, Type: "traceroute", Target: "192.0.2.65", IsOneoff: true}
+	}
+
+	scheduler := &atlas.Scheduler{Client: client, Active: active}
+	if peak := runScheduler(t, scheduler, oneoffRequests(10, "192.0.2.65")); peak != atlas.MaxOneoffsPerTarget {
+		t.Errorf("%d one-offs to the same target ran at once, want %d", peak, atlas.MaxOneoffsPerTarget)
+	}
+}
+
+func TestSchedulerNoRoom(t *testing.T) {
+	_, client := newTestClient(t)
+
+	active := make([]atlas.MeasurementStatus, atlas.MaxConcurrentMeasurements)
+	scheduler := &atlas.Scheduler{Client: client, Active: active}
+
+	_, _, err := scheduler.Run(context.Background(), oneoffRequests(1, ""))
+	if !errors.Is(err, atlas.ErrQuotaExceeded) {
+		t.Errorf("Run = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestSchedulerTimeout(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.ResultsPerPoll = 0 // measurements never complete
+
+	var asked [][]int
+	scheduler := &atlas.Scheduler{
+		Client:  client,
+		Timeout: 50 * time.Millisecond,
+		KeepWaiting: func(ctx context.Context, unfinished []int) (bool, error) {
+			asked = append(asked, unfinished)
+			return len(asked) < 2, nil
+		},
+	}
+
+	ids, unfinished, err := scheduler.Run(context.Background(), oneoffRequests(3, ""))
+	if !errors.Is(err, atlas.ErrWaitTimeout) {
+		t.Fatalf("Run = %v, want ErrWaitTimeout", err)
+	}
+
+	want := slices.Concat(ids...)
+	if !slices.Equal(unfinished, want) {
+		t.Errorf("unfinished = %v, want %v", unfinished, want)
+	}
+	if len(asked) != 2 || !slices.Equal(asked[0], want) {
+		t.Errorf("KeepWaiting asked about %v, want %v twice", asked, want)
+	}
+}
+
+func TestSchedulerTimeoutWithoutPrompt(t *testing.T) {
+	srv, client := newTestClient(t)
+	srv.ResultsPerPoll = 0
+
+	scheduler := &atlas.Scheduler{Client: client, Timeout: 20 * time.Millisecond}
+	_, unfinished, err := scheduler.Run(context.Background(), oneoffRequests(1, ""))
+	if !errors.Is(err, atlas.ErrWaitTimeout) || len(unfinished) != 1 {
+		t.Errorf("Run = %v with %v unfinished, want ErrWaitTimeout with one", err, unfinished)
+	}
+
+	status, ok := srv.Measurement(1000001)
+	if !ok || status.Status.ID == atlas.MeasurementStopped {
+		t.Errorf("measurement = %+v, want it still running", status)
+	}
+}
@@ -0,0 +1,102 @@
+package atlas
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Probe set types Atlas resolves server-side, besides "probes" (explicit IDs)
+const (
+	ProbeSetCountry = "country"
+	ProbeSetArea    = "area"
+	ProbeSetPrefix  = "prefix"
+	ProbeSetASN     = "asn"
+	ProbeSetMsm     = "msm"
+)
+
+// Areas are the values of an "area" probe set
+var Areas = []string{"WW", "West", "North-Central", "South-Central", "North-East", "South-East"}
+
+// ParseProbeSet parses a VALUE[:N] selector for a server-side probe set of
+// the given type, where N is the number of probes requested and defaults to
+// defaultRequested. The value is validated and normalized for its type.
+func ParseProbeSet(setType, spec string, defaultRequested int) (ProbeSet, error) {
+	set := ProbeSet{Type: setType, Value: strings.TrimSpace(spec), Requested: defaultRequested}
+
+	// The count follows the last colon, which IPv6 prefixes also contain,
+	// so only an all-digit suffix after the prefix length counts
+	if i := strings.LastIndex(set.Value, ":"); i >= 0 {
+		if n, err := strconv.Atoi(set.Value[i+1:]); err == nil && (setType != ProbeSetPrefix || strings.Contains(set.Value[:i], "/")) {
+			if n <= 0 {
+				return ProbeSet{}, fmt.Errorf("%s %s: probe count must be positive", setType, spec)
+			}
+			set.Value, set.Requested = set.Value[:i], n
+		}
+	}
+
+	if set.Value == "" {
+		return ProbeSet{}, fmt.Errorf("%s selector is empty", setType)
+	}
+
+	switch setType {
+	case ProbeSetCountry:
+		if len(set.Value) != 2 || strings.ContainsFunc(set.Value, func(r rune) bool { return !isLetter(r) }) {
+			return ProbeSet{}, fmt.Errorf("invalid country %q: must be a two-letter ISO code", set.Value)
+		}
+		set.Value = strings.ToUpper(set.Value)
+
+	case ProbeSetArea:
+		i := slices.IndexFunc(Areas, func(area string) bool { return strings.EqualFold(area, set.Value) })
+		if i < 0 {
+			return ProbeSet{}, fmt.Errorf("invalid area %q: must be one of %s", set.Value, strings.Join(Areas, ", "))
+		}
+		set.Value = Areas[i]
+
+	case ProbeSetPrefix:
+		prefix, err := netip.ParsePrefix(set.Value)
+		if err != nil {
+			return ProbeSet{}, fmt.Errorf("invalid prefix %q: %w", set.Value, err)
+		}
+		set.Value = prefix.Masked().String()
+
+	case ProbeSetASN, ProbeSetMsm:
+		if id, err := strconv.Atoi(set.Value); err != nil || id <= 0 {
+			return ProbeSet{}, fmt.Errorf("invalid %s %q: must be a positive number", setType, set.Value)
+		}
+
+	default:
+		return ProbeSet{}, fmt.Errorf("unknown probe set type %q", setType)
+	}
+
+	return set, nil
+}
+
+// isLetter reports whether r is an ASCII letter
+func isLetter(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+// String describes the probe set, e.g. "country DE (10 probes)"
+func (s ProbeSet) String() string {
+	var sb strings.Builder
+
+	if s.Type == "probes" {
+		sb.WriteString(fmt.Sprintf("%d listed probes", s.Requested))
+	} else {
+		sb.WriteString(fmt.Sprintf("%s %s (%d probes)", s.Type, s.Value, s.Requested))
+	}
+
+	if s.Tags != nil {
+		for _, tag := range s.Tags.Include {
+			sb.WriteString(" +" + tag)
+		}
+		for _, tag := range s.Tags.Exclude {
+			sb.WriteString(" -" + tag)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,82 @@
+package atlas
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProbeSet(t *testing.T) {
+	tests := []struct {
+		setType, spec string
+		wantValue     string
+		wantRequested int
+	}{
+		// The count defaults to 10 below
+		{ProbeSetCountry, "DE", "DE", 10},
+		{ProbeSetCountry, "de:5", "DE", 5},
+		{ProbeSetCountry, " nl:3 ", "NL", 3},
+		{ProbeSetArea, "WW", "WW", 10},
+		{ProbeSetArea, "west:20", "West", 20},
+		{ProbeSetArea, "north-central", "North-Central", 10},
+		{ProbeSetASN, "3320", "3320", 10},
+		{ProbeSetASN, "3320:50", "3320", 50},
+		{ProbeSetMsm, "1000001:7", "1000001", 7},
+		{ProbeSetPrefix, "192.0.2.0/24", "192.0.2.0/24", 10},
+		{ProbeSetPrefix, "192.0.2.0/24:5", "192.0.2.0/24", 5},
+		{ProbeSetPrefix, "192.0.2.77/24", "192.0.2.0/24", 10},
+
+		// IPv6 prefixes contain colons, only a number after the length is a count
+		{ProbeSetPrefix, "2001:db8::/32", "2001:db8::/32", 10},
+		{ProbeSetPrefix, "2001:db8::/32:10", "2001:db8::/32", 10},
+		{ProbeSetPrefix, "2001:db8::/32:3", "2001:db8::/32", 3},
+		{ProbeSetPrefix, "2001:db8:1:2::/64:25", "2001:db8:1:2::/64", 25},
+		{ProbeSetPrefix, "2001:DB8:0:0:1::/48", "2001:db8::/48", 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setType+" "+tt.spec, func(t *testing.T) {
+			set, err := ParseProbeSet(tt.setType, tt.spec, 10)
+			if err != nil {
+				t.Fatalf("ParseProbeSet: %v", err)
+			}
+			if set.Type != tt.setType || set.Value != tt.wantValue || set.Requested != tt.wantRequested {
+				t.Errorf("ParseProbeSet = %+v, want %s %s with %d probes", set, tt.setType, tt.wantValue, tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestParseProbeSetErrors(t *testing.T) {
+	tests := []struct {
+		setType, spec string
+		want          string
+	}{
+		{ProbeSetCountry, "", "selector is empty"},
+		{ProbeSetCountry, ":5", "selector is empty"},
+		{ProbeSetCountry, "DEU", "two-letter ISO code"},
+		{ProbeSetCountry, "D1", "two-letter ISO code"},
+		{ProbeSetCountry, "DE:0", "probe count must be positive"},
+		{ProbeSetCountry, "DE:-3", "probe count must be positive"},
+		{ProbeSetCountry, "DE:many", "two-letter ISO code"},
+		{ProbeSetArea, "Mars", "must be one of WW, West"},
+		{ProbeSetASN, "AS3320", "must be a positive number"},
+		{ProbeSetASN, "0", "must be a positive number"},
+		{ProbeSetMsm, "-1", "must be a positive number"},
+		{ProbeSetPrefix, "192.0.2.0", "invalid prefix"},
+		{ProbeSetPrefix, "192.0.2.0/33", "invalid prefix"},
+		{ProbeSetPrefix, "192.0.2.0/24:0", "probe count must be positive"},
+		// Without a prefix length the last group is part of the address, not a count
+		{ProbeSetPrefix, "2001:db8::10", "invalid prefix"},
+		{ProbeSetPrefix, "2001:db8::/32:0", "probe count must be positive"},
+		{"city", "Berlin", "unknown probe set type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setType+" "+tt.spec, func(t *testing.T) {
+			set, err := ParseProbeSet(tt.setType, tt.spec, 10)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseProbeSet = %+v, %v, want an error containing %q", set, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package atlas
+
+import (
+	"strings"
+)
+
+// ShardRequest splits req into requests of at most maxProbes probes each, so
+// a selection larger than MaxProbesPerMeasurement can run as several
+// measurements. Every shard carries all definitions of req. Explicit probe
+// lists are split by ID, other probe sets are packed whole into the first
+// shard with room for them; a single set asking for more than maxProbes gets
+// a shard of its own and is left for Atlas to reject.
+func ShardRequest(req MeasurementRequest, maxProbes int) []MeasurementRequest {
+	var shards [][]ProbeSet
+	var sizes []int
+
+	add := func(set ProbeSet) {
+		for i := range shards {
+			if sizes[i]+set.Requested <= maxProbes {
+				shards[i] = append(shards[i], set)
+				sizes[i] += set.Requested
+				return
+			}
+		}
+		shards = append(shards, []ProbeSet{set})
+		sizes = append(sizes, set.Requested)
+	}
+
+	for _, set := range req.Probes {
+		if set.Type != "probes" || set.Requested <= maxProbes {
+			add(set)
+			continue
+		}
+
+		ids := strings.Split(set.Value, ",")
+		for start := 0; start < len(ids); start += maxProbes {
+			chunk := ids[start:min(start+maxProbes, len(ids))]
+			add(ProbeSet{Type: set.Type, Value: strings.Join(chunk, ","), Requested: len(chunk), Tags: set.Tags})
+		}
+	}
+
+	requests := make([]MeasurementRequest, len(shards))
+	for i, sets := range shards {
+		requests[i] = req
+		requests[i].Probes = sets
+	}
+	return requests
+}
+
+// RequestedProbes returns the number of probes req asks for across its probe sets
+func (req MeasurementRequest) RequestedProbes() int {
+	total := 0
+	for _, set := range req.Probes {
+		total += set.Requested
+	}
+	return total
+}
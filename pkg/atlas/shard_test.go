@@ -0,0 +1,100 @@
+package atlas
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// probeIDs returns an explicit probe set of the IDs from..from+n-1
+func probeIDs(from, n int) ProbeSet {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(from + i)
+	}
+	return ProbeSet{Type: "probes", Value: strings.Join(ids, ","), Requested: n}
+}
+
+func TestShardRequest(t *testing.T) {
+	asn := ProbeSet{Type: "asn", Value: "64500", Requested: 300}
+	country := ProbeSet{Type: "country", Value: "NL", Requested: 200}
+	area := ProbeSet{Type: "area", Value: "WW", Requested: 1200}
+
+	tests := []struct {
+		name  string
+		sets  []ProbeSet
+		sizes []int // probes per shard
+	}{
+		{name: "999 probes", sets: []ProbeSet{probeIDs(1, 999)}, sizes: []int{999}},
+		{name: "1000 probes", sets: []ProbeSet{probeIDs(1, 1000)}, sizes: []int{1000}},
+		{name: "1001 probes", sets: []ProbeSet{probeIDs(1, 1001)}, sizes: []int{1000, 1}},
+		{name: "2500 probes", sets: []ProbeSet{probeIDs(1, 2500)}, sizes: []int{1000, 1000, 500}},
+		{name: "999 listed and a selector", sets: []ProbeSet{probeIDs(1, 999), asn}, sizes: []int{999, 300}},
+		{name: "700 listed and selectors", sets: []ProbeSet{probeIDs(1, 500), asn, country}, sizes: []int{1000}},
+		{name: "1001 with a list that fits whole", sets: []ProbeSet{asn, probeIDs(1, 501), country}, sizes: []int{801, 200}},
+		{
+			name:  "selectors fill the gaps of 2500 listed",
+			sets:  []ProbeSet{asn, country, probeIDs(1, 2500)},
+			sizes: []int{1000, 1000, 1000},
+		},
+		{
+			name:  "oversized selector gets its own shard",
+			sets:  []ProbeSet{probeIDs(1, 1001), area},
+			sizes: []int{1000, 1, 1200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := MeasurementRequest{
+				Definitions: []MeasurementDefinition{{Type: "traceroute", AF: 4}, {Type: "traceroute", AF: 6}},
+				Probes:      tt.sets,
+				IsOneoff:    true,
+			}
+			shards := ShardRequest(req, MaxProbesPerMeasurement)
+
+			var sizes []int
+			var ids, selectors []string
+			for _, shard := range shards {
+				sizes = append(sizes, shard.RequestedProbes())
+				if len(shard.Definitions) != 2 || !shard.IsOneoff {
+					t.Errorf("shard = %+v, want both definitions and one-off", shard)
+				}
+				for _, set := range shard.Probes {
+					if set.Type == "probes" {
+						ids = append(ids, strings.Split(set.Value, ",")...)
+						if n := len(strings.Split(set.Value, ",")); n != set.Requested {
+							t.Errorf("set of %d IDs requests %d probes", n, set.Requested)
+						}
+					} else {
+						selectors = append(selectors, set.Type)
+					}
+				}
+			}
+			if !slices.Equal(sizes, tt.sizes) {
+				t.Errorf("shard sizes = %v, want %v", sizes, tt.sizes)
+			}
+
+			// Every listed ID and selector ends up in exactly one shard
+			var wantIDs, wantSelectors []string
+			for _, set := range tt.sets {
+				if set.Type == "probes" {
+					wantIDs = append(wantIDs, strings.Split(set.Value, ",")...)
+				} else {
+					wantSelectors = append(wantSelectors, set.Type)
+				}
+			}
+			slices.Sort(ids)
+			slices.Sort(wantIDs)
+			slices.Sort(selectors)
+			slices.Sort(wantSelectors)
+			if !slices.Equal(ids, wantIDs) {
+				t.Errorf("shards list %d IDs, want the %d requested once each", len(ids), len(wantIDs))
+			}
+			if !slices.Equal(selectors, wantSelectors) {
+				t.Errorf("shards carry selectors %v, want %v", selectors, wantSelectors)
+			}
+		})
+	}
+}
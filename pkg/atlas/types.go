@@ -1,16 +1,42 @@
 package atlas
 
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
 // Probe represents a RIPE Atlas probe
 type Probe struct {
-	ID          int    `json:"id"`
-	AddressV4   string `json:"address_v4"`
-	AddressV6   string `json:"address_v6"`
-	ASNV4       int    `json:"asn_v4"`
-	ASNV6       int    `json:"asn_v6"`
-	CountryCode string `json:"country_code"`
-	Description string `json:"description"`
-	Status      Status `json:"status"`
-	IsPublic    bool   `json:"is_public"`
+	ID             int        `json:"id"`
+	AddressV4      string     `json:"address_v4"`
+	AddressV6      string     `json:"address_v6"`
+	PrefixV4       string     `json:"prefix_v4"`
+	PrefixV6       string     `json:"prefix_v6"`
+	ASNV4          int        `json:"asn_v4"`
+	ASNV6          int        `json:"asn_v6"`
+	CountryCode    string     `json:"country_code"`
+	Description    string     `json:"description"`
+	Status         Status     `json:"status"`
+	IsPublic       bool       `json:"is_public"`
+	IsAnchor       bool       `json:"is_anchor"`
+	FirstConnected int64      `json:"first_connected"` // Unix time
+	TotalUptime    int64      `json:"total_uptime"`    // seconds
+	Tags           []ProbeTag `json:"tags"`
+	Geometry       *Geometry  `json:"geometry"`
+}
+
+// ProbeTag is a system or user tag of a probe
+type ProbeTag struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Geometry is the GeoJSON location of a probe, Coordinates are [longitude, latitude]
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
 }
 
 // Status represents probe status
@@ -21,32 +47,55 @@ type Status struct {
 }
 
 // ProbeResponse represents the API response for probe queries
-type ProbeResponse struct {
-	Count    int     `json:"count"`
-	Next     *string `json:"next"`
-	Previous *string `json:"previous"`
-	Results  []Probe `json:"results"`
-}
+type ProbeResponse = Page[Probe]
 
-// MeasurementDefinition defines a traceroute measurement
+// MeasurementDefinition defines a measurement, fields that do not apply to
+// the measurement type are left at their zero value and omitted
 type MeasurementDefinition struct {
 	Type            string `json:"type"`
 	AF              int    `json:"af"`
-	Target          string `json:"target"`
+	Target          string `json:"target,omitempty"` // empty for DNS measurements using the probe's resolver
 	Description     string `json:"description"`
-	Protocol        string `json:"protocol"`
-	Packets         int    `json:"packets"`
-	Size            int    `json:"size"`
-	MaxHops         int    `json:"max_hops"`
-	Paris           int    `json:"paris"`
-	ResponseTimeout int    `json:"response_timeout"`
+	Protocol        string `json:"protocol,omitempty"`
+	Packets         int    `json:"packets,omitempty"`
+	Size            *int   `json:"size,omitempty"` // nil for the Atlas default, 0 is sent as is
+	FirstHop        int    `json:"first_hop,omitempty"`
+	MaxHops         int    `json:"max_hops,omitempty"`
+	Paris           *int   `json:"paris,omitempty"` // nil for the Atlas default, 0 disables Paris traceroute
+	DontFragment    bool   `json:"dont_fragment,omitempty"`
+	ResponseTimeout int    `json:"response_timeout,omitempty"`
+	Spread          int    `json:"spread,omitempty"`
+
+	// DNS
+	QueryClass       string `json:"query_class,omitempty"`
+	QueryType        string `json:"query_type,omitempty"`
+	QueryArgument    string `json:"query_argument,omitempty"`
+	UseProbeResolver bool   `json:"use_probe_resolver,omitempty"`
+	SetRDBit         bool   `json:"set_rd_bit,omitempty"`
+	UDPPayloadSize   int    `json:"udp_payload_size,omitempty"`
+
+	// HTTP and SSL certificate
+	Method          string `json:"method,omitempty"`
+	Path            string `json:"path,omitempty"`
+	Port            int    `json:"port,omitempty"` // also the destination port of UDP and TCP traceroutes
+	HTTPS           bool   `json:"https,omitempty"`
+	Hostname        string `json:"hostname,omitempty"` // SNI name for sslcert
+	TimingVerbosity int    `json:"timing_verbosity,omitempty"`
 }
 
 // ProbeSet defines which probes to use
 type ProbeSet struct {
-	Type      string `json:"type"`
-	Value     string `json:"value"`
-	Requested int    `json:"requested"`
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	Requested int        `json:"requested"`
+	Tags      *ProbeTags `json:"tags,omitempty"`
+}
+
+// ProbeTags limits a probe set to probes carrying every Include tag and none
+// of the Exclude tags
+type ProbeTags struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
 }
 
 // MeasurementRequest represents a measurement creation request
@@ -61,17 +110,80 @@ type MeasurementResponse struct {
 	Measurements []int `json:"measurements"`
 }
 
+// Measurement status IDs reported in MeasurementStatus.Status.ID
+const (
+	MeasurementSpecified        = 0
+	MeasurementScheduled        = 1
+	MeasurementOngoing          = 2
+	MeasurementStopped          = 4
+	MeasurementForcedStop       = 5
+	MeasurementNoSuitableProbes = 6
+	MeasurementFailed           = 7
+	MeasurementArchived         = 8
+)
+
+// MeasurementStatusIDs maps lower-case status names to status IDs
+var MeasurementStatusIDs = map[string]int{
+	"specified": MeasurementSpecified,
+	"scheduled": MeasurementScheduled,
+	"ongoing":   MeasurementOngoing,
+	"stopped":   MeasurementStopped,
+	"forced":    MeasurementForcedStop,
+	"noprobes":  MeasurementNoSuitableProbes,
+	"failed":    MeasurementFailed,
+	"archived":  MeasurementArchived,
+}
+
 // MeasurementStatus represents the status of a measurement
 type MeasurementStatus struct {
 	ID               int        `json:"id"`
+	Type             string     `json:"type"`
+	AF               int        `json:"af"`
+	Target           string     `json:"target"`
+	TargetIP         string     `json:"target_ip"`
+	Description      string     `json:"description"`
+	IsOneoff         bool       `json:"is_oneoff"`
+	IsPublic         bool       `json:"is_public"`
+	Tags             []string   `json:"tags"`
 	Status           StatusInfo `json:"status"`
 	ProbesScheduled  int        `json:"probes_scheduled"`
 	ProbesRequested  int        `json:"probes_requested"`
 	ParticipantCount int        `json:"participant_count"`
+	CreationTime     int64      `json:"creation_time"`
 	StartTime        int64      `json:"start_time"`
 	StopTime         int64      `json:"stop_time"`
 }
 
+// MeasurementFilter narrows down a measurement list, zero values are not filtered on
+type MeasurementFilter struct {
+	StatusIDs []int
+	Type      string
+	Target    string
+	Tags      []string
+}
+
+// values encodes the filter as Atlas list query parameters
+func (f MeasurementFilter) values() url.Values {
+	query := url.Values{}
+	if len(f.StatusIDs) > 0 {
+		ids := make([]string, len(f.StatusIDs))
+		for i, id := range f.StatusIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		query.Set("status__in", strings.Join(ids, ","))
+	}
+	if f.Type != "" {
+		query.Set("type", f.Type)
+	}
+	if f.Target != "" {
+		query.Set("target", f.Target)
+	}
+	if len(f.Tags) > 0 {
+		query.Set("tags", strings.Join(f.Tags, ","))
+	}
+	return query
+}
+
 // StatusInfo represents detailed status information
 type StatusInfo struct {
 	ID   int    `json:"id"`
@@ -85,7 +197,9 @@ type TracerouteResult struct {
 	Timestamp int64       `json:"timestamp"`
 	From      string      `json:"from"`
 	Type      string      `json:"type"`
+	AF        int         `json:"af,omitempty"`
 	Result    []HopResult `json:"result"`
+	DstName   string      `json:"dst_name,omitempty"`
 	DstAddr   string      `json:"dst_addr"`
 	SrcAddr   string      `json:"src_addr"`
 }
@@ -106,6 +220,284 @@ type HopReply struct {
 	X    string  `json:"x,omitempty"` // Timeout indicator
 }
 
+// DNSResult represents a single DNS measurement result. Measurements against
+// a named server report one response in Result or Error, measurements using
+// the probe's resolvers report one entry per resolver in ResultSet.
+type DNSResult struct {
+	ProbeID   int            `json:"prb_id"`
+	MsmID     int            `json:"msm_id"`
+	Timestamp int64          `json:"timestamp"`
+	From      string         `json:"from"`
+	Type      string         `json:"type"`
+	AF        int            `json:"af,omitempty"`
+	DstAddr   string         `json:"dst_addr,omitempty"`
+	SrcAddr   string         `json:"src_addr,omitempty"`
+	Proto     string         `json:"proto,omitempty"`
+	Result    *DNSResponse   `json:"result,omitempty"`
+	Error     *DNSError      `json:"error,omitempty"`
+	ResultSet []DNSResultSet `json:"resultset,omitempty"`
+}
+
+// DNSResultSet is the outcome of querying one of the probe's resolvers
+type DNSResultSet struct {
+	Time    int64        `json:"time"`
+	AF      int          `json:"af,omitempty"`
+	DstAddr string       `json:"dst_addr,omitempty"`
+	SrcAddr string       `json:"src_addr,omitempty"`
+	Proto   string       `json:"proto,omitempty"`
+	Result  *DNSResponse `json:"result,omitempty"`
+	Error   *DNSError    `json:"error,omitempty"`
+}
+
+// DNSResponse holds the timing and raw answer of a DNS response
+type DNSResponse struct {
+	RT      float64 `json:"rt"` // response time in milliseconds
+	Size    int     `json:"size"`
+	Abuf    string  `json:"abuf"` // base64 encoded DNS wire-format message
+	ID      int     `json:"ID"`
+	ANCount int     `json:"ANCOUNT"`
+	QDCount int     `json:"QDCOUNT"`
+	NSCount int     `json:"NSCOUNT"`
+	ARCount int     `json:"ARCOUNT"`
+}
+
+// DNSError describes why a DNS query produced no response
+type DNSError struct {
+	Timeout     int    `json:"timeout,omitempty"` // timeout in milliseconds
+	Socket      string `json:"socket,omitempty"`
+	GetAddrInfo string `json:"getaddrinfo,omitempty"`
+}
+
+// Responses returns one entry per queried resolver, folding a single
+// response into the same shape as a probe resolver result set
+func (r DNSResult) Responses() []DNSResultSet {
+	if len(r.ResultSet) > 0 {
+		return r.ResultSet
+	}
+	return []DNSResultSet{{
+		Time:    r.Timestamp,
+		AF:      r.AF,
+		DstAddr: r.DstAddr,
+		SrcAddr: r.SrcAddr,
+		Proto:   r.Proto,
+		Result:  r.Result,
+		Error:   r.Error,
+	}}
+}
+
+// String describes the error in a single line
+func (e DNSError) String() string {
+	switch {
+	case e.Timeout > 0:
+		return fmt.Sprintf("timeout after %d ms", e.Timeout)
+	case e.Socket != "":
+		return "socket: " + e.Socket
+	case e.GetAddrInfo != "":
+		return "getaddrinfo: " + e.GetAddrInfo
+	}
+	return "unknown error"
+}
+
+// HTTPResult represents a single HTTP measurement result
+type HTTPResult struct {
+	ProbeID   int            `json:"prb_id"`
+	MsmID     int            `json:"msm_id"`
+	Timestamp int64          `json:"timestamp"`
+	From      string         `json:"from"`
+	Type      string         `json:"type"`
+	URI       string         `json:"uri"`
+	Result    []HTTPResponse `json:"result"`
+}
+
+// HTTPResponse represents the outcome of a single HTTP request
+type HTTPResponse struct {
+	AF      int     `json:"af,omitempty"`
+	DstAddr string  `json:"dst_addr,omitempty"`
+	SrcAddr string  `json:"src_addr,omitempty"`
+	Method  string  `json:"method,omitempty"`
+	Ver     string  `json:"ver,omitempty"`
+	Res     int     `json:"res,omitempty"` // HTTP status code
+	RT      float64 `json:"rt,omitempty"`  // total time in milliseconds
+	TTC     float64 `json:"ttc,omitempty"` // time to connect
+	TTFB    float64 `json:"ttfb,omitempty"`
+	HSize   int     `json:"hsize,omitempty"`
+	BSize   int     `json:"bsize,omitempty"`
+	Err     string  `json:"err,omitempty"`
+	DNSErr  string  `json:"dnserr,omitempty"`
+}
+
+// SSLCertResult represents a single SSL certificate measurement result
+type SSLCertResult struct {
+	ProbeID   int       `json:"prb_id"`
+	MsmID     int       `json:"msm_id"`
+	Timestamp int64     `json:"timestamp"`
+	From      string    `json:"from"`
+	Type      string    `json:"type"`
+	AF        int       `json:"af,omitempty"`
+	DstName   string    `json:"dst_name,omitempty"`
+	DstAddr   string    `json:"dst_addr,omitempty"`
+	DstPort   string    `json:"dst_port,omitempty"`
+	SrcAddr   string    `json:"src_addr,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Ver       string    `json:"ver,omitempty"`
+	RT        float64   `json:"rt,omitempty"`
+	TTC       float64   `json:"ttc,omitempty"`
+	Cert      []string  `json:"cert,omitempty"` // PEM encoded chain, leaf first
+	Alert     *SSLAlert `json:"alert,omitempty"`
+	Err       string    `json:"err,omitempty"`
+}
+
+// SSLAlert is a TLS alert received during the handshake
+type SSLAlert struct {
+	Level       int `json:"level"`
+	Description int `json:"description"`
+}
+
+// PingResult represents a single ping measurement result
+type PingResult struct {
+	ProbeID   int         `json:"prb_id"`
+	MsmID     int         `json:"msm_id"`
+	Timestamp int64       `json:"timestamp"`
+	From      string      `json:"from"`
+	Type      string      `json:"type"`
+	AF        int         `json:"af"`
+	DstAddr   string      `json:"dst_addr"`
+	SrcAddr   string      `json:"src_addr"`
+	Sent      int         `json:"sent"`
+	Rcvd      int         `json:"rcvd"`
+	Min       float64     `json:"min"` // -1 when no reply was received
+	Avg       float64     `json:"avg"`
+	Max       float64     `json:"max"`
+	Result    []PingReply `json:"result"`
+}
+
+// PingReply represents the outcome of a single ping packet
+type PingReply struct {
+	RTT   float64 `json:"rtt,omitempty"`
+	X     string  `json:"x,omitempty"` // Timeout indicator
+	Error string  `json:"error,omitempty"`
+}
+
+// LatencyStats summarises ping latency for a group of probes (an ASN, a country, ...)
+type LatencyStats struct {
+	Group    string
+	Probes   int
+	Sent     int
+	Received int
+	Min      float64
+	Median   float64
+	P95      float64
+	Max      float64
+	Loss     float64 // packet loss in percent
+}
+
+// DNSAnalysis is the outcome of comparing DNS answers across probes
+type DNSAnalysis struct {
+	Consensus      string // most common answer set
+	ConsensusCount int
+	Responses      int // responses received, errors excluded
+	ByASN          []DNSASNSummary
+	Findings       []DNSFinding
+}
+
+// DNSASNSummary summarises the DNS answers seen by the probes of one ASN
+type DNSASNSummary struct {
+	ASN       int
+	Probes    int
+	Responses int
+	Errors    int
+	MedianRT  float64
+	MaxRT     float64
+	Answers   []DNSAnswerCount // most common first
+}
+
+// DNSAnswerCount counts the responses that returned one answer set
+type DNSAnswerCount struct {
+	Answer string
+	Count  int
+	Reason string // why the answer set was flagged, empty when it looks fine
+}
+
+// DNSFinding flags a single response whose answer looks inconsistent or hijacked
+type DNSFinding struct {
+	ProbeID  int
+	ASN      int
+	Resolver string
+	Answer   string
+	Reason   string
+}
+
+// HTTPASNSummary summarises the HTTP responses seen by the probes of one ASN
+type HTTPASNSummary struct {
+	ASN         int
+	Probes      int
+	Requests    int
+	Errors      int
+	StatusCodes map[int]int // responses per status code
+	MedianTTFB  float64
+	P95TTFB     float64
+	MedianRT    float64
+}
+
+// CertASNSummary summarises the certificates seen by the probes of one ASN
+type CertASNSummary struct {
+	ASN          int
+	Probes       int
+	Errors       int
+	Mismatches   int
+	MedianRT     float64
+	Fingerprints []FingerprintCount // most common first
+}
+
+// FingerprintCount counts the probes that were served one certificate chain
+type FingerprintCount struct {
+	Fingerprint string // SHA-256 of the leaf certificate
+	Subject     string
+	Count       int
+	Match       bool // matches the expected fingerprint, or none was given
+}
+
+// CertMismatch records a probe that was served an unexpected certificate
+type CertMismatch struct {
+	ProbeID     int
+	ASN         int
+	DstAddr     string
+	Fingerprint string
+	Subject     string
+}
+
+// DualStackComparison is the outcome of comparing paired IPv4 and IPv6
+// traceroutes from the same probes
+type DualStackComparison struct {
+	Probes        []DualStackProbe
+	Identical     int // probes whose v4 and v6 AS paths are the same
+	Divergent     int
+	Unpaired      int         // probes with a result in only one family
+	MedianRTTDiff float64     // median of RTTV6 - RTTV4 over probes that reached the target in both
+	OnlyV4        []FamilyASN // ASNs seen in IPv4 paths but in no IPv6 path
+	OnlyV6        []FamilyASN
+}
+
+// DualStackProbe compares the IPv4 and IPv6 paths of one probe
+type DualStackProbe struct {
+	ProbeID   int
+	ASN       int
+	PathV4    []int // AS path, consecutive duplicates collapsed
+	PathV6    []int
+	DivergeAt int // index of the first differing AS path entry, -1 when identical
+	RTTV4     float64
+	RTTV6     float64
+	ReachedV4 bool
+	ReachedV6 bool
+}
+
+// FamilyASN is an ASN seen over only one address family
+type FamilyASN struct {
+	ASN    int
+	Name   string
+	Probes int // probes whose path crossed the ASN
+}
+
 // ASNInfo represents ASN information extracted from traceroute
 type ASNInfo struct {
 	ASN         int
@@ -0,0 +1,71 @@
+package atlas
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Limits enforced by the Atlas API on measurement definitions
+const (
+	MaxPackets         = 16
+	MaxPacketSize      = 2048
+	MaxHopLimit        = 255
+	MaxParis           = 64
+	MaxResponseTimeout = 60000 // milliseconds
+	MaxPort            = 65535
+)
+
+// TracerouteProtocols are the protocols a traceroute can use
+var TracerouteProtocols = []string{"ICMP", "UDP", "TCP"}
+
+// Validate checks the definition against the limits the Atlas API enforces,
+// so mistakes are reported before any credits are spent. Packets, first hop,
+// max hops and response timeout are required where the type uses them: a
+// zero would be omitted from the request and silently replaced by the Atlas
+// default. Port and spread left at zero use the Atlas default.
+func (d MeasurementDefinition) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(d.AF == 4 || d.AF == 6, "af must be 4 or 6, got %d", d.AF)
+	check(d.Target != "" || d.UseProbeResolver, "target is required")
+	check(d.Size == nil || (*d.Size >= 0 && *d.Size <= MaxPacketSize), "size must be between 0 and %d bytes, got %d", MaxPacketSize, deref(d.Size))
+	// A zero port is unset, Atlas then uses the default for the type
+	check(d.Port == 0 || (d.Port >= 1 && d.Port <= MaxPort), "port must be between 1 and %d, got %d", MaxPort, d.Port)
+	check(d.Spread >= 0, "spread must not be negative, got %d", d.Spread)
+
+	// packetCosts lists the types that send a configurable number of packets
+	if _, ok := packetCosts[d.Type]; ok {
+		check(d.Packets >= 1 && d.Packets <= MaxPackets, "packets must be between 1 and %d, got %d", MaxPackets, d.Packets)
+	}
+
+	if d.Type == "traceroute" {
+		check(d.Protocol == "" || slices.Contains(TracerouteProtocols, d.Protocol),
+			"protocol must be one of %v, got %q", TracerouteProtocols, d.Protocol)
+		check(d.FirstHop >= 1 && d.FirstHop <= MaxHopLimit, "first hop must be between 1 and %d, got %d", MaxHopLimit, d.FirstHop)
+		check(d.MaxHops >= 1 && d.MaxHops <= MaxHopLimit, "max hops must be between 1 and %d, got %d", MaxHopLimit, d.MaxHops)
+		check(d.FirstHop <= d.MaxHops, "first hop (%d) must not exceed max hops (%d)", d.FirstHop, d.MaxHops)
+		check(d.ResponseTimeout >= 1 && d.ResponseTimeout <= MaxResponseTimeout,
+			"response timeout must be between 1 and %d ms, got %d", MaxResponseTimeout, d.ResponseTimeout)
+		check(d.Paris == nil || (*d.Paris >= 0 && *d.Paris <= MaxParis), "paris must be between 0 and %d, got %d", MaxParis, deref(d.Paris))
+		check(d.Port == 0 || d.Protocol == "UDP" || d.Protocol == "TCP", "port only applies to UDP and TCP traceroutes")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid %s definition: %w", d.Type, errors.Join(errs...))
+	}
+	return nil
+}
+
+// deref returns the value of p, or 0 when it is nil
+func deref(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
@@ -0,0 +1,132 @@
+package atlas
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// validTraceroute returns a traceroute definition with the defaults of the traceroute command
+func validTraceroute() MeasurementDefinition {
+	paris, size := 16, 48
+	return MeasurementDefinition{
+		Type:            "traceroute",
+		AF:              4,
+		Target:          "192.0.2.1",
+		Protocol:        "ICMP",
+		Packets:         3,
+		Size:            &size,
+		FirstHop:        1,
+		MaxHops:         40,
+		Paris:           &paris,
+		ResponseTimeout: 4000,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name   string
+		modify func(d *MeasurementDefinition)
+		want   string // substring of the error, "" when valid
+	}{
+		{"defaults", func(d *MeasurementDefinition) {}, ""},
+		{"IPv6", func(d *MeasurementDefinition) { d.AF = 6 }, ""},
+		{"bad af", func(d *MeasurementDefinition) { d.AF = 5 }, "af must be 4 or 6"},
+		{"no target", func(d *MeasurementDefinition) { d.Target = "" }, "target is required"},
+
+		{"zero packets", func(d *MeasurementDefinition) { d.Packets = 0 }, "packets must be between 1 and 16, got 0"},
+		{"max packets", func(d *MeasurementDefinition) { d.Packets = MaxPackets }, ""},
+		{"too many packets", func(d *MeasurementDefinition) { d.Packets = MaxPackets + 1 }, "packets must be between 1 and 16"},
+
+		{"default size", func(d *MeasurementDefinition) { d.Size = nil }, ""},
+		{"zero size", func(d *MeasurementDefinition) { d.Size = intPtr(0) }, ""},
+		{"max size", func(d *MeasurementDefinition) { d.Size = intPtr(MaxPacketSize) }, ""},
+		{"size too large", func(d *MeasurementDefinition) { d.Size = intPtr(MaxPacketSize + 1) }, "size must be between 0 and 2048 bytes, got 2049"},
+		{"negative size", func(d *MeasurementDefinition) { d.Size = intPtr(-1) }, "size must be between 0 and 2048"},
+
+		{"zero first hop", func(d *MeasurementDefinition) { d.FirstHop = 0 }, "first hop must be between 1 and 255, got 0"},
+		{"zero max hops", func(d *MeasurementDefinition) { d.MaxHops = 0 }, "max hops must be between 1 and 255, got 0"},
+		{"max hops too large", func(d *MeasurementDefinition) { d.MaxHops = MaxHopLimit + 1 }, "max hops must be between 1 and 255"},
+		{"first hop above max hops", func(d *MeasurementDefinition) { d.FirstHop, d.MaxHops = 10, 5 }, "first hop (10) must not exceed max hops (5)"},
+		{"first hop equals max hops", func(d *MeasurementDefinition) { d.FirstHop, d.MaxHops = 5, 5 }, ""},
+
+		{"zero response timeout", func(d *MeasurementDefinition) { d.ResponseTimeout = 0 }, "response timeout must be between 1 and 60000 ms, got 0"},
+		{"response timeout too long", func(d *MeasurementDefinition) { d.ResponseTimeout = MaxResponseTimeout + 1 }, "response timeout must be between 1 and 60000 ms"},
+
+		{"paris disabled", func(d *MeasurementDefinition) { d.Paris = intPtr(0) }, ""},
+		{"paris too large", func(d *MeasurementDefinition) { d.Paris = intPtr(MaxParis + 1) }, "paris must be between 0 and 64, got 65"},
+		{"unknown protocol", func(d *MeasurementDefinition) { d.Protocol = "SCTP" }, "protocol must be one of"},
+		{"port with ICMP", func(d *MeasurementDefinition) { d.Port = 443 }, "port only applies to UDP and TCP"},
+		{"port with TCP", func(d *MeasurementDefinition) { d.Protocol, d.Port = "TCP", 443 }, ""},
+		{"port too large", func(d *MeasurementDefinition) { d.Protocol, d.Port = "TCP", MaxPort+1 }, "port must be between 1 and 65535, got 65536"},
+		{"negative port", func(d *MeasurementDefinition) { d.Protocol, d.Port = "TCP", -1 }, "port must be between 1 and 65535, got -1"},
+		{"port unset", func(d *MeasurementDefinition) { d.Protocol, d.Port = "TCP", 0 }, ""},
+		{"negative spread", func(d *MeasurementDefinition) { d.Spread = -1 }, "spread must not be negative"},
+
+		{"ping needs packets", func(d *MeasurementDefinition) { *d = MeasurementDefinition{Type: "ping", AF: 4, Target: "192.0.2.1"} }, "packets must be between 1 and 16"},
+		{"ping ignores hops", func(d *MeasurementDefinition) {
+			*d = MeasurementDefinition{Type: "ping", AF: 4, Target: "192.0.2.1", Packets: 3}
+		}, ""},
+		{"http has no packets", func(d *MeasurementDefinition) { *d = MeasurementDefinition{Type: "http", AF: 4, Target: "example.com"} }, ""},
+		{"dns with the probe resolver", func(d *MeasurementDefinition) {
+			*d = MeasurementDefinition{Type: "dns", AF: 4, UseProbeResolver: true}
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := validTraceroute()
+			tt.modify(&def)
+
+			err := def.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	def := validTraceroute()
+	def.Packets, def.MaxHops = 0, 0
+
+	err := def.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil")
+	}
+	for _, want := range []string{"invalid traceroute definition", "packets", "max hops"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q lacks %q", err, want)
+		}
+	}
+}
+
+func TestDefinitionSendsZeroSize(t *testing.T) {
+	tests := []struct {
+		size *int
+		want string
+	}{
+		{nil, ""},
+		{new(int), `"size":0`},
+	}
+
+	for _, tt := range tests {
+		def := validTraceroute()
+		def.Size = tt.size
+
+		data, err := json.Marshal(def)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if got := string(data); tt.want == "" && strings.Contains(got, `"size"`) {
+			t.Errorf("nil size encoded as %s", got)
+		} else if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("definition %s lacks %s", got, tt.want)
+		}
+	}
+}
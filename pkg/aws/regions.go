@@ -191,8 +191,9 @@ var regionTestIPs = map[string][]string{
 	},
 }
 
-// GetRegionIP returns a random test IP from the specified AWS region
-func GetRegionIP(region string) (string, error) {
+// GetRegionIP returns a random test IP from the specified AWS region, drawn
+// from rng or the global math/rand source when rng is nil
+func GetRegionIP(region string, rng *rand.Rand) (string, error) {
 	// Parse region from format like "aws_us-west-2"
 	if strings.HasPrefix(region, "aws_") {
 		region = strings.TrimPrefix(region, "aws_")
@@ -204,7 +205,11 @@ func GetRegionIP(region string) (string, error) {
 	}
 
 	// Select a random IP from the list
-	randomIP := ips[rand.Intn(len(ips))]
+	intn := rand.Intn
+	if rng != nil {
+		intn = rng.Intn
+	}
+	randomIP := ips[intn(len(ips))]
 
 	return randomIP, nil
 }
@@ -0,0 +1,202 @@
+package httpretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how transient failures are retried
+type Policy struct {
+	MaxRetries int           // retries after the first attempt, 0 disables retrying
+	BaseDelay  time.Duration // backoff before the first retry, doubled for every following one
+	MaxDelay   time.Duration // upper bound for a single backoff, also the longest Retry-After honored
+}
+
+// DefaultPolicy returns the retry policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: 4,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// Client wraps an http.Client and retries transient failures with
+// exponential backoff and full jitter
+type Client struct {
+	httpClient *http.Client
+	policy     Policy
+}
+
+// New creates a retrying client around httpClient
+func New(httpClient *http.Client, policy Policy) *Client {
+	return &Client{
+		httpClient: httpClient,
+		policy:     policy,
+	}
+}
+
+// Policy returns the retry policy of the client
+func (c *Client) Policy() Policy {
+	return c.policy
+}
+
+// SetPolicy replaces the retry policy of the client
+func (c *Client) SetPolicy(policy Policy) {
+	c.policy = policy
+}
+
+// Do sends req, retrying network errors and retryable status codes.
+//
+// Responses that are not worth retrying (success, redirects and client
+// errors such as 400/403/404) are returned untouched for the caller to
+// interpret. When retries are exhausted the last response is returned.
+// POST requests are only retried on 429 and 503, where the server is known
+// not to have processed them, so a measurement is never created twice.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(attemptReq)
+
+		if attempt >= c.policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if !idempotent(req.Method) {
+				return nil, err
+			}
+			delay = c.policy.backoff(attempt)
+
+		case shouldRetry(req.Method, resp.StatusCode):
+			delay = c.policy.backoff(attempt)
+			if after, ok := retryAfter(resp); ok {
+				if after > c.policy.MaxDelay {
+					// The server asks us to wait longer than we are willing to
+					return resp, nil
+				}
+				delay = max(delay, after)
+			}
+
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+		default:
+			return resp, nil
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Retryable reports whether a response status is a transient failure worth retrying
+func Retryable(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// shouldRetry applies Retryable, restricted for non-idempotent methods
+func shouldRetry(method string, status int) bool {
+	if idempotent(method) {
+		return Retryable(status)
+	}
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// idempotent reports whether repeating a request with method has no additional effect
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// rewind returns the request to send for the given attempt, with a fresh body on retries
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, errors.New("request body cannot be replayed for retry")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// backoff returns a random delay in [0, min(MaxDelay, BaseDelay*2^attempt)]
+func (p Policy) backoff(attempt int) time.Duration {
+	ceiling := p.MaxDelay
+	if shift := p.BaseDelay << attempt; attempt < 32 && shift > 0 && shift < ceiling {
+		ceiling = shift
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// retryAfter parses the Retry-After header, which holds either seconds or an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		return max(time.Until(when), 0), true
+	}
+
+	return 0, false
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
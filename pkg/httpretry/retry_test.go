@@ -0,0 +1,255 @@
+package httpretry_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/httpretry"
+)
+
+// fastPolicy retries quickly enough for tests
+var fastPolicy = httpretry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// scriptedServer answers with the given status codes in order, repeating the
+// last one, and records the body of every request
+type scriptedServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+	header   http.Header
+}
+
+func newScriptedServer(t *testing.T, statuses ...int) *scriptedServer {
+	t.Helper()
+
+	s := &scriptedServer{statuses: statuses, header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		status := s.statuses[min(len(s.bodies), len(s.statuses)-1)]
+		s.bodies = append(s.bodies, string(body))
+		for key, values := range s.header {
+			w.Header()[key] = values
+		}
+		s.mu.Unlock()
+
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the bodies of the requests received so far
+func (s *scriptedServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func do(t *testing.T, ctx context.Context, policy httpretry.Policy, method, url, body string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	resp, err := httpretry.New(http.DefaultClient, policy).Do(req)
+	if resp != nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestDoRetriesPOSTOnlyWhenNotProcessed(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     int
+		attempts int
+	}{
+		{"429 then created", []int{http.StatusTooManyRequests, http.StatusCreated}, http.StatusCreated, 2},
+		{"503 then created", []int{http.StatusServiceUnavailable, http.StatusCreated}, http.StatusCreated, 2},
+		{"500 is not retried", []int{http.StatusInternalServerError, http.StatusCreated}, http.StatusInternalServerError, 1},
+		{"502 is not retried", []int{http.StatusBadGateway, http.StatusCreated}, http.StatusBadGateway, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newScriptedServer(t, tt.statuses...)
+
+			resp, err := do(t, context.Background(), fastPolicy, http.MethodPost, srv.URL, "{}")
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if got := len(srv.requests()); got != tt.attempts {
+				t.Errorf("attempts = %d, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestDoRetriesGETOnServerErrors(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+
+	resp, err := do(t, context.Background(), fastPolicy, http.MethodGet, srv.URL, "")
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := len(srv.requests()); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusNotFound, http.StatusOK)
+
+	resp, err := do(t, context.Background(), fastPolicy, http.MethodGet, srv.URL, "")
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestDoReturnsLongRetryAfter(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusTooManyRequests, http.StatusOK)
+	srv.header.Set("Retry-After", "60")
+
+	start := time.Now()
+	resp, err := do(t, context.Background(), fastPolicy, http.MethodGet, srv.URL, "")
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do slept %v for a Retry-After above MaxDelay", elapsed)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestDoRewindsBody(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusCreated)
+
+	const body = `{"definitions":[]}`
+	resp, err := do(t, context.Background(), fastPolicy, http.MethodPost, srv.URL, body)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusCreated)
+	}
+
+	bodies := srv.requests()
+	if len(bodies) != 3 {
+		t.Fatalf("attempts = %d, want 3", len(bodies))
+	}
+	for i, got := range bodies {
+		if got != body {
+			t.Errorf("attempt %d body = %q, want %q", i+1, got, body)
+		}
+	}
+}
+
+func TestDoRejectsBodyWithoutGetBody(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable, http.StatusCreated)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, io.NopCloser(strings.NewReader("{}")))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	if _, err := httpretry.New(http.DefaultClient, fastPolicy).Do(req); err == nil {
+		t.Error("Do succeeded retrying a body that cannot be replayed")
+	}
+}
+
+func TestDoCancelDuringBackoff(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable)
+	// Retry-After below MaxDelay is honored, so Do sleeps for a full minute
+	srv.header.Set("Retry-After", "60")
+	policy := httpretry.Policy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	resp, err := do(t, ctx, policy, http.MethodGet, srv.URL, "")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do error = %v, want %v", err, context.Canceled)
+	}
+	if resp != nil {
+		t.Errorf("Do returned a response alongside the cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Do took %v to notice the cancellation", elapsed)
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestDoGivesUpAfterMaxRetries(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable)
+
+	resp, err := do(t, context.Background(), fastPolicy, http.MethodGet, srv.URL, "")
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	// The last response is handed back unread
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if string(body) != http.StatusText(http.StatusServiceUnavailable) {
+		t.Errorf("body = %q", body)
+	}
+	if got, want := len(srv.requests()), fastPolicy.MaxRetries+1; got != want {
+		t.Errorf("attempts = %d, want %d", got, want)
+	}
+}
+
+func TestDoNoRetriesWhenDisabled(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable, http.StatusOK)
+
+	resp, err := do(t, context.Background(), httpretry.Policy{}, http.MethodGet, srv.URL, "")
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := len(srv.requests()); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
@@ -0,0 +1,47 @@
+// Command fakeatlas serves the atlastest fake RIPE Atlas and RIPEstat APIs
+// with demo data, for running the CLI end-to-end without network access.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cmingou/ripeatlas-cli/pkg/atlas/atlastest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:0", "listen address")
+	apiKey := flag.String("api-key", "", "require this API key for creating and stopping measurements")
+	credits := flag.Int64("credits", 1000000, "credit balance reported for the API key")
+	bulk := flag.Int("bulk-probes", 0, "register this many more IPv4 probes in AS64500")
+	resultsPerPoll := flag.Int("results-per-poll", 5, "results delivered per status poll")
+	flag.Parse()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := atlastest.NewUnstartedServer()
+	srv.Listener.Close()
+	srv.Listener = listener
+	srv.APIKey = *apiKey
+	srv.Credits = *credits
+	srv.ResultsPerPoll = *resultsPerPoll
+	srv.SeedDemo()
+	srv.SeedBulk(*bulk)
+	srv.Start()
+	defer srv.Close()
+
+	fmt.Printf("Fake RIPE Atlas listening on %s\n", srv.Server.URL)
+	fmt.Printf("  --api-url %s --ripestat-url %s\n", srv.URL(), srv.StatURL())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+}